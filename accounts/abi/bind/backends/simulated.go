@@ -68,6 +68,7 @@ type SimulatedBackend struct {
 	pendingState *state.StateDB // Currently pending state that will be the active on request
 
 	events *filters.EventSystem // Event system for filtering log events live
+	tracer vm.Tracer            // Tracer attached to sent transactions and contract calls, if any
 
 	config *params.ChainConfig
 }
@@ -103,6 +104,21 @@ func (b *SimulatedBackend) Close() error {
 	return nil
 }
 
+// SetTracer attaches a tracer to the execution of all subsequently sent
+// transactions and contract calls, or detaches it if nil. Gas estimations and
+// the re-execution of pending transactions are not traced.
+func (b *SimulatedBackend) SetTracer(tracer vm.Tracer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tracer = tracer
+}
+
+// vmConfig returns the EVM configuration to execute traced operations with.
+func (b *SimulatedBackend) vmConfig() vm.Config {
+	return vm.Config{Debug: b.tracer != nil, Tracer: b.tracer}
+}
+
 // Commit imports all the pending transactions as a single block and starts a
 // fresh new state.
 func (b *SimulatedBackend) Commit() {
@@ -389,7 +405,7 @@ func (b *SimulatedBackend) CallContract(ctx context.Context, call ethereum.CallM
 	if err != nil {
 		return nil, err
 	}
-	res, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), stateDB)
+	res, err := b.callContract(ctx, call, b.blockchain.CurrentBlock(), stateDB, b.vmConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -406,7 +422,7 @@ func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call ethereu
 	defer b.mu.Unlock()
 	defer b.pendingState.RevertToSnapshot(b.pendingState.Snapshot())
 
-	res, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+	res, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState, b.vmConfig())
 	if err != nil {
 		return nil, err
 	}
@@ -477,7 +493,7 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 		call.Gas = gas
 
 		snapshot := b.pendingState.Snapshot()
-		res, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState)
+		res, err := b.callContract(ctx, call, b.pendingBlock, b.pendingState, vm.Config{})
 		b.pendingState.RevertToSnapshot(snapshot)
 
 		if err != nil {
@@ -527,7 +543,7 @@ func (b *SimulatedBackend) EstimateGas(ctx context.Context, call ethereum.CallMs
 
 // callContract implements common code between normal and pending contract calls.
 // state is modified during execution, make sure to copy it if necessary.
-func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallMsg, block *types.Block, stateDB *state.StateDB) (*core.ExecutionResult, error) {
+func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallMsg, block *types.Block, stateDB *state.StateDB, vmConfig vm.Config) (*core.ExecutionResult, error) {
 	// Ensure message is initialized properly.
 	if call.GasPrice == nil {
 		call.GasPrice = big.NewInt(1)
@@ -548,7 +564,7 @@ func (b *SimulatedBackend) callContract(ctx context.Context, call ethereum.CallM
 	evmContext := core.NewEVMBlockContext(block.Header(), b.blockchain, nil)
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
-	vmEnv := vm.NewEVM(evmContext, txContext, stateDB, b.config, vm.Config{})
+	vmEnv := vm.NewEVM(evmContext, txContext, stateDB, b.config, vmConfig)
 	gasPool := new(core.GasPool).AddGas(math.MaxUint64)
 
 	return core.NewStateTransition(vmEnv, msg, gasPool).TransitionDb()
@@ -573,7 +589,7 @@ func (b *SimulatedBackend) SendTransaction(ctx context.Context, tx *types.Transa
 		for _, tx := range b.pendingBlock.Transactions() {
 			block.AddTxWithChain(b.blockchain, tx)
 		}
-		block.AddTxWithChain(b.blockchain, tx)
+		block.AddTxWithVMConfig(b.blockchain, tx, b.vmConfig())
 	})
 	stateDB, _ := b.blockchain.State()
 
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -1114,3 +1115,45 @@ func TestSimulatedBackend_CallContractRevert(t *testing.T) {
 		sim.Commit()
 	}
 }
+
+func TestSimulatedBackend_SetTracer(t *testing.T) {
+	testAddr := crypto.PubkeyToAddress(testKey.PublicKey)
+	sim := simTestBackend(testAddr)
+	defer sim.Close()
+	bgCtx := context.Background()
+
+	logger := vm.NewStructLogger(nil)
+	sim.SetTracer(logger)
+
+	// Deploy a contract returning 1, the creation should be traced but not the
+	// gas estimation
+	contractAuth, _ := bind.NewKeyedTransactorWithChainID(testKey, big.NewInt(1337))
+	addr, _, _, err := bind.DeployContract(contractAuth, abi.ABI{}, common.FromHex("69600160005260206000f3600052600a6016f3"), sim)
+	if err != nil {
+		t.Fatalf("could not deploy contract: %v", err)
+	}
+	deployed := len(logger.StructLogs())
+	if deployed == 0 {
+		t.Fatalf("contract creation was not traced")
+	}
+	sim.Commit()
+	if have := len(logger.StructLogs()); have != deployed {
+		t.Errorf("block import was traced: have %d steps, want %d", have, deployed)
+	}
+	// Calls should be traced until the tracer is detached
+	call := ethereum.CallMsg{From: testAddr, To: &addr}
+	if _, err := sim.CallContract(bgCtx, call, nil); err != nil {
+		t.Fatalf("could not call contract: %v", err)
+	}
+	called := len(logger.StructLogs())
+	if called == deployed {
+		t.Errorf("contract call was not traced")
+	}
+	sim.SetTracer(nil)
+	if _, err := sim.CallContract(bgCtx, call, nil); err != nil {
+		t.Fatalf("could not call contract: %v", err)
+	}
+	if have := len(logger.StructLogs()); have != called {
+		t.Errorf("contract call traced after detaching: have %d steps, want %d", have, called)
+	}
+}
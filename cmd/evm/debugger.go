@@ -0,0 +1,479 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/t8ntool"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var debugCommand = cli.Command{
+	Action:    debugCmd,
+	Name:      "debug",
+	Usage:     "interactively steps through an evm execution",
+	ArgsUsage: "<code>",
+	Flags: []cli.Flag{
+		t8ntool.InputAllocFlag,
+		t8ntool.InputEnvFlag,
+		t8ntool.InputTxsFlag,
+		t8ntool.ForknameFlag,
+		t8ntool.ChainIDFlag,
+		t8ntool.RewardFlag,
+		DebugTxFlag,
+	},
+	Description: `
+The debug command executes EVM code the same way the run command does, or one
+transaction of a state transition if --input.txs is given, and opens a prompt
+to step forwards and backwards through the recorded execution. Type 'help' at
+the prompt for the list of commands.`,
+}
+
+const debugHelp = `Navigation:
+  s, step [n]              step forwards n instructions (default 1)
+  sb, back [n]             step backwards n instructions (default 1)
+  c, continue              run forwards to the next breakpoint
+  rc, reverse-continue     run backwards to the previous breakpoint
+  g, goto <step>           jump to the given step
+Breakpoints:
+  b, break pc <pc>         break at a program counter
+  b, break op <opcode>     break at an opcode
+  b, break depth <depth>   break when entering a call depth
+  b, break slot <slot>     break when a storage slot is loaded or stored
+  d, delete <id>           delete a breakpoint
+  info                     show the breakpoints and the execution result
+Inspection:
+  p, print                 show the current instruction
+  stack                    show the stack
+  memory                   show the memory
+  storage                  show the storage accessed so far
+  return                   show the return data of the last call
+  q, quit                  exit the debugger
+An empty line repeats the last command.
+`
+
+func debugCmd(ctx *cli.Context) error {
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
+	log.Root().SetHandler(glogger)
+
+	// Record the whole execution up front, the debugger only ever replays it
+	var (
+		logger  = vm.NewStructLogger(&vm.LogConfig{})
+		tracer  = vm.Tracer(logger)
+		sources *tracers.SourceTracer
+	)
+	if path := ctx.GlobalString(SourceMapFlag.Name); path != "" {
+		var err error
+		if sources, err = newSourceTracer(path, logger); err != nil {
+			return err
+		}
+		tracer = sources
+	}
+	var err error
+	if ctx.IsSet(t8ntool.InputTxsFlag.Name) {
+		err = recordTransition(ctx, tracer)
+	} else {
+		err = recordCode(ctx, tracer)
+	}
+	if err != nil {
+		return err
+	}
+	if len(logger.StructLogs()) == 0 {
+		return errors.New("nothing to debug, no instructions were executed")
+	}
+	session := newDebugSession(logger.StructLogs(), logger.Output(), logger.Error())
+	if sources != nil {
+		session.locations = sources.Steps
+	}
+	return session.run(prompt.Stdin, os.Stdout)
+}
+
+// recordCode executes the code configured the same way as for `evm run`.
+func recordCode(ctx *cli.Context, tracer vm.Tracer) error {
+	setup, err := newRunSetup(ctx)
+	if err != nil {
+		return err
+	}
+	setup.config.EVMConfig.Tracer, setup.config.EVMConfig.Debug = tracer, true
+
+	// Execution errors are captured by the tracer and shown by the debugger
+	setup.exec()
+	return nil
+}
+
+// recordTransition executes a state transition the same way as `evm t8n`,
+// tracing only the selected transaction.
+func recordTransition(ctx *cli.Context, tracer vm.Tracer) error {
+	for _, flag := range []cli.StringFlag{t8ntool.InputAllocFlag, t8ntool.InputEnvFlag, t8ntool.InputTxsFlag} {
+		if ctx.String(flag.Name) == "stdin" {
+			return fmt.Errorf("--%s: the debugger reads its commands from stdin", flag.Name)
+		}
+	}
+	prestate, txs, chainConfig, vmConfig, err := t8ntool.LoadInput(ctx)
+	if err != nil {
+		return err
+	}
+	index := ctx.Int(DebugTxFlag.Name)
+	if index < 0 || index >= len(txs) {
+		return fmt.Errorf("transaction index %d out of range, have %d transactions", index, len(txs))
+	}
+	hash := txs[index].Hash()
+	getTracer := func(txIndex int, txHash common.Hash) (vm.Tracer, error) {
+		if txHash == hash {
+			return tracer, nil
+		}
+		return nil, nil
+	}
+	_, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(t8ntool.RewardFlag.Name), getTracer)
+	if err != nil {
+		return err
+	}
+	for _, rejected := range result.Rejected {
+		if rejected == index {
+			return fmt.Errorf("transaction %d (%x) was rejected", index, hash)
+		}
+	}
+	return nil
+}
+
+// breakpoint is a condition to stop at when running through an execution.
+type breakpoint struct {
+	id    int
+	kind  string // One of pc, op, depth or slot
+	pc    uint64
+	op    vm.OpCode
+	depth int
+	slot  common.Hash
+}
+
+// parseBreakpoint creates a breakpoint of the given kind from its textual value.
+func parseBreakpoint(kind, value string) (*breakpoint, error) {
+	bp := &breakpoint{kind: kind}
+	switch kind {
+	case "pc":
+		pc, ok := math.ParseUint64(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid program counter %q", value)
+		}
+		bp.pc = pc
+	case "op":
+		name := strings.ToUpper(value)
+		if bp.op = vm.StringToOp(name); bp.op.String() != name {
+			return nil, fmt.Errorf("unknown opcode %q", value)
+		}
+	case "depth":
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("invalid call depth %q", value)
+		}
+		bp.depth = depth
+	case "slot":
+		slot, ok := math.ParseBig256(value)
+		if !ok {
+			return nil, fmt.Errorf("invalid storage slot %q", value)
+		}
+		bp.slot = common.BigToHash(slot)
+	default:
+		return nil, fmt.Errorf("unknown breakpoint kind %q, want pc, op, depth or slot", kind)
+	}
+	return bp, nil
+}
+
+// matches returns whether the breakpoint triggers at the given step.
+func (bp *breakpoint) matches(logs []vm.StructLog, step int) bool {
+	log := &logs[step]
+	switch bp.kind {
+	case "pc":
+		return log.Pc == bp.pc
+	case "op":
+		return log.Op == bp.op
+	case "depth":
+		return log.Depth == bp.depth && (step == 0 || logs[step-1].Depth != bp.depth)
+	case "slot":
+		if (log.Op != vm.SLOAD && log.Op != vm.SSTORE) || len(log.Stack) == 0 {
+			return false
+		}
+		return common.BigToHash(log.Stack[len(log.Stack)-1]) == bp.slot
+	}
+	return false
+}
+
+// String implements fmt.Stringer.
+func (bp *breakpoint) String() string {
+	switch bp.kind {
+	case "pc":
+		return fmt.Sprintf("pc %d", bp.pc)
+	case "op":
+		return fmt.Sprintf("op %v", bp.op)
+	case "depth":
+		return fmt.Sprintf("depth %d", bp.depth)
+	default:
+		return fmt.Sprintf("slot %x", bp.slot)
+	}
+}
+
+// debugSession is a recorded execution that can be navigated in both directions.
+type debugSession struct {
+	logs      []vm.StructLog
+	locations []*compiler.SourceLocation // Source location of each step, if known
+	output    []byte
+	err       error
+
+	step        int // Index of the current step
+	breakpoints []*breakpoint
+	nextID      int
+}
+
+// newDebugSession creates a debug session over the steps of an execution,
+// positioned at its first step.
+func newDebugSession(logs []vm.StructLog, output []byte, err error) *debugSession {
+	return &debugSession{logs: logs, output: output, err: err, nextID: 1}
+}
+
+// seek moves to the given step, clamped to the bounds of the execution.
+func (s *debugSession) seek(step int) {
+	switch {
+	case step < 0:
+		s.step = 0
+	case step >= len(s.logs):
+		s.step = len(s.logs) - 1
+	default:
+		s.step = step
+	}
+}
+
+// hit returns the first breakpoint triggering at the given step, if any.
+func (s *debugSession) hit(step int) *breakpoint {
+	for _, bp := range s.breakpoints {
+		if bp.matches(s.logs, step) {
+			return bp
+		}
+	}
+	return nil
+}
+
+// forward moves to the next step a breakpoint triggers at, or to the last step
+// if there is none.
+func (s *debugSession) forward() *breakpoint {
+	for step := s.step + 1; step < len(s.logs); step++ {
+		if bp := s.hit(step); bp != nil {
+			s.step = step
+			return bp
+		}
+	}
+	s.step = len(s.logs) - 1
+	return nil
+}
+
+// backward moves to the previous step a breakpoint triggers at, or to the first
+// step if there is none.
+func (s *debugSession) backward() *breakpoint {
+	for step := s.step - 1; step >= 0; step-- {
+		if bp := s.hit(step); bp != nil {
+			s.step = step
+			return bp
+		}
+	}
+	s.step = 0
+	return nil
+}
+
+// run reads commands from the prompter until the user quits.
+func (s *debugSession) run(prompter prompt.UserPrompter, out io.Writer) error {
+	fmt.Fprintf(out, "Recorded %d steps, type 'help' for the list of commands\n", len(s.logs))
+	s.printStep(out)
+
+	var last string
+	for {
+		line, err := prompter.PromptInput("debug> ")
+		if err != nil {
+			return nil // Interrupted or end of input
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			line = last
+		} else {
+			prompter.AppendHistory(line)
+		}
+		if line == "" {
+			continue
+		}
+		last = line
+
+		quit, err := s.execute(line, out)
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// execute runs a single debugger command, returning whether the user quit.
+func (s *debugSession) execute(line string, out io.Writer) (bool, error) {
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "s", "step", "sb", "back":
+		n := 1
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+				return false, fmt.Errorf("invalid step count %q", args[0])
+			}
+		}
+		if cmd == "sb" || cmd == "back" {
+			n = -n
+		}
+		s.seek(s.step + n)
+		s.printStep(out)
+
+	case "c", "continue", "rc", "reverse-continue":
+		var bp *breakpoint
+		if cmd == "c" || cmd == "continue" {
+			bp = s.forward()
+		} else {
+			bp = s.backward()
+		}
+		switch {
+		case bp != nil:
+			fmt.Fprintf(out, "Breakpoint %d: %v\n", bp.id, bp)
+		case s.step == 0:
+			fmt.Fprintln(out, "Reached start of execution")
+		default:
+			fmt.Fprintln(out, "Reached end of execution")
+		}
+		s.printStep(out)
+
+	case "g", "goto":
+		if len(args) != 1 {
+			return false, errors.New("usage: goto <step>")
+		}
+		step, err := strconv.Atoi(args[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid step %q", args[0])
+		}
+		s.seek(step)
+		s.printStep(out)
+
+	case "b", "break":
+		if len(args) != 2 {
+			return false, errors.New("usage: break pc|op|depth|slot <value>")
+		}
+		bp, err := parseBreakpoint(args[0], args[1])
+		if err != nil {
+			return false, err
+		}
+		bp.id, s.nextID = s.nextID, s.nextID+1
+		s.breakpoints = append(s.breakpoints, bp)
+		fmt.Fprintf(out, "Breakpoint %d: %v\n", bp.id, bp)
+
+	case "d", "delete":
+		if len(args) != 1 {
+			return false, errors.New("usage: delete <id>")
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return false, fmt.Errorf("invalid breakpoint id %q", args[0])
+		}
+		for i, bp := range s.breakpoints {
+			if bp.id == id {
+				s.breakpoints = append(s.breakpoints[:i], s.breakpoints[i+1:]...)
+				return false, nil
+			}
+		}
+		return false, fmt.Errorf("no breakpoint %d", id)
+
+	case "info":
+		for _, bp := range s.breakpoints {
+			fmt.Fprintf(out, "Breakpoint %d: %v\n", bp.id, bp)
+		}
+		fmt.Fprintf(out, "Output: 0x%x\n", s.output)
+		if s.err != nil {
+			fmt.Fprintf(out, "Error: %v\n", s.err)
+		}
+
+	case "p", "print":
+		s.printStep(out)
+
+	case "stack":
+		stack := s.logs[s.step].Stack
+		for i := len(stack) - 1; i >= 0; i-- {
+			fmt.Fprintf(out, "%08d  %x\n", len(stack)-i-1, math.PaddedBigBytes(stack[i], 32))
+		}
+
+	case "memory":
+		fmt.Fprint(out, hex.Dump(s.logs[s.step].Memory))
+
+	case "storage":
+		storage := s.logs[s.step].Storage
+		slots := make([]common.Hash, 0, len(storage))
+		for slot := range storage {
+			slots = append(slots, slot)
+		}
+		sort.Slice(slots, func(i, j int) bool { return slots[i].Big().Cmp(slots[j].Big()) < 0 })
+		for _, slot := range slots {
+			fmt.Fprintf(out, "%x: %x\n", slot, storage[slot])
+		}
+
+	case "return":
+		fmt.Fprint(out, hex.Dump(s.logs[s.step].ReturnData))
+
+	case "h", "help":
+		fmt.Fprint(out, debugHelp)
+
+	case "q", "quit", "exit":
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("unknown command %q, type 'help' for the list of commands", cmd)
+	}
+	return false, nil
+}
+
+// printStep writes a summary of the current step.
+func (s *debugSession) printStep(out io.Writer) {
+	log := &s.logs[s.step]
+	fmt.Fprintf(out, "[%d/%d] depth=%d %-16spc=%08d gas=%v cost=%v", s.step, len(s.logs)-1, log.Depth, log.Op, log.Pc, log.Gas, log.GasCost)
+	if log.Err != nil {
+		fmt.Fprintf(out, " ERROR: %v", log.Err)
+	}
+	fmt.Fprintln(out)
+	if s.step < len(s.locations) && s.locations[s.step] != nil {
+		fmt.Fprintf(out, "Source: %v\n", s.locations[s.step])
+	}
+	if s.step == len(s.logs)-1 && s.err != nil {
+		fmt.Fprintf(out, "Execution failed: %v\n", s.err)
+	}
+}
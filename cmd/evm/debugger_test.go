@@ -0,0 +1,72 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func TestDebugSessionBreakpoints(t *testing.T) {
+	logs := []vm.StructLog{
+		{Pc: 0, Op: vm.PUSH1, Depth: 1},
+		{Pc: 2, Op: vm.SLOAD, Depth: 1, Stack: []*big.Int{big.NewInt(7)}},
+		{Pc: 3, Op: vm.CALL, Depth: 1},
+		{Pc: 0, Op: vm.PUSH1, Depth: 2},
+		{Pc: 2, Op: vm.SSTORE, Depth: 2, Stack: []*big.Int{big.NewInt(1), big.NewInt(7)}},
+		{Pc: 3, Op: vm.STOP, Depth: 2},
+		{Pc: 4, Op: vm.STOP, Depth: 1},
+	}
+	session := newDebugSession(logs, nil, nil)
+	for _, cmd := range []string{"break slot 0x07", "break depth 2", "break op stop"} {
+		if _, err := session.execute(cmd, ioutil.Discard); err != nil {
+			t.Fatalf("%q: %v", cmd, err)
+		}
+	}
+	// Run forwards through all the breakpoints and back again
+	forward := []int{1, 3, 4, 5, 6, 6}
+	for i, want := range forward {
+		session.forward()
+		if session.step != want {
+			t.Errorf("continue %d: step mismatch: have %d, want %d", i, session.step, want)
+		}
+	}
+	backward := []int{5, 4, 3, 1, 0}
+	for i, want := range backward {
+		session.backward()
+		if session.step != want {
+			t.Errorf("reverse-continue %d: step mismatch: have %d, want %d", i, session.step, want)
+		}
+	}
+	// Stepping is clamped to the execution, deleted breakpoints are skipped
+	session.execute("back 3", ioutil.Discard)
+	if session.step != 0 {
+		t.Errorf("back: step mismatch: have %d, want 0", session.step)
+	}
+	session.execute("delete 1", ioutil.Discard)
+	if bp := session.forward(); bp == nil || bp.id != 2 || session.step != 3 {
+		t.Errorf("continue after delete: have step %d, breakpoint %v", session.step, bp)
+	}
+	for _, cmd := range []string{"break op FOO", "break pc x", "break gas 1", "delete 9"} {
+		if _, err := session.execute(cmd, ioutil.Discard); err == nil {
+			t.Errorf("%q: expected error", cmd)
+		}
+	}
+}
@@ -123,12 +123,33 @@ func Main(ctx *cli.Context) error {
 			return nil, nil
 		}
 	}
+	prestate, txs, chainConfig, vmConfig, err := LoadInput(ctx)
+	if err != nil {
+		return err
+	}
+	vmConfig.Tracer, vmConfig.Debug = tracer, (tracer != nil)
+
+	// Run the test and aggregate the result
+	state, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), getTracer)
+	if err != nil {
+		return err
+	}
+	// Dump the excution result
+	//postAlloc := state.DumpGenesisFormat(false, false, false)
+	collector := make(Alloc)
+	state.DumpToCollector(collector, false, false, false, nil, -1)
+	return dispatchOutput(ctx, baseDir, result, collector)
+
+}
+
+// LoadInput reads the prestate and the transactions to apply from the alloc, env
+// and txs inputs, along with the chain and VM configuration of the selected fork.
+func LoadInput(ctx *cli.Context) (*Prestate, types.Transactions, *params.ChainConfig, vm.Config, error) {
 	// We need to load three things: alloc, env and transactions. May be either in
 	// stdin input or in files.
 	// Check if anything needs to be read from stdin
 	var (
-		prestate Prestate
-		txs      types.Transactions // txs to apply
+		prestate = new(Prestate)
 		allocStr = ctx.String(InputAllocFlag.Name)
 
 		envStr    = ctx.String(InputEnvFlag.Name)
@@ -143,25 +164,25 @@ func Main(ctx *cli.Context) error {
 	if allocStr != stdinSelector {
 		inFile, err := os.Open(allocStr)
 		if err != nil {
-			return NewError(ErrorIO, fmt.Errorf("failed reading alloc file: %v", err))
+			return nil, nil, nil, vm.Config{}, NewError(ErrorIO, fmt.Errorf("failed reading alloc file: %v", err))
 		}
 		defer inFile.Close()
 		decoder := json.NewDecoder(inFile)
 		if err := decoder.Decode(&inputData.Alloc); err != nil {
-			return NewError(ErrorJson, fmt.Errorf("Failed unmarshaling alloc-file: %v", err))
+			return nil, nil, nil, vm.Config{}, NewError(ErrorJson, fmt.Errorf("Failed unmarshaling alloc-file: %v", err))
 		}
 	}
 
 	if envStr != stdinSelector {
 		inFile, err := os.Open(envStr)
 		if err != nil {
-			return NewError(ErrorIO, fmt.Errorf("failed reading env file: %v", err))
+			return nil, nil, nil, vm.Config{}, NewError(ErrorIO, fmt.Errorf("failed reading env file: %v", err))
 		}
 		defer inFile.Close()
 		decoder := json.NewDecoder(inFile)
 		var env stEnv
 		if err := decoder.Decode(&env); err != nil {
-			return NewError(ErrorJson, fmt.Errorf("Failed unmarshaling env-file: %v", err))
+			return nil, nil, nil, vm.Config{}, NewError(ErrorJson, fmt.Errorf("Failed unmarshaling env-file: %v", err))
 		}
 		inputData.Env = &env
 	}
@@ -169,30 +190,25 @@ func Main(ctx *cli.Context) error {
 	if txStr != stdinSelector {
 		inFile, err := os.Open(txStr)
 		if err != nil {
-			return NewError(ErrorIO, fmt.Errorf("failed reading txs file: %v", err))
+			return nil, nil, nil, vm.Config{}, NewError(ErrorIO, fmt.Errorf("failed reading txs file: %v", err))
 		}
 		defer inFile.Close()
 		decoder := json.NewDecoder(inFile)
 		var txs types.Transactions
 		if err := decoder.Decode(&txs); err != nil {
-			return NewError(ErrorJson, fmt.Errorf("Failed unmarshaling txs-file: %v", err))
+			return nil, nil, nil, vm.Config{}, NewError(ErrorJson, fmt.Errorf("Failed unmarshaling txs-file: %v", err))
 		}
 		inputData.Txs = txs
 	}
 
 	prestate.Pre = inputData.Alloc
 	prestate.Env = *inputData.Env
-	txs = inputData.Txs
 
-	// Iterate over all the tests, run them and aggregate the results
-	vmConfig := vm.Config{
-		Tracer: tracer,
-		Debug:  (tracer != nil),
-	}
+	var vmConfig vm.Config
 	// Construct the chainconfig
 	var chainConfig *params.ChainConfig
 	if cConf, extraEips, err := tests.GetChainConfig(ctx.String(ForknameFlag.Name)); err != nil {
-		return NewError(ErrorVMConfig, fmt.Errorf("Failed constructing chain configuration: %v", err))
+		return nil, nil, nil, vm.Config{}, NewError(ErrorVMConfig, fmt.Errorf("Failed constructing chain configuration: %v", err))
 	} else {
 		chainConfig = cConf
 		vmConfig.ExtraEips = extraEips
@@ -200,17 +216,7 @@ func Main(ctx *cli.Context) error {
 	// Set the chain id
 	chainConfig.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
 
-	// Run the test and aggregate the result
-	state, result, err := prestate.Apply(vmConfig, chainConfig, txs, ctx.Int64(RewardFlag.Name), getTracer)
-	if err != nil {
-		return err
-	}
-	// Dump the excution result
-	//postAlloc := state.DumpGenesisFormat(false, false, false)
-	collector := make(Alloc)
-	state.DumpToCollector(collector, false, false, false, nil, -1)
-	return dispatchOutput(ctx, baseDir, result, collector)
-
+	return prestate, inputData.Txs, chainConfig, vmConfig, nil
 }
 
 type Alloc map[common.Address]core.GenesisAccount
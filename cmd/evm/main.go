@@ -46,6 +46,18 @@ var (
 		Name:  "cpuprofile",
 		Usage: "creates a CPU profile at the given path",
 	}
+	ProfileFlag = cli.StringFlag{
+		Name:  "profile",
+		Usage: "write a gas profile in collapsed stack format to the given file, and its JSON summary to <file>.json",
+	}
+	SourceMapFlag = cli.StringFlag{
+		Name:  "sourcemap",
+		Usage: "solc --combined-json output to annotate the trace and reverts with source locations",
+	}
+	CoverageFlag = cli.StringFlag{
+		Name:  "coverage",
+		Usage: "write the code coverage of state tests to the given file, as lcov if --sourcemap is set or raw JSON otherwise",
+	}
 	StatDumpFlag = cli.BoolFlag{
 		Name:  "statdump",
 		Usage: "displays stack and heap memory information",
@@ -134,6 +146,10 @@ var (
 		Usage: "External EVM configuration (default = built-in interpreter)",
 		Value: "",
 	}
+	DebugTxFlag = cli.IntFlag{
+		Name:  "tx",
+		Usage: "index of the transaction to debug when given a state transition",
+	}
 )
 
 var stateTransitionCommand = cli.Command{
@@ -175,6 +191,9 @@ func init() {
 		InputFileFlag,
 		MemProfileFlag,
 		CPUProfileFlag,
+		ProfileFlag,
+		SourceMapFlag,
+		CoverageFlag,
 		StatDumpFlag,
 		GenesisFlag,
 		MachineFlag,
@@ -188,6 +207,7 @@ func init() {
 	}
 	app.Commands = []cli.Command{
 		compileCommand,
+		debugCommand,
 		disasmCommand,
 		runCommand,
 		stateTestCommand,
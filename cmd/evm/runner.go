@@ -19,10 +19,13 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"os"
+	"path/filepath"
 	goruntime "runtime"
 	"runtime/pprof"
 	"testing"
@@ -36,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"gopkg.in/urfave/cli.v1"
@@ -116,13 +120,8 @@ func runCmd(ctx *cli.Context) error {
 	}
 
 	var (
-		tracer        vm.Tracer
-		debugLogger   *vm.StructLogger
-		statedb       *state.StateDB
-		chainConfig   *params.ChainConfig
-		sender        = common.BytesToAddress([]byte("sender"))
-		receiver      = common.BytesToAddress([]byte("receiver"))
-		genesisConfig *core.Genesis
+		tracer      vm.Tracer
+		debugLogger *vm.StructLogger
 	)
 	if ctx.GlobalBool(MachineFlag.Name) {
 		tracer = vm.NewJSONLogger(logconfig, os.Stdout)
@@ -132,6 +131,136 @@ func runCmd(ctx *cli.Context) error {
 	} else {
 		debugLogger = vm.NewStructLogger(logconfig)
 	}
+	var profiler *tracers.GasProfiler
+	if ctx.GlobalString(ProfileFlag.Name) != "" {
+		if tracer != nil || ctx.GlobalBool(BenchFlag.Name) {
+			return errors.New("--profile cannot be combined with --json, --debug or --bench")
+		}
+		profiler = tracers.NewGasProfiler()
+		tracer = profiler
+	}
+	var sources *tracers.SourceTracer
+	if path := ctx.GlobalString(SourceMapFlag.Name); path != "" {
+		var err error
+		if sources, err = newSourceTracer(path, tracer); err != nil {
+			return err
+		}
+	}
+	// Assemble the execution environment and run it with tracing configured
+	setup, err := newRunSetup(ctx)
+	if err != nil {
+		return err
+	}
+	statedb := setup.statedb
+
+	setup.config.EVMConfig.Tracer = tracer
+	setup.config.EVMConfig.Debug = ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name) || profiler != nil
+	if sources != nil {
+		setup.config.EVMConfig.Tracer, setup.config.EVMConfig.Debug = sources, true
+	}
+
+	if cpuProfilePath := ctx.GlobalString(CPUProfileFlag.Name); cpuProfilePath != "" {
+		f, err := os.Create(cpuProfilePath)
+		if err != nil {
+			fmt.Println("could not create CPU profile: ", err)
+			os.Exit(1)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Println("could not start CPU profile: ", err)
+			os.Exit(1)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	bench := ctx.GlobalBool(BenchFlag.Name)
+	output, leftOverGas, stats, err := timedExec(bench, setup.exec)
+
+	if ctx.GlobalBool(DumpFlag.Name) {
+		statedb.Commit(true)
+		statedb.IntermediateRoot(true)
+		fmt.Println(string(statedb.Dump(false, false, true)))
+	}
+
+	if memProfilePath := ctx.GlobalString(MemProfileFlag.Name); memProfilePath != "" {
+		f, err := os.Create(memProfilePath)
+		if err != nil {
+			fmt.Println("could not create memory profile: ", err)
+			os.Exit(1)
+		}
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			fmt.Println("could not write memory profile: ", err)
+			os.Exit(1)
+		}
+		f.Close()
+	}
+
+	if ctx.GlobalBool(DebugFlag.Name) {
+		if debugLogger != nil {
+			fmt.Fprintln(os.Stderr, "#### TRACE ####")
+			if sources != nil {
+				writeSourceTrace(os.Stderr, debugLogger.StructLogs(), sources)
+			} else {
+				vm.WriteTrace(os.Stderr, debugLogger.StructLogs())
+			}
+		}
+		fmt.Fprintln(os.Stderr, "#### LOGS ####")
+		vm.WriteLogs(os.Stderr, statedb.Logs())
+	}
+
+	if sources != nil && len(sources.Reverts) > 0 {
+		fmt.Fprintln(os.Stderr, "#### REVERTS ####")
+		for _, loc := range sources.Reverts {
+			if loc.Function != "" {
+				fmt.Fprintf(os.Stderr, "%v (in %s)\n", loc, loc.Function)
+			} else {
+				fmt.Fprintln(os.Stderr, loc)
+			}
+		}
+	}
+
+	if profiler != nil {
+		if err := writeProfile(ctx.GlobalString(ProfileFlag.Name), profiler); err != nil {
+			return err
+		}
+	}
+
+	if bench || ctx.GlobalBool(StatDumpFlag.Name) {
+		fmt.Fprintf(os.Stderr, `EVM gas used:    %d
+execution time:  %v
+allocations:     %d
+allocated bytes: %d
+`, setup.initialGas-leftOverGas, stats.time, stats.allocs, stats.bytesAllocated)
+	}
+	if tracer == nil || profiler != nil {
+		fmt.Printf("0x%x\n", output)
+		if err != nil {
+			fmt.Printf(" error: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// runSetup is the execution environment of an `evm run` style invocation.
+type runSetup struct {
+	statedb    *state.StateDB
+	config     runtime.Config
+	initialGas uint64
+	exec       func() ([]byte, uint64, error)
+}
+
+// newRunSetup assembles the state, the runtime configuration and the execution
+// of an `evm run` style invocation from the command line flags. The tracing
+// configuration is left for the caller to fill in.
+func newRunSetup(ctx *cli.Context) (*runSetup, error) {
+	var (
+		setup         = new(runSetup)
+		statedb       *state.StateDB
+		chainConfig   *params.ChainConfig
+		sender        = common.BytesToAddress([]byte("sender"))
+		receiver      = common.BytesToAddress([]byte("receiver"))
+		genesisConfig *core.Genesis
+	)
 	if ctx.GlobalString(GenesisFlag.Name) != "" {
 		gen := readGenesis(ctx.GlobalString(GenesisFlag.Name))
 		genesisConfig = gen
@@ -188,22 +317,22 @@ func runCmd(ctx *cli.Context) error {
 		// EASM-file to compile
 		src, err := ioutil.ReadFile(fn)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		bin, err := compiler.Compile(fn, src, false)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		code = common.Hex2Bytes(bin)
 	}
-	initialGas := ctx.GlobalUint64(GasFlag.Name)
+	setup.initialGas = ctx.GlobalUint64(GasFlag.Name)
 	if genesisConfig.GasLimit != 0 {
-		initialGas = genesisConfig.GasLimit
+		setup.initialGas = genesisConfig.GasLimit
 	}
-	runtimeConfig := runtime.Config{
+	setup.config = runtime.Config{
 		Origin:      sender,
 		State:       statedb,
-		GasLimit:    initialGas,
+		GasLimit:    setup.initialGas,
 		GasPrice:    utils.GlobalBig(ctx, PriceFlag.Name),
 		Value:       utils.GlobalBig(ctx, ValueFlag.Name),
 		Difficulty:  genesisConfig.Difficulty,
@@ -211,29 +340,14 @@ func runCmd(ctx *cli.Context) error {
 		Coinbase:    genesisConfig.Coinbase,
 		BlockNumber: new(big.Int).SetUint64(genesisConfig.Number),
 		EVMConfig: vm.Config{
-			Tracer:         tracer,
-			Debug:          ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
 			EVMInterpreter: ctx.GlobalString(EVMInterpreterFlag.Name),
 		},
 	}
 
-	if cpuProfilePath := ctx.GlobalString(CPUProfileFlag.Name); cpuProfilePath != "" {
-		f, err := os.Create(cpuProfilePath)
-		if err != nil {
-			fmt.Println("could not create CPU profile: ", err)
-			os.Exit(1)
-		}
-		if err := pprof.StartCPUProfile(f); err != nil {
-			fmt.Println("could not start CPU profile: ", err)
-			os.Exit(1)
-		}
-		defer pprof.StopCPUProfile()
-	}
-
 	if chainConfig != nil {
-		runtimeConfig.ChainConfig = chainConfig
+		setup.config.ChainConfig = chainConfig
 	} else {
-		runtimeConfig.ChainConfig = params.AllEthashProtocolChanges
+		setup.config.ChainConfig = params.AllEthashProtocolChanges
 	}
 
 	var hexInput []byte
@@ -248,66 +362,100 @@ func runCmd(ctx *cli.Context) error {
 	}
 	input := common.FromHex(string(bytes.TrimSpace(hexInput)))
 
-	var execFunc func() ([]byte, uint64, error)
 	if ctx.GlobalBool(CreateFlag.Name) {
 		input = append(code, input...)
-		execFunc = func() ([]byte, uint64, error) {
-			output, _, gasLeft, err := runtime.Create(input, &runtimeConfig)
+		setup.exec = func() ([]byte, uint64, error) {
+			output, _, gasLeft, err := runtime.Create(input, &setup.config)
 			return output, gasLeft, err
 		}
 	} else {
 		if len(code) > 0 {
 			statedb.SetCode(receiver, code)
 		}
-		execFunc = func() ([]byte, uint64, error) {
-			return runtime.Call(receiver, input, &runtimeConfig)
+		setup.exec = func() ([]byte, uint64, error) {
+			return runtime.Call(receiver, input, &setup.config)
 		}
 	}
+	setup.statedb = statedb
+	return setup, nil
+}
 
-	bench := ctx.GlobalBool(BenchFlag.Name)
-	output, leftOverGas, stats, err := timedExec(bench, execFunc)
-
-	if ctx.GlobalBool(DumpFlag.Name) {
-		statedb.Commit(true)
-		statedb.IntermediateRoot(true)
-		fmt.Println(string(statedb.Dump(false, false, true)))
+// writeProfile writes the gas profile of an execution in collapsed stack format
+// into the given file, and its JSON summary into a sibling file.
+func writeProfile(path string, profiler *tracers.GasProfiler) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create gas profile: %v", err)
 	}
+	defer f.Close()
 
-	if memProfilePath := ctx.GlobalString(MemProfileFlag.Name); memProfilePath != "" {
-		f, err := os.Create(memProfilePath)
-		if err != nil {
-			fmt.Println("could not create memory profile: ", err)
-			os.Exit(1)
-		}
-		if err := pprof.WriteHeapProfile(f); err != nil {
-			fmt.Println("could not write memory profile: ", err)
-			os.Exit(1)
-		}
-		f.Close()
+	if err := profiler.WriteFolded(f, false); err != nil {
+		return fmt.Errorf("could not write gas profile: %v", err)
+	}
+	summary, err := json.MarshalIndent(profiler.Profile(), "", "  ")
+	if err != nil {
+		return err
 	}
+	if err := ioutil.WriteFile(path+".json", summary, 0644); err != nil {
+		return fmt.Errorf("could not write gas profile summary: %v", err)
+	}
+	return nil
+}
 
-	if ctx.GlobalBool(DebugFlag.Name) {
-		if debugLogger != nil {
-			fmt.Fprintln(os.Stderr, "#### TRACE ####")
-			vm.WriteTrace(os.Stderr, debugLogger.StructLogs())
-		}
-		fmt.Fprintln(os.Stderr, "#### LOGS ####")
-		vm.WriteLogs(os.Stderr, statedb.Logs())
+// newSourceTracer wraps a tracer to resolve the executed steps against the
+// contracts of the given solc combined-json output.
+func newSourceTracer(path string, tracer vm.Tracer) (*tracers.SourceTracer, error) {
+	registry, err := loadSourceRegistry(path)
+	if err != nil {
+		return nil, err
 	}
+	return tracers.NewSourceTracer(tracer, registry), nil
+}
 
-	if bench || ctx.GlobalBool(StatDumpFlag.Name) {
-		fmt.Fprintf(os.Stderr, `EVM gas used:    %d
-execution time:  %v
-allocations:     %d
-allocated bytes: %d
-`, initialGas-leftOverGas, stats.time, stats.allocs, stats.bytesAllocated)
+// loadSourceRegistry creates a source registry of the contracts of the given solc
+// combined-json output.
+func loadSourceRegistry(path string) (*tracers.SourceRegistry, error) {
+	combinedJSON, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
-	if tracer == nil {
-		fmt.Printf("0x%x\n", output)
+	registry := tracers.NewSourceRegistry()
+	if err := registry.RegisterCombinedJSON(combinedJSON, filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// writeCoverage writes the collected code coverage into the given file, as lcov
+// if a source map is supplied or as raw per program counter hit counts otherwise.
+func writeCoverage(path string, coverage *tracers.CoverageTracer, sourcemap string) error {
+	if sourcemap == "" {
+		blob, err := json.MarshalIndent(coverage.Coverage(), "", "  ")
 		if err != nil {
-			fmt.Printf(" error: %v\n", err)
+			return err
 		}
+		return ioutil.WriteFile(path, blob, 0644)
+	}
+	registry, err := loadSourceRegistry(sourcemap)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not create coverage report: %v", err)
 	}
+	defer f.Close()
 
-	return nil
+	return coverage.WriteLCOV(f, registry)
+}
+
+// writeSourceTrace writes the structured logs of an execution, each followed by
+// the source location it maps to, if known.
+func writeSourceTrace(w io.Writer, logs []vm.StructLog, sources *tracers.SourceTracer) {
+	for i := range logs {
+		vm.WriteTrace(w, logs[i:i+1])
+		if i < len(sources.Steps) && sources.Steps[i] != nil {
+			fmt.Fprintf(w, "Source: %v\n", sources.Steps[i])
+		}
+	}
 }
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/tests"
 
@@ -79,6 +80,14 @@ func stateTestCmd(ctx *cli.Context) error {
 	default:
 		debugger = vm.NewStructLogger(config)
 	}
+	var coverage *tracers.CoverageTracer
+	if ctx.GlobalString(CoverageFlag.Name) != "" {
+		if tracer != nil {
+			return errors.New("--coverage cannot be combined with --json or --debug")
+		}
+		coverage = tracers.NewCoverageTracer()
+		tracer = coverage
+	}
 	// Load the test content from the input file
 	src, err := ioutil.ReadFile(ctx.Args().First())
 	if err != nil {
@@ -91,7 +100,7 @@ func stateTestCmd(ctx *cli.Context) error {
 	// Iterate over all the tests, run them and aggregate the results
 	cfg := vm.Config{
 		Tracer: tracer,
-		Debug:  ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
+		Debug:  ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name) || coverage != nil,
 	}
 	results := make([]StatetestResult, 0, len(tests))
 	for key, test := range tests {
@@ -123,6 +132,11 @@ func stateTestCmd(ctx *cli.Context) error {
 			}
 		}
 	}
+	if coverage != nil {
+		if err := writeCoverage(ctx.GlobalString(CoverageFlag.Name), coverage, ctx.GlobalString(SourceMapFlag.Name)); err != nil {
+			return err
+		}
+	}
 	out, _ := json.MarshalIndent(results, "", "  ")
 	fmt.Println(string(out))
 	return nil
@@ -0,0 +1,75 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/eth"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var balancesCommand = cli.Command{
+	Name:      "balances",
+	Usage:     "Manage the recorded balance changes of accounts",
+	ArgsUsage: "",
+	Category:  "BLOCKCHAIN COMMANDS",
+	Description: `
+The balances commands operate on the attributed balance changes of the accounts
+touched by imported blocks, which are recorded when running with --balancechanges.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "backfill",
+			Usage:     "Re-execute historical blocks to record their balance changes",
+			ArgsUsage: " ",
+			Action:    utils.MigrateFlags(backfillBalanceChanges),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.AncientFlag,
+				utils.CacheFlag,
+				utils.SyncModeFlag,
+				utils.RopstenFlag,
+				utils.RinkebyFlag,
+				utils.GoerliFlag,
+				utils.YoloV2Flag,
+				utils.LegacyTestnetFlag,
+				BackfillFromFlag,
+				BackfillToFlag,
+				BackfillWorkersFlag,
+				BackfillReexecFlag,
+			},
+			Description: `
+    geth balances backfill --from N --to M --workers K
+
+re-executes the blocks N to M (inclusive) on top of the locally available state
+and stores the balance changes of the accounts they touched. It's meant for blocks
+imported before --balancechanges was enabled, and for the blocks the node failed
+to record on import, whose range is logged on startup. The state preceding block
+N needs to be available, or regenerable by re-executing at most --reexec blocks.
+
+The state root of every re-executed block is verified against the header. The
+progress is checkpointed into the database, so an interrupted backfill is resumed
+by rerunning the command with the same range. The node must not be running.`,
+		},
+	},
+}
+
+// backfillBalanceChanges re-executes a range of the local chain, recording and
+// storing the balance changes of its blocks.
+func backfillBalanceChanges(ctx *cli.Context) error {
+	return runBackfill(ctx, eth.BackfillBalanceChanges)
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	BackfillFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to backfill",
+		Value: 1,
+	}
+	BackfillToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to backfill (default = current head)",
+	}
+	BackfillWorkersFlag = cli.IntFlag{
+		Name:  "workers",
+		Usage: "Number of blocks re-executed concurrently",
+		Value: runtime.NumCPU(),
+	}
+	BackfillReexecFlag = cli.Uint64Flag{
+		Name:  "reexec",
+		Usage: "Number of blocks to re-execute for regenerating the state preceding the range",
+		Value: 128,
+	}
+	innerTxCommand = cli.Command{
+		Name:      "innertx",
+		Usage:     "Manage the recorded inner transactions",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The innertx commands operate on the inner transactions (nested calls and contract
+creations) recorded for imported blocks when running with --innertx.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "backfill",
+				Usage:     "Re-execute historical blocks to record their inner transactions",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(backfillInnerTxs),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+					utils.RopstenFlag,
+					utils.RinkebyFlag,
+					utils.GoerliFlag,
+					utils.YoloV2Flag,
+					utils.LegacyTestnetFlag,
+					BackfillFromFlag,
+					BackfillToFlag,
+					BackfillWorkersFlag,
+					BackfillReexecFlag,
+				},
+				Description: `
+    geth innertx backfill --from N --to M --workers K
+
+re-executes the blocks N to M (inclusive) on top of the locally available state
+and stores the inner transactions recorded along the way. It's meant for nodes
+which enabled --innertx only after syncing. The state preceding block N needs to
+be available, or regenerable by re-executing at most --reexec blocks.
+
+The receipts root of every re-executed block is verified against the header. The
+progress is checkpointed into the database, so an interrupted backfill is resumed
+by rerunning the command with the same range. The node must not be running.
+The inner transaction indexes stall at the first section with unrecorded blocks,
+and catch up once the node is restarted after the backfill.`,
+			},
+		},
+	}
+)
+
+// backfillInnerTxs re-executes a range of the local chain, recording and storing
+// the inner transactions of its blocks.
+func backfillInnerTxs(ctx *cli.Context) error {
+	return runBackfill(ctx, eth.BackfillInnerTxs)
+}
+
+// runBackfill opens the local chain and runs a backfill over the block range set
+// by the command line flags, until finished or interrupted.
+func runBackfill(ctx *cli.Context, backfill func(chain *core.BlockChain, db ethdb.Database, from, to uint64, workers int, reexec uint64, stop <-chan struct{}) error) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack, false)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	from, to := ctx.Uint64(BackfillFromFlag.Name), ctx.Uint64(BackfillToFlag.Name)
+	if !ctx.IsSet(BackfillToFlag.Name) {
+		to = chain.CurrentBlock().NumberU64()
+	}
+	// Watch for Ctrl-C while the backfill is running, stopping at a checkpoint
+	var (
+		interrupt = make(chan os.Signal, 1)
+		stop      = make(chan struct{})
+		done      = make(chan struct{})
+	)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(done)
+
+	go func() {
+		select {
+		case <-interrupt:
+			log.Info("Interrupted during backfill, stopping at next block")
+			close(stop)
+		case <-done:
+		}
+	}()
+	return backfill(chain, chainDb, from, to, ctx.Int(BackfillWorkersFlag.Name), ctx.Uint64(BackfillReexecFlag.Name), stop)
+}
@@ -147,6 +147,10 @@ var (
 		utils.GoerliFlag,
 		utils.YoloV2Flag,
 		utils.VMEnableDebugFlag,
+		utils.InnerTxFlag,
+		utils.RevertReasonsFlag,
+		utils.BalanceChangesFlag,
+		utils.TokenTransfersFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
 		utils.FakePoWFlag,
@@ -167,6 +171,7 @@ var (
 		utils.HTTPPortFlag,
 		utils.HTTPCORSDomainFlag,
 		utils.HTTPVirtualHostsFlag,
+		utils.HTTPJWTAuthFlag,
 		utils.LegacyRPCEnabledFlag,
 		utils.LegacyRPCListenAddrFlag,
 		utils.LegacyRPCPortFlag,
@@ -185,12 +190,17 @@ var (
 		utils.WSApiFlag,
 		utils.LegacyWSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSJWTAuthFlag,
 		utils.LegacyWSAllowedOriginsFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCBatchRequestLimitFlag,
+		utils.RPCBatchResponseMaxSizeFlag,
+		utils.RPCJWTSecretFlag,
+		utils.RPCAccessControlFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -231,6 +241,10 @@ func init() {
 		dumpCommand,
 		dumpGenesisCommand,
 		inspectCommand,
+		// See innertxcmd.go:
+		innerTxCommand,
+		revertReasonCommand,
+		balancesCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
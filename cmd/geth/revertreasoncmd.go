@@ -0,0 +1,76 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/eth"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var revertReasonCommand = cli.Command{
+	Name:      "revertreason",
+	Usage:     "Manage the recorded revert reasons of failed transactions",
+	ArgsUsage: "",
+	Category:  "BLOCKCHAIN COMMANDS",
+	Description: `
+The revertreason commands operate on the data returned by transactions aborted by
+REVERT, which is recorded for imported blocks when running with --revertreasons and
+reported in their receipts.`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "backfill",
+			Usage:     "Re-execute historical blocks to record their revert reasons",
+			ArgsUsage: " ",
+			Action:    utils.MigrateFlags(backfillRevertReasons),
+			Category:  "BLOCKCHAIN COMMANDS",
+			Flags: []cli.Flag{
+				utils.DataDirFlag,
+				utils.AncientFlag,
+				utils.CacheFlag,
+				utils.SyncModeFlag,
+				utils.RopstenFlag,
+				utils.RinkebyFlag,
+				utils.GoerliFlag,
+				utils.YoloV2Flag,
+				utils.LegacyTestnetFlag,
+				BackfillFromFlag,
+				BackfillToFlag,
+				BackfillWorkersFlag,
+				BackfillReexecFlag,
+			},
+			Description: `
+    geth revertreason backfill --from N --to M --workers K
+
+re-executes the blocks N to M (inclusive) on top of the locally available state
+and stores the revert reasons of their failed transactions. It's meant for blocks
+imported before revert reasons were recorded, or synced without execution. The
+state preceding block N needs to be available, or regenerable by re-executing at
+most --reexec blocks.
+
+The receipts root of every re-executed block is verified against the header. The
+progress is checkpointed into the database, so an interrupted backfill is resumed
+by rerunning the command with the same range. The node must not be running.`,
+		},
+	},
+}
+
+// backfillRevertReasons re-executes a range of the local chain, recording and
+// storing the revert reasons of its failed transactions.
+func backfillRevertReasons(ctx *cli.Context) error {
+	return runBackfill(ctx, eth.BackfillRevertReasons)
+}
@@ -137,16 +137,22 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.HTTPApiFlag,
 			utils.HTTPCORSDomainFlag,
 			utils.HTTPVirtualHostsFlag,
+			utils.HTTPJWTAuthFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSJWTAuthFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCBatchRequestLimitFlag,
+			utils.RPCBatchResponseMaxSizeFlag,
+			utils.RPCJWTSecretFlag,
+			utils.RPCAccessControlFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -197,6 +203,10 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.InnerTxFlag,
+			utils.RevertReasonsFlag,
+			utils.BalanceChangesFlag,
+			utils.TokenTransfersFlag,
 			utils.EVMInterpreterFlag,
 			utils.EWASMInterpreterFlag,
 		},
@@ -454,6 +454,22 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	InnerTxFlag = cli.BoolFlag{
+		Name:  "innertx",
+		Usage: "Record the inner transactions (nested calls and creations) of imported blocks",
+	}
+	RevertReasonsFlag = cli.BoolFlag{
+		Name:  "revertreasons",
+		Usage: "Store the revert reasons of the failed transactions of imported blocks",
+	}
+	BalanceChangesFlag = cli.BoolFlag{
+		Name:  "balancechanges",
+		Usage: "Record the balance changes of imported blocks, broken down by cause",
+	}
+	TokenTransfersFlag = cli.BoolFlag{
+		Name:  "tokentransfers",
+		Usage: "Index the ERC-20, ERC-721 and ERC-1155 token transfers of the chain by holder",
+	}
 	InsecureUnlockAllowedFlag = cli.BoolFlag{
 		Name:  "allow-insecure-unlock",
 		Usage: "Allow insecure account unlocking when account-related RPCs are exposed by http",
@@ -468,6 +484,24 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: eth.DefaultConfig.RPCTxFeeCap,
 	}
+	RPCBatchRequestLimitFlag = cli.IntFlag{
+		Name:  "rpc.batch-request-limit",
+		Usage: "Maximum number of requests in a batch (0 = no limit)",
+		Value: node.DefaultConfig.BatchRequestLimit,
+	}
+	RPCBatchResponseMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.batch-response-max-size",
+		Usage: "Maximum number of bytes returned from a batched or single call (0 = no limit)",
+		Value: node.DefaultConfig.BatchResponseMaxSize,
+	}
+	RPCJWTSecretFlag = cli.StringFlag{
+		Name:  "rpc.jwtsecret",
+		Usage: "Path to a hex encoded 32 byte secret for JWT authentication of the RPC servers (default = inside the datadir, generated if missing)",
+	}
+	RPCAccessControlFlag = cli.StringFlag{
+		Name:  "rpc.accesscontrol",
+		Usage: "Path to a JSON file of API keys, allowed methods and quotas required by the HTTP-RPC and WS-RPC servers",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -519,6 +553,10 @@ var (
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	HTTPJWTAuthFlag = cli.BoolFlag{
+		Name:  "http.jwtauth",
+		Usage: "Require JWT authentication (HS256, see --rpc.jwtsecret) on the HTTP-RPC server",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -557,6 +595,10 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSJWTAuthFlag = cli.BoolFlag{
+		Name:  "ws.jwtauth",
+		Usage: "Require JWT authentication (HS256, see --rpc.jwtsecret) on the WS-RPC server",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -942,6 +984,9 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = SplitAndTrim(ctx.GlobalString(HTTPVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(HTTPJWTAuthFlag.Name) {
+		cfg.HTTPJWTAuth = ctx.GlobalBool(HTTPJWTAuthFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -991,6 +1036,9 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = SplitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSJWTAuthFlag.Name) {
+		cfg.WSJWTAuth = ctx.GlobalBool(WSJWTAuthFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -1005,6 +1053,23 @@ func setIPC(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setRPCLimits applies the batch and response size limits, the JWT secret and the
+// access control of the RPC endpoints from the command line flags.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBatchRequestLimitFlag.Name) {
+		cfg.BatchRequestLimit = ctx.GlobalInt(RPCBatchRequestLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchResponseMaxSizeFlag.Name) {
+		cfg.BatchResponseMaxSize = ctx.GlobalInt(RPCBatchResponseMaxSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(RPCJWTSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAccessControlFlag.Name) {
+		cfg.AccessControlFile = ctx.GlobalString(RPCAccessControlFlag.Name)
+	}
+}
+
 // setLes configures the les server and ultra light client settings from the command line flags.
 func setLes(ctx *cli.Context, cfg *eth.Config) {
 	if ctx.GlobalIsSet(LegacyLightServFlag.Name) {
@@ -1211,6 +1276,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setHTTP(ctx, cfg)
 	setGraphQL(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
@@ -1570,6 +1636,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(InnerTxFlag.Name) {
+		cfg.EnableInnerTxs = ctx.GlobalBool(InnerTxFlag.Name)
+	}
+	if ctx.GlobalIsSet(RevertReasonsFlag.Name) {
+		cfg.EnableRevertReasons = ctx.GlobalBool(RevertReasonsFlag.Name)
+	}
+	if ctx.GlobalIsSet(BalanceChangesFlag.Name) {
+		cfg.EnableBalanceChanges = ctx.GlobalBool(BalanceChangesFlag.Name)
+	}
+	if ctx.GlobalIsSet(TokenTransfersFlag.Name) {
+		cfg.EnableTokenTransfers = ctx.GlobalBool(TokenTransfersFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(EWASMInterpreterFlag.Name) {
 		cfg.EWASMInterpreter = ctx.GlobalString(EWASMInterpreterFlag.Name)
@@ -1855,6 +1933,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 		TrieTimeLimit:       eth.DefaultConfig.TrieTimeout,
 		SnapshotLimit:       eth.DefaultConfig.SnapshotCache,
 		Preimages:           ctx.GlobalBool(CachePreimagesFlag.Name),
+		RevertReasons:       ctx.GlobalBool(RevertReasonsFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
@@ -1869,7 +1948,10 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readOnly bool) (chain *core.B
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieDirtyLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	vmcfg := vm.Config{
+		EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name),
+		RecordInnerTxs:          ctx.GlobalBool(InnerTxFlag.Name),
+	}
 	var limit *uint64
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) && !readOnly {
 		l := ctx.GlobalUint64(TxLookupLimitFlag.Name)
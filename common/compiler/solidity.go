@@ -41,7 +41,8 @@ type solcOutput struct {
 		Bin, SrcMap, Abi, Devdoc, Userdoc, Metadata string
 		Hashes                                      map[string]string
 	}
-	Version string
+	SourceList []string `json:"sourceList"`
+	Version    string
 }
 
 func (s *Solidity) makeArgs() []string {
@@ -171,6 +172,7 @@ func ParseCombinedJSON(combinedJSON []byte, source string, languageVersion strin
 				UserDoc:         userdoc,
 				DeveloperDoc:    devdoc,
 				Metadata:        info.Metadata,
+				SourceList:      output.SourceList,
 			},
 		}
 	}
@@ -0,0 +1,276 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package compiler
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// declarationRegexp matches the contract and function-like declarations
+	// of Solidity sources which may enclose code mapped to by a source map.
+	declarationRegexp = regexp.MustCompile(`\b(?:(contract|library|interface)\s+([A-Za-z_$][\w$]*)|(function|modifier)\s+([A-Za-z_$][\w$]*)\s*\(|(constructor|fallback|receive)\s*\()`)
+
+	// maxSnippetLength is the maximum length of the source snippet reported for
+	// a location, longer ones are truncated.
+	maxSnippetLength = 120
+)
+
+// SourceMapEntry is a single decompressed element of a solc source map, mapping
+// one instruction to a range of a source file.
+type SourceMapEntry struct {
+	Start  int    // Byte offset of the range within the source file
+	Length int    // Length of the range in bytes
+	File   int    // Index of the source file in the source list, -1 if none
+	Jump   string // Whether the instruction jumps into (i) or out of (o) a function
+}
+
+// ParseSourceMap decompresses a solc source map, in which empty fields inherit
+// the value of the previous entry, into one entry per instruction.
+func ParseSourceMap(srcmap string) ([]SourceMapEntry, error) {
+	if srcmap == "" {
+		return nil, nil
+	}
+	var (
+		items   = strings.Split(srcmap, ";")
+		entries = make([]SourceMapEntry, len(items))
+		last    = SourceMapEntry{File: -1, Jump: "-"}
+	)
+	for i, item := range items {
+		fields := strings.Split(item, ":")
+		for j, field := range fields {
+			if field == "" {
+				continue
+			}
+			switch j {
+			case 0, 1, 2:
+				n, err := strconv.Atoi(field)
+				if err != nil {
+					return nil, fmt.Errorf("source map entry %d: invalid field %q", i, field)
+				}
+				switch j {
+				case 0:
+					last.Start = n
+				case 1:
+					last.Length = n
+				case 2:
+					last.File = n
+				}
+			case 3:
+				last.Jump = field
+			}
+		}
+		entries[i] = last
+	}
+	return entries, nil
+}
+
+// Source is a named source file of a compilation.
+type Source struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// SourceLocation is a position within a source file a program counter maps to.
+type SourceLocation struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Function string `json:"function,omitempty"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// String implements fmt.Stringer, formatting the location as file:line followed
+// by the source snippet.
+func (l *SourceLocation) String() string {
+	if l.Snippet == "" {
+		return fmt.Sprintf("%s:%d", l.File, l.Line)
+	}
+	return fmt.Sprintf("%s:%d %s", l.File, l.Line, l.Snippet)
+}
+
+// declaration is a contract or function-like declaration in a source file.
+type declaration struct {
+	name       string
+	start, end int
+}
+
+// sourceFile is a source file indexed for location lookups.
+type sourceFile struct {
+	name      string
+	content   string
+	lines     []int         // Byte offsets of the line starts
+	contracts []declaration // Contract declarations, in source order
+	functions []declaration // Function-like declarations, in source order
+}
+
+// newSourceFile indexes the lines and the declarations of a source file.
+func newSourceFile(src Source) *sourceFile {
+	file := &sourceFile{name: src.Name, content: src.Content, lines: []int{0}}
+	for i := 0; i < len(src.Content); i++ {
+		if src.Content[i] == '\n' {
+			file.lines = append(file.lines, i+1)
+		}
+	}
+	for _, match := range declarationRegexp.FindAllStringSubmatchIndex(src.Content, -1) {
+		end := matchBlock(src.Content, match[1])
+		if end < 0 {
+			continue // Declaration without a body
+		}
+		switch {
+		case match[2] >= 0:
+			file.contracts = append(file.contracts, declaration{src.Content[match[4]:match[5]], match[0], end})
+		case match[6] >= 0:
+			file.functions = append(file.functions, declaration{src.Content[match[8]:match[9]], match[0], end})
+		default:
+			file.functions = append(file.functions, declaration{src.Content[match[10]:match[11]], match[0], end})
+		}
+	}
+	return file
+}
+
+// matchBlock finds the body of the declaration starting at the given offset and
+// returns the offset of its closing brace, or -1 if the declaration has no body.
+// Comments and string literals are skipped over.
+func matchBlock(content string, offset int) int {
+	depth := 0
+	for i := offset; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(content[i+2:], "*/")
+			if end < 0 {
+				return -1
+			}
+			i += end + 3
+		case c == '"' || c == '\'':
+			for i++; i < len(content) && content[i] != c; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+		case c == ';' && depth == 0:
+			return -1
+		case c == '{':
+			depth++
+		case c == '}':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// enclosing returns the innermost declaration containing the given range.
+func enclosing(decls []declaration, start, end int) *declaration {
+	var found *declaration
+	for i := range decls {
+		if decls[i].start <= start && end <= decls[i].end+1 {
+			if found == nil || decls[i].end-decls[i].start < found.end-found.start {
+				found = &decls[i]
+			}
+		}
+	}
+	return found
+}
+
+// locate resolves a byte range of the source file into a location.
+func (f *sourceFile) locate(start, length int) *SourceLocation {
+	if start < 0 || start > len(f.content) {
+		return nil
+	}
+	end := start + length
+	if end > len(f.content) {
+		end = len(f.content)
+	}
+	line := sort.Search(len(f.lines), func(i int) bool { return f.lines[i] > start })
+	loc := &SourceLocation{
+		File:   f.name,
+		Line:   line,
+		Column: start - f.lines[line-1] + 1,
+	}
+	if fn := enclosing(f.functions, start, end); fn != nil {
+		loc.Function = fn.name
+		if c := enclosing(f.contracts, start, end); c != nil {
+			loc.Function = c.name + "." + fn.name
+		}
+	}
+	snippet := f.content[start:end]
+	if i := strings.IndexByte(snippet, '\n'); i >= 0 {
+		snippet = strings.TrimSpace(snippet[:i]) + " ..."
+	}
+	if len(snippet) > maxSnippetLength {
+		snippet = snippet[:maxSnippetLength] + " ..."
+	}
+	loc.Snippet = strings.TrimSpace(snippet)
+	return loc
+}
+
+// SourceMapper resolves the program counters of a contract's bytecode to the
+// locations in the sources the bytecode was compiled from.
+type SourceMapper struct {
+	instructions map[uint64]int // Instruction index of each program counter
+	entries      []SourceMapEntry
+	files        []*sourceFile
+}
+
+// NewSourceMapper creates a source mapper for the given bytecode from its solc
+// source map and the sources in the order of the compiler's source list.
+func NewSourceMapper(code []byte, srcmap string, sources []Source) (*SourceMapper, error) {
+	entries, err := ParseSourceMap(srcmap)
+	if err != nil {
+		return nil, err
+	}
+	m := &SourceMapper{
+		instructions: make(map[uint64]int),
+		entries:      entries,
+		files:        make([]*sourceFile, len(sources)),
+	}
+	for i, src := range sources {
+		m.files[i] = newSourceFile(src)
+	}
+	for pc, index := 0, 0; pc < len(code); index++ {
+		m.instructions[uint64(pc)] = index
+		if op := code[pc]; op >= 0x60 && op <= 0x7f { // PUSH1 ... PUSH32
+			pc += int(op-0x60) + 1
+		}
+		pc++
+	}
+	return m, nil
+}
+
+// Locate resolves the given program counter into a source location, or nil if
+// it doesn't map to any known source.
+func (m *SourceMapper) Locate(pc uint64) *SourceLocation {
+	index, ok := m.instructions[pc]
+	if !ok || index >= len(m.entries) {
+		return nil
+	}
+	entry := m.entries[index]
+	if entry.File < 0 || entry.File >= len(m.files) {
+		return nil
+	}
+	return m.files[entry.File].locate(entry.Start, entry.Length)
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSourceMap(t *testing.T) {
+	entries, err := ParseSourceMap("0:10:0:-;2:5;;:3:1:i;-1::-1:o")
+	if err != nil {
+		t.Fatalf("failed to parse source map: %v", err)
+	}
+	want := []SourceMapEntry{
+		{Start: 0, Length: 10, File: 0, Jump: "-"},
+		{Start: 2, Length: 5, File: 0, Jump: "-"},
+		{Start: 2, Length: 5, File: 0, Jump: "-"},
+		{Start: 2, Length: 3, File: 1, Jump: "i"},
+		{Start: -1, Length: 3, File: -1, Jump: "o"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries mismatch:\nhave %+v\nwant %+v", entries, want)
+	}
+	if _, err := ParseSourceMap("0:x:0"); err == nil {
+		t.Errorf("expected error for invalid source map")
+	}
+}
+
+const sourceMapTestSource = `pragma solidity ^0.6.0;
+
+contract Vault {
+    // Braces in comments { and strings are ignored
+    string constant name = "}";
+
+    function withdraw(uint amount) public {
+        require(amount > 0, "zero amount");
+    }
+}
+`
+
+func TestSourceMapperLocate(t *testing.T) {
+	var (
+		require = strings.Index(sourceMapTestSource, `require(amount > 0, "zero amount")`)
+		length  = len(`require(amount > 0, "zero amount")`)
+		srcmap  = fmt.Sprintf("0:%d:0:-;%d:%d:0:-;;0:0:-1:-", len(sourceMapTestSource), require, length)
+	)
+	// PUSH1 0x80, PUSH2 0x0001, JUMPDEST, REVERT
+	code := []byte{0x60, 0x80, 0x61, 0x00, 0x01, 0x5b, 0xfd}
+
+	mapper, err := NewSourceMapper(code, srcmap, []Source{{Name: "Vault.sol", Content: sourceMapTestSource}})
+	if err != nil {
+		t.Fatalf("failed to create source mapper: %v", err)
+	}
+	if loc := mapper.Locate(0); loc == nil || loc.Line != 1 || loc.Function != "" {
+		t.Errorf("pc 0: location mismatch: have %+v", loc)
+	}
+	want := &SourceLocation{
+		File:     "Vault.sol",
+		Line:     8,
+		Column:   9,
+		Function: "Vault.withdraw",
+		Snippet:  `require(amount > 0, "zero amount")`,
+	}
+	for _, pc := range []uint64{2, 5} {
+		if loc := mapper.Locate(pc); !reflect.DeepEqual(loc, want) {
+			t.Errorf("pc %d: location mismatch: have %+v, want %+v", pc, loc, want)
+		}
+	}
+	if have := want.String(); have != `Vault.sol:8 require(amount > 0, "zero amount")` {
+		t.Errorf("location string mismatch: have %s", have)
+	}
+	// Push data and unmapped instructions should not resolve
+	for _, pc := range []uint64{1, 3, 6, 7} {
+		if loc := mapper.Locate(pc); loc != nil {
+			t.Errorf("pc %d: unexpected location %+v", pc, loc)
+		}
+	}
+}
@@ -87,6 +87,7 @@ const (
 	blockCacheLimit     = 256
 	receiptsCacheLimit  = 32
 	txLookupCacheLimit  = 1024
+	revertReasonsLimit  = 256
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
@@ -130,6 +131,7 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	RevertReasons       bool          // Whether to store the revert reasons of failed transactions
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -195,6 +197,7 @@ type BlockChain struct {
 	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
 	blockCache    *lru.Cache     // Cache for the most recent entire blocks
 	txLookupCache *lru.Cache     // Cache for the most recent transaction lookup data.
+	revertReasons *lru.Cache     // Failed receipts of the most recent blocks, stored once they become canonical
 	futureBlocks  *lru.Cache     // future blocks are blocks added for later processing
 
 	quit          chan struct{}  // blockchain quit channel
@@ -226,6 +229,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
 	txLookupCache, _ := lru.New(txLookupCacheLimit)
+	revertReasons, _ := lru.New(revertReasonsLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
 
@@ -246,6 +250,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		receiptsCache:  receiptsCache,
 		blockCache:     blockCache,
 		txLookupCache:  txLookupCache,
+		revertReasons:  revertReasons,
 		futureBlocks:   futureBlocks,
 		engine:         engine,
 		vmConfig:       vmConfig,
@@ -581,6 +586,12 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 	}
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(db ethdb.KeyValueWriter, hash common.Hash, num uint64) {
+		// Revert reasons are keyed by transaction, delete them while the body is around
+		if body := rawdb.ReadBody(bc.db, hash, num); body != nil {
+			for _, tx := range body.Transactions {
+				rawdb.DeleteRevertReason(db, tx.Hash())
+			}
+		}
 		// Ignore the error here since light client won't hit this path
 		frozen, _ := bc.db.Ancients()
 		if num+1 <= frozen {
@@ -598,6 +609,9 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 			rawdb.DeleteBody(db, hash, num)
 			rawdb.DeleteReceipts(db, hash, num)
 		}
+		// Inner transactions and balance changes are never moved into the ancient store
+		rawdb.DeleteInnerTxs(db, hash, num)
+		rawdb.DeleteBalanceChanges(db, hash, num)
 		// Todo(rjl493456442) txlookup, bloombits, etc
 	}
 	// If SetHead was only called as a chain reparation method, try to skip
@@ -776,6 +790,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	batch := bc.db.NewBatch()
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
 	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	if receipts, ok := bc.revertReasons.Get(block.Hash()); ok {
+		rawdb.WriteRevertReasons(batch, receipts.(types.Receipts))
+	}
 	rawdb.WriteHeadBlockHash(batch, block.Hash())
 
 	// If the block is better than our head or is on a different chain, force update heads
@@ -931,6 +948,16 @@ func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	return receipts
 }
 
+// GetInnerTxsByHash retrieves the inner transactions recorded for all transactions
+// in a given block, or nil if they were not recorded when the block was imported.
+func (bc *BlockChain) GetInnerTxsByHash(hash common.Hash) []types.InnerTxs {
+	number := rawdb.ReadHeaderNumber(bc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadInnerTxs(bc.db, hash, *number)
+}
+
 // GetBlocksFromHash returns the block corresponding to hash and up to n-1 ancestors.
 // [deprecated by eth/62]
 func (bc *BlockChain) GetBlocksFromHash(hash common.Hash, n int) (blocks []*types.Block) {
@@ -1249,6 +1276,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 					// Wipe out canonical block data.
 					for _, nh := range deleted {
 						rawdb.DeleteBlockWithoutNumber(batch, nh.hash, nh.number)
+						rawdb.DeleteInnerTxs(batch, nh.hash, nh.number)
 						rawdb.DeleteCanonicalHash(batch, nh.number)
 					}
 					if err := batch.Write(); err != nil {
@@ -1259,6 +1287,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 					for _, nh := range deleted {
 						for _, hash := range rawdb.ReadAllHashes(bc.db, nh.number) {
 							rawdb.DeleteBlock(batch, hash, nh.number)
+							rawdb.DeleteInnerTxs(batch, hash, nh.number)
 						}
 					}
 					if err := batch.Write(); err != nil {
@@ -1311,12 +1340,14 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		// Wipe out canonical block data.
 		for _, nh := range deleted {
 			rawdb.DeleteBlockWithoutNumber(batch, nh.hash, nh.number)
+			rawdb.DeleteInnerTxs(batch, nh.hash, nh.number)
 			rawdb.DeleteCanonicalHash(batch, nh.number)
 		}
 		for _, block := range blockChain {
 			// Always keep genesis block in active database.
 			if block.NumberU64() != 0 {
 				rawdb.DeleteBlockWithoutNumber(batch, block.Hash(), block.NumberU64())
+				rawdb.DeleteInnerTxs(batch, block.Hash(), block.NumberU64())
 				rawdb.DeleteCanonicalHash(batch, block.NumberU64())
 			}
 		}
@@ -1329,6 +1360,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		for _, nh := range deleted {
 			for _, hash := range rawdb.ReadAllHashes(bc.db, nh.number) {
 				rawdb.DeleteBlock(batch, hash, nh.number)
+				rawdb.DeleteInnerTxs(batch, hash, nh.number)
 			}
 		}
 		for _, block := range blockChain {
@@ -1336,6 +1368,7 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			if block.NumberU64() != 0 {
 				for _, hash := range rawdb.ReadAllHashes(bc.db, block.NumberU64()) {
 					rawdb.DeleteBlock(batch, hash, block.NumberU64())
+					rawdb.DeleteInnerTxs(batch, hash, block.NumberU64())
 				}
 			}
 		}
@@ -1490,16 +1523,19 @@ func (bc *BlockChain) writeKnownBlock(block *types.Block) error {
 }
 
 // WriteBlockWithState writes the block and all associated state to the database.
-func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
+// The inner transactions are only written if non-nil, i.e. if they were recorded
+// while producing the block.
+func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, logs []*types.Log, innerTxs []types.InnerTxs, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
 
-	return bc.writeBlockWithState(block, receipts, logs, state, emitHeadEvent)
+	return bc.writeBlockWithState(block, receipts, logs, innerTxs, state, emitHeadEvent)
 }
 
 // writeBlockWithState writes the block and all associated state to the database,
-// but is expects the chain mutex to be held.
-func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
+// but is expects the chain mutex to be held. The inner transactions are only
+// written if non-nil, i.e. if they were recorded while processing the block.
+func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, logs []*types.Log, innerTxs []types.InnerTxs, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
@@ -1521,10 +1557,27 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	rawdb.WriteTd(blockBatch, block.Hash(), block.NumberU64(), externTd)
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
+	if innerTxs != nil {
+		rawdb.WriteInnerTxs(blockBatch, block.Hash(), block.NumberU64(), innerTxs)
+	}
 	rawdb.WritePreimages(blockBatch, state.Preimages())
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
+	// Revert reasons are keyed by transaction, so they are only stored for the
+	// canonical chain. Keep them around until the block is written as the head,
+	// either right away or by a later reorg.
+	if bc.cacheConfig.RevertReasons {
+		var failed types.Receipts
+		for _, receipt := range receipts {
+			if receipt.Status == types.ReceiptStatusFailed && len(receipt.RevertReason) > 0 {
+				failed = append(failed, receipt)
+			}
+		}
+		if len(failed) > 0 {
+			bc.revertReasons.Add(block.Hash(), failed)
+		}
+	}
 	// Commit all cached state changes into underlying memory database.
 	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
 	if err != nil {
@@ -1882,7 +1935,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 		}
 		// Process block using the parent state as reference point
 		substart := time.Now()
-		receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
+		receipts, logs, innerTxs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			atomic.StoreUint32(&followupInterrupt, 1)
@@ -1919,7 +1972,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, er
 
 		// Write the block to the chain and get the status.
 		substart = time.Now()
-		status, err := bc.writeBlockWithState(block, receipts, logs, statedb, false)
+		status, err := bc.writeBlockWithState(block, receipts, logs, innerTxs, statedb, false)
 		atomic.StoreUint32(&followupInterrupt, 1)
 		if err != nil {
 			return it.index, err
@@ -2227,6 +2280,16 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	} else {
 		log.Error("Impossible reorg, please file an issue", "oldnum", oldBlock.Number(), "oldhash", oldBlock.Hash(), "newnum", newBlock.Number(), "newhash", newBlock.Hash())
 	}
+	// Delete the revert reasons of all the dropped transactions, the ones also
+	// included in the new chain may have a different outcome. The reasons of the
+	// new chain are written along with its blocks.
+	reasonsBatch := bc.db.NewBatch()
+	for _, tx := range deletedTxs {
+		rawdb.DeleteRevertReason(reasonsBatch, tx.Hash())
+	}
+	if err := reasonsBatch.Write(); err != nil {
+		log.Crit("Failed to delete revert reasons", "err", err)
+	}
 	// Insert the new chain(except the head block(reverse order)),
 	// taking care of the proper incremental order.
 	for i := len(newChain) - 1; i >= 1; i-- {
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -149,7 +150,7 @@ func testBlockChainImport(chain types.Blocks, blockchain *BlockChain) error {
 		if err != nil {
 			return err
 		}
-		receipts, _, usedGas, err := blockchain.processor.Process(block, statedb, vm.Config{})
+		receipts, _, _, usedGas, err := blockchain.processor.Process(block, statedb, vm.Config{})
 		if err != nil {
 			blockchain.reportBlock(block, receipts, err)
 			return err
@@ -935,6 +936,113 @@ func TestChainTxReorgs(t *testing.T) {
 	}
 }
 
+// Tests that revert reasons are only stored when enabled and only for the
+// canonical chain, that reorgs replace the ones of the dropped transactions with
+// the ones of the new chain, and that rewinding the chain deletes them.
+func TestRevertReasonReorgs(t *testing.T) {
+	testRevertReasonReorgs(t, false)
+	testRevertReasonReorgs(t, true)
+}
+
+func testRevertReasonReorgs(t *testing.T, enabled bool) {
+	var (
+		key1, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		key2, _  = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr1    = crypto.PubkeyToAddress(key1.PublicKey)
+		addr2    = crypto.PubkeyToAddress(key2.PublicKey)
+		reverter = common.HexToAddress("0xdead")
+		db       = rawdb.NewMemoryDatabase()
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				addr1: {Balance: big.NewInt(params.Ether)},
+				addr2: {Balance: big.NewInt(params.Ether)},
+				// Reverts with the single byte 0x2a as its reason in block #1 only
+				reverter: {Balance: new(big.Int), Code: []byte{
+					byte(vm.NUMBER), byte(vm.PUSH1), 1, byte(vm.EQ), byte(vm.PUSH1), 8, byte(vm.JUMPI), byte(vm.STOP),
+					byte(vm.JUMPDEST), byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0, byte(vm.MSTORE8),
+					byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.REVERT),
+				}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	// Create three transactions, all of them failing when included in block #1:
+	//  - dropped: included in the original chain only
+	//  - kept:    included in the forked chain only
+	//  - shared:  included in both, but succeeding in the forked chain
+	dropped, _ := types.SignTx(types.NewTransaction(0, reverter, nil, 100000, big.NewInt(1), nil), signer, key1)
+	kept, _ := types.SignTx(types.NewTransaction(0, reverter, nil, 100000, big.NewInt(2), nil), signer, key1)
+	shared, _ := types.SignTx(types.NewTransaction(0, reverter, nil, 100000, big.NewInt(1), nil), signer, key2)
+
+	cacheConfig := &CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		RevertReasons:  enabled,
+	}
+	blockchain, _ := NewBlockChain(db, cacheConfig, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+
+	check := func(stage string, want map[*types.Transaction]bool) {
+		for tx, failed := range want {
+			reason := rawdb.ReadRevertReason(db, tx.Hash())
+			if failed && enabled {
+				if !bytes.Equal(reason, []byte{0x2a}) {
+					t.Errorf("enabled %v, %s: tx %x revert reason mismatch: have %x, want 2a", enabled, stage, tx.Hash(), reason)
+				}
+			} else if reason != nil {
+				t.Errorf("enabled %v, %s: tx %x unexpected revert reason: %x", enabled, stage, tx.Hash(), reason)
+			}
+		}
+	}
+	original, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, gen *BlockGen) {
+		if i == 0 {
+			gen.AddTx(dropped)
+			gen.AddTx(shared)
+		}
+		gen.OffsetTime(9) // Lower the block difficulty to simulate a weaker chain
+	})
+	if i, err := blockchain.InsertChain(original); err != nil {
+		t.Fatalf("failed to insert original chain[%d]: %v", i, err)
+	}
+	check("original", map[*types.Transaction]bool{dropped: true, shared: true, kept: false})
+
+	// Replace the original chain with a heavier one
+	forked, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, gen *BlockGen) {
+		switch i {
+		case 0:
+			gen.AddTx(kept)
+		case 1:
+			gen.AddTx(shared)
+		}
+	})
+	if i, err := blockchain.InsertChain(forked); err != nil {
+		t.Fatalf("failed to insert forked chain[%d]: %v", i, err)
+	}
+	check("forked", map[*types.Transaction]bool{dropped: false, shared: false, kept: true})
+
+	// Import a weaker side chain failing the shared transaction again
+	side, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, gen *BlockGen) {
+		gen.AddTx(shared)
+		gen.OffsetTime(9)
+	})
+	if i, err := blockchain.InsertChain(side); err != nil {
+		t.Fatalf("failed to insert side chain[%d]: %v", i, err)
+	}
+	if blockchain.CurrentBlock().Hash() != forked[2].Hash() {
+		t.Fatalf("side chain became canonical")
+	}
+	check("side", map[*types.Transaction]bool{dropped: false, shared: false, kept: true})
+
+	// Rewind the chain below the failed transactions
+	if err := blockchain.SetHead(0); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	check("rewound", map[*types.Transaction]bool{dropped: false, shared: false, kept: false})
+}
+
 func TestLogReorgs(t *testing.T) {
 	var (
 		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
@@ -3030,3 +3138,64 @@ func TestInitThenFailCreateContract(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the inner transactions recorded during block processing outlive the
+// migration of their block into the ancient store.
+func TestInnerTxsSurviveFreezing(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		caller  = common.HexToAddress("0xca")
+		callee  = common.HexToAddress("0xaa")
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address: {Balance: big.NewInt(params.Ether)},
+				// CALL(gas, 0xaa, 0, 0, 0, 0, 0)
+				caller: {Code: []byte{
+					byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+					byte(vm.PUSH1), 0xaa, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP),
+				}, Balance: new(big.Int)},
+			},
+		}
+		gendb   = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(gendb)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 8, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(address), caller, new(big.Int), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), frdir, "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer db.Close()
+
+	gspec.MustCommit(db)
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{RecordInnerTxs: true}, nil, nil)
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	// Move the bulk of the chain into the ancient store
+	db.(interface{ Freeze(threshold uint64) }).Freeze(2)
+	if frozen, _ := db.Ancients(); frozen < 2 {
+		t.Fatalf("blocks not frozen: have %d ancients", frozen)
+	}
+	for _, block := range blocks {
+		innerTxs := rawdb.ReadInnerTxs(db, block.Hash(), block.NumberU64())
+		if len(innerTxs) != 1 || len(innerTxs[0]) != 1 {
+			t.Fatalf("block #%d: inner txs mismatch: have %v, want one call", block.NumberU64(), innerTxs)
+		}
+		if inner := innerTxs[0][0]; inner.Type != "CALL" || inner.From != caller || inner.To != callee {
+			t.Errorf("block #%d: inner tx mismatch: have %s %x -> %x", block.NumberU64(), inner.Type, inner.From, inner.To)
+		}
+	}
+}
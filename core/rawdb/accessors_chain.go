@@ -268,6 +268,60 @@ func WriteFastTxLookupLimit(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadInnerTxBackfillCheckpoint retrieves the number of the next block whose inner
+// transactions are to be backfilled, or nil if no backfill is in progress.
+func ReadInnerTxBackfillCheckpoint(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(innerTxBackfillKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteInnerTxBackfillCheckpoint stores the number of the next block whose inner
+// transactions are to be backfilled into database.
+func WriteInnerTxBackfillCheckpoint(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(innerTxBackfillKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the inner transaction backfill checkpoint", "err", err)
+	}
+}
+
+// DeleteInnerTxBackfillCheckpoint removes the inner transaction backfill checkpoint
+// from the database, marking the backfill finished.
+func DeleteInnerTxBackfillCheckpoint(db ethdb.KeyValueWriter) {
+	if err := db.Delete(innerTxBackfillKey); err != nil {
+		log.Crit("Failed to delete the inner transaction backfill checkpoint", "err", err)
+	}
+}
+
+// ReadRevertReasonBackfillCheckpoint retrieves the number of the next block whose
+// revert reasons are to be backfilled, or nil if no backfill is in progress.
+func ReadRevertReasonBackfillCheckpoint(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(revertReasonBackfillKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteRevertReasonBackfillCheckpoint stores the number of the next block whose
+// revert reasons are to be backfilled into database.
+func WriteRevertReasonBackfillCheckpoint(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(revertReasonBackfillKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the revert reason backfill checkpoint", "err", err)
+	}
+}
+
+// DeleteRevertReasonBackfillCheckpoint removes the revert reason backfill checkpoint
+// from the database, marking the backfill finished.
+func DeleteRevertReasonBackfillCheckpoint(db ethdb.KeyValueWriter) {
+	if err := db.Delete(revertReasonBackfillKey); err != nil {
+		log.Crit("Failed to delete the revert reason backfill checkpoint", "err", err)
+	}
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	// First try to look up the data in ancient database. Extra hash
@@ -603,6 +657,12 @@ func ReadReceipts(db ethdb.Reader, hash common.Hash, number uint64, config *para
 		log.Error("Failed to derive block receipts fields", "hash", hash, "number", number, "err", err)
 		return nil
 	}
+	// Attach the revert reasons of failed transactions from their side table
+	for _, receipt := range receipts {
+		if len(receipt.PostState) == 0 && receipt.Status == types.ReceiptStatusFailed {
+			receipt.RevertReason = ReadRevertReason(db, receipt.TxHash)
+		}
+	}
 	return receipts
 }
 
@@ -630,6 +690,153 @@ func DeleteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
+// HasInnerTxs verifies whether the inner transactions of a block have been
+// recorded. Blocks imported while recording was disabled have none.
+func HasInnerTxs(db ethdb.Reader, hash common.Hash, number uint64) bool {
+	if has, err := db.Has(blockInnerTxsKey(number, hash)); !has || err != nil {
+		return false
+	}
+	return true
+}
+
+// ReadInnerTxsRLP retrieves the inner transactions of a block in RLP encoding.
+func ReadInnerTxsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	data, _ := db.Get(blockInnerTxsKey(number, hash))
+	return data
+}
+
+// ReadInnerTxs retrieves the inner transactions recorded while processing a
+// block, grouped by the transaction they belong to. Nil is returned if the
+// block's inner transactions were never recorded.
+func ReadInnerTxs(db ethdb.Reader, hash common.Hash, number uint64) []types.InnerTxs {
+	data := ReadInnerTxsRLP(db, hash, number)
+	if len(data) == 0 {
+		return nil
+	}
+	innerTxs := []types.InnerTxs{}
+	if err := rlp.DecodeBytes(data, &innerTxs); err != nil {
+		log.Error("Invalid inner transaction array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return innerTxs
+}
+
+// WriteInnerTxs stores the inner transactions of all the transactions in a block.
+func WriteInnerTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64, innerTxs []types.InnerTxs) {
+	bytes, err := rlp.EncodeToBytes(innerTxs)
+	if err != nil {
+		log.Crit("Failed to encode block inner transactions", "err", err)
+	}
+	if err := db.Put(blockInnerTxsKey(number, hash), bytes); err != nil {
+		log.Crit("Failed to store block inner transactions", "err", err)
+	}
+}
+
+// DeleteInnerTxs removes all inner transaction data associated with a block hash.
+// Inner transactions are kept in the key-value store also after the block is
+// frozen, so unlike the other block components they are not removed by
+// DeleteBlock and DeleteBlockWithoutNumber, but by the callers dropping the
+// block from the chain altogether.
+func DeleteInnerTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockInnerTxsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block inner transactions", "err", err)
+	}
+}
+
+// ReadBalanceChanges retrieves the attributed balance changes of the accounts
+// touched by a block. Nil is returned if they were never recorded.
+func ReadBalanceChanges(db ethdb.Reader, hash common.Hash, number uint64) []*types.AccountBalanceChanges {
+	data, _ := db.Get(blockBalancesKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	changes := []*types.AccountBalanceChanges{}
+	if err := rlp.DecodeBytes(data, &changes); err != nil {
+		log.Error("Invalid balance change array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return changes
+}
+
+// WriteBalanceChanges stores the attributed balance changes of the accounts
+// touched by a block.
+func WriteBalanceChanges(db ethdb.KeyValueWriter, hash common.Hash, number uint64, changes []*types.AccountBalanceChanges) {
+	bytes, err := rlp.EncodeToBytes(changes)
+	if err != nil {
+		log.Crit("Failed to encode block balance changes", "err", err)
+	}
+	if err := db.Put(blockBalancesKey(number, hash), bytes); err != nil {
+		log.Crit("Failed to store block balance changes", "err", err)
+	}
+}
+
+// DeleteBalanceChanges removes all balance change data associated with a block hash.
+func DeleteBalanceChanges(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockBalancesKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block balance changes", "err", err)
+	}
+}
+
+// ReadBalanceChangesBackfillCheckpoint retrieves the number of the next block
+// whose balance changes are to be backfilled, or nil if no backfill is in progress.
+func ReadBalanceChangesBackfillCheckpoint(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(balanceChangesBackfillKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteBalanceChangesBackfillCheckpoint stores the number of the next block whose
+// balance changes are to be backfilled into database.
+func WriteBalanceChangesBackfillCheckpoint(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(balanceChangesBackfillKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the balance change backfill checkpoint", "err", err)
+	}
+}
+
+// DeleteBalanceChangesBackfillCheckpoint removes the balance change backfill
+// checkpoint from the database, marking the backfill finished.
+func DeleteBalanceChangesBackfillCheckpoint(db ethdb.KeyValueWriter) {
+	if err := db.Delete(balanceChangesBackfillKey); err != nil {
+		log.Crit("Failed to delete the balance change backfill checkpoint", "err", err)
+	}
+}
+
+// ReadBalanceChangesBacklog retrieves the numbers of the blocks whose balance
+// changes could not be recorded on import, in ascending order.
+func ReadBalanceChangesBacklog(db ethdb.KeyValueReader) []uint64 {
+	data, _ := db.Get(balanceChangesBacklogKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var numbers []uint64
+	if err := rlp.DecodeBytes(data, &numbers); err != nil {
+		log.Error("Invalid balance change backlog RLP", "err", err)
+		return nil
+	}
+	return numbers
+}
+
+// WriteBalanceChangesBacklog stores the numbers of the blocks whose balance changes
+// are waiting to be backfilled, removing the backlog if there are none.
+func WriteBalanceChangesBacklog(db ethdb.KeyValueWriter, numbers []uint64) {
+	if len(numbers) == 0 {
+		if err := db.Delete(balanceChangesBacklogKey); err != nil {
+			log.Crit("Failed to delete the balance change backlog", "err", err)
+		}
+		return
+	}
+	bytes, err := rlp.EncodeToBytes(numbers)
+	if err != nil {
+		log.Crit("Failed to encode the balance change backlog", "err", err)
+	}
+	if err := db.Put(balanceChangesBacklogKey, bytes); err != nil {
+		log.Crit("Failed to store the balance change backlog", "err", err)
+	}
+}
+
 // ReadBlock retrieves an entire block corresponding to the hash, assembling it
 // back from the stored header and body. If either the header or body could not
 // be retrieved nil is returned.
@@ -342,6 +342,46 @@ func TestBlockReceiptStorage(t *testing.T) {
 	}
 }
 
+// Tests that inner transactions can be stored and retrieved, and that blocks
+// recorded without any are distinguishable from unrecorded ones.
+func TestBlockInnerTxStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	innerTxs := []types.InnerTxs{
+		{
+			{Type: "CALL", From: common.HexToAddress("0x1"), To: common.HexToAddress("0x2"), Value: big.NewInt(1), Gas: 100, GasUsed: 50, Input: []byte{0x01}, Depth: 1, TraceAddress: []uint64{0}},
+			{Type: "CREATE", From: common.HexToAddress("0x2"), To: common.HexToAddress("0x3"), Value: new(big.Int), Gas: 40, GasUsed: 40, Depth: 2, TraceAddress: []uint64{0, 0}, Error: "out of gas"},
+		},
+		{},
+	}
+	hash := common.BytesToHash([]byte{0x03, 0x14})
+	if HasInnerTxs(db, hash, 0) {
+		t.Fatalf("non existent inner txs reported")
+	}
+	if txs := ReadInnerTxs(db, hash, 0); txs != nil {
+		t.Fatalf("non existent inner txs returned: %v", txs)
+	}
+	WriteInnerTxs(db, hash, 0, innerTxs)
+	if !HasInnerTxs(db, hash, 0) {
+		t.Fatalf("stored inner txs not reported")
+	}
+	have, _ := rlp.EncodeToBytes(ReadInnerTxs(db, hash, 0))
+	want, _ := rlp.EncodeToBytes(innerTxs)
+	if !bytes.Equal(have, want) {
+		t.Fatalf("inner txs mismatch: have %x, want %x", have, want)
+	}
+	// Blocks without transactions must still be marked as recorded
+	empty := common.BytesToHash([]byte{0x03, 0x15})
+	WriteInnerTxs(db, empty, 0, []types.InnerTxs{})
+	if txs := ReadInnerTxs(db, empty, 0); txs == nil || len(txs) != 0 {
+		t.Fatalf("empty inner txs mismatch: have %v", txs)
+	}
+	DeleteInnerTxs(db, hash, 0)
+	if HasInnerTxs(db, hash, 0) {
+		t.Fatalf("deleted inner txs reported")
+	}
+}
+
 func checkReceiptsRLP(have, want types.Receipts) error {
 	if len(have) != len(want) {
 		return fmt.Errorf("receipts sizes mismatch: have %d, want %d", len(have), len(want))
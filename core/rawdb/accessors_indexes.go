@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -141,6 +142,36 @@ func ReadReceipt(db ethdb.Reader, hash common.Hash, config *params.ChainConfig)
 	return nil, common.Hash{}, 0, 0
 }
 
+// ReadRevertReason retrieves the data returned by a transaction aborted by REVERT,
+// or nil if the transaction is unknown, succeeded or reverted without data.
+func ReadRevertReason(db ethdb.KeyValueReader, hash common.Hash) []byte {
+	data, _ := db.Get(revertReasonKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// WriteRevertReasons stores the revert reasons of all the failed transactions of a
+// block which returned any data.
+func WriteRevertReasons(db ethdb.KeyValueWriter, receipts types.Receipts) {
+	for _, receipt := range receipts {
+		if receipt.Status != types.ReceiptStatusFailed || len(receipt.RevertReason) == 0 {
+			continue
+		}
+		if err := db.Put(revertReasonKey(receipt.TxHash), receipt.RevertReason); err != nil {
+			log.Crit("Failed to store revert reason", "err", err)
+		}
+	}
+}
+
+// DeleteRevertReason removes the revert reason of a transaction.
+func DeleteRevertReason(db ethdb.KeyValueWriter, hash common.Hash) {
+	if err := db.Delete(revertReasonKey(hash)); err != nil {
+		log.Crit("Failed to delete revert reason", "err", err)
+	}
+}
+
 // ReadBloomBits retrieves the compressed bloom bit vector belonging to the given
 // section and bit index from the.
 func ReadBloomBits(db ethdb.KeyValueReader, bit uint, section uint64, head common.Hash) ([]byte, error) {
@@ -175,3 +206,186 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// InnerTxPosition is the location of an inner transaction within the chain.
+type InnerTxPosition struct {
+	Number    uint64 // Number of the block containing the transaction
+	TxIndex   uint32 // Index of the transaction within the block
+	CallIndex uint32 // Index of the inner transaction within the transaction's frames
+}
+
+// InnerTxAddressEntry is a single entry of the address index of inner transactions.
+// Entries are never deleted on reorgs, so the block hash they were indexed from
+// needs to be verified against the canonical chain.
+type InnerTxAddressEntry struct {
+	InnerTxPosition
+	BlockHash common.Hash // Hash of the block the entry was indexed from
+}
+
+// WriteInnerTxAddressEntry stores an entry linking an account to an inner
+// transaction it was involved in.
+func WriteInnerTxAddressEntry(db ethdb.KeyValueWriter, address common.Address, pos InnerTxPosition, hash common.Hash) {
+	if err := db.Put(innerTxAddressKey(address, pos), hash.Bytes()); err != nil {
+		log.Crit("Failed to store inner transaction address index", "err", err)
+	}
+}
+
+// ReadInnerTxAddressEntries retrieves at most limit entries of the inner transactions
+// involving an account, starting at the given position and ending before the
+// given block number.
+func ReadInnerTxAddressEntries(db ethdb.Iteratee, address common.Address, start InnerTxPosition, end uint64, limit int) []InnerTxAddressEntry {
+	prefix := append(innerTxAddressPrefix, address.Bytes()...)
+	it := db.NewIterator(prefix, innerTxAddressKey(address, start)[len(prefix):])
+	defer it.Release()
+
+	var entries []InnerTxAddressEntry
+	for len(entries) < limit && it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+16 || len(it.Value()) != common.HashLength {
+			continue
+		}
+		entry := InnerTxAddressEntry{
+			InnerTxPosition: InnerTxPosition{
+				Number:    binary.BigEndian.Uint64(key[len(prefix):]),
+				TxIndex:   binary.BigEndian.Uint32(key[len(prefix)+8:]),
+				CallIndex: binary.BigEndian.Uint32(key[len(prefix)+12:]),
+			},
+			BlockHash: common.BytesToHash(it.Value()),
+		}
+		if entry.Number >= end {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// ReadContractCreation retrieves the creation of the contract deployed at the given
+// address. Entries are never deleted on reorgs, so the block hash of the creation
+// needs to be verified against the canonical chain.
+func ReadContractCreation(db ethdb.KeyValueReader, address common.Address) *types.ContractCreation {
+	data, _ := db.Get(contractCreationKey(address))
+	if len(data) == 0 {
+		return nil
+	}
+	creation := new(types.ContractCreation)
+	if err := rlp.DecodeBytes(data, creation); err != nil {
+		log.Error("Invalid contract creation RLP", "address", address, "err", err)
+		return nil
+	}
+	creation.Address = address
+	return creation
+}
+
+// WriteContractCreation stores the creation of a contract, overwriting any earlier
+// creation at the same address.
+func WriteContractCreation(db ethdb.KeyValueWriter, creation *types.ContractCreation) {
+	data, err := rlp.EncodeToBytes(creation)
+	if err != nil {
+		log.Crit("Failed to encode contract creation", "err", err)
+	}
+	if err := db.Put(contractCreationKey(creation.Address), data); err != nil {
+		log.Crit("Failed to store contract creation", "err", err)
+	}
+}
+
+// TokenTransferPosition is the location of a token transfer within the chain.
+type TokenTransferPosition struct {
+	Number     uint64 // Number of the block containing the transfer
+	LogIndex   uint32 // Index of the transfer event within the block
+	BatchIndex uint32 // Index of the transfer within an ERC-1155 batch event
+}
+
+// WriteTokenTransfer stores a token transfer in the index of one of its holders.
+func WriteTokenTransfer(db ethdb.KeyValueWriter, holder common.Address, transfer *types.TokenTransfer) {
+	data, err := rlp.EncodeToBytes(transfer)
+	if err != nil {
+		log.Crit("Failed to encode token transfer", "err", err)
+	}
+	pos := TokenTransferPosition{Number: transfer.BlockNumber, LogIndex: uint32(transfer.LogIndex), BatchIndex: uint32(transfer.BatchIndex)}
+	if err := db.Put(tokenTransferKey(holder, pos), data); err != nil {
+		log.Crit("Failed to store token transfer", "err", err)
+	}
+}
+
+// ReadTokenTransfers retrieves at most limit token transfers sending or receiving
+// tokens of a holder, starting at the given position and ending before the given
+// block number.
+func ReadTokenTransfers(db ethdb.Iteratee, holder common.Address, start TokenTransferPosition, end uint64, limit int) []*types.TokenTransfer {
+	prefix := append(tokenTransferPrefix, holder.Bytes()...)
+	it := db.NewIterator(prefix, tokenTransferKey(holder, start)[len(prefix):])
+	defer it.Release()
+
+	var transfers []*types.TokenTransfer
+	for len(transfers) < limit && it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+16 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number >= end {
+			break
+		}
+		transfer := new(types.TokenTransfer)
+		if err := rlp.DecodeBytes(it.Value(), transfer); err != nil {
+			log.Error("Invalid token transfer RLP", "holder", holder, "number", number, "err", err)
+			continue
+		}
+		transfer.BlockNumber = number
+		transfer.LogIndex = uint(binary.BigEndian.Uint32(key[len(prefix)+8:]))
+		transfer.BatchIndex = uint(binary.BigEndian.Uint32(key[len(prefix)+12:]))
+		transfers = append(transfers, transfer)
+	}
+	return transfers
+}
+
+// DeleteTokenTransfers removes the token transfers of a holder in the block range
+// [from, to) from the index.
+func DeleteTokenTransfers(db ethdb.Iteratee, w ethdb.KeyValueWriter, holder common.Address, from, to uint64) {
+	prefix := append(tokenTransferPrefix, holder.Bytes()...)
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+16 {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(prefix):]) >= to {
+			break
+		}
+		if err := w.Delete(key); err != nil {
+			log.Crit("Failed to delete token transfer", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to iterate token transfers", "err", it.Error())
+	}
+}
+
+// ReadTokenTransferHolders retrieves the holders whose token transfers were indexed
+// in the given section of the token transfer index.
+func ReadTokenTransferHolders(db ethdb.KeyValueReader, section uint64) []common.Address {
+	data, _ := db.Get(tokenTransferHoldersKey(section))
+	if len(data)%common.AddressLength != 0 {
+		log.Error("Invalid token transfer holders", "section", section, "size", len(data))
+		return nil
+	}
+	holders := make([]common.Address, len(data)/common.AddressLength)
+	for i := range holders {
+		holders[i] = common.BytesToAddress(data[i*common.AddressLength : (i+1)*common.AddressLength])
+	}
+	return holders
+}
+
+// WriteTokenTransferHolders stores the holders whose token transfers were indexed
+// in the given section, allowing them to be rolled back if the section is reorged.
+func WriteTokenTransferHolders(db ethdb.KeyValueWriter, section uint64, holders []common.Address) {
+	data := make([]byte, 0, len(holders)*common.AddressLength)
+	for _, holder := range holders {
+		data = append(data, holder.Bytes()...)
+	}
+	if err := db.Put(tokenTransferHoldersKey(section), data); err != nil {
+		log.Crit("Failed to store token transfer holders", "err", err)
+	}
+}
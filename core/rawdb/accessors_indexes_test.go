@@ -177,3 +177,43 @@ func TestDeleteBloomBits(t *testing.T) {
 	check(1, 1, params.MainnetGenesisHash, true)
 	check(1, 1, params.RinkebyGenesisHash, true)
 }
+
+func TestInnerTxAddressEntries(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	addr1 := common.BytesToAddress([]byte{0x01})
+	addr2 := common.BytesToAddress([]byte{0x02})
+	hash := common.BytesToHash([]byte{0xff})
+
+	positions := []InnerTxPosition{
+		{Number: 1, TxIndex: 0, CallIndex: 0},
+		{Number: 1, TxIndex: 0, CallIndex: 3},
+		{Number: 1, TxIndex: 2, CallIndex: 1},
+		{Number: 5, TxIndex: 0, CallIndex: 0},
+		{Number: 256, TxIndex: 1, CallIndex: 0},
+	}
+	for _, pos := range positions {
+		WriteInnerTxAddressEntry(db, addr1, pos, hash)
+	}
+	WriteInnerTxAddressEntry(db, addr2, InnerTxPosition{Number: 2}, hash)
+
+	check := func(start InnerTxPosition, end uint64, limit int, want []InnerTxPosition) {
+		entries := ReadInnerTxAddressEntries(db, addr1, start, end, limit)
+		if len(entries) != len(want) {
+			t.Fatalf("entry count mismatch from %v to %d: have %d, want %d", start, end, len(entries), len(want))
+		}
+		for i, entry := range entries {
+			if entry.InnerTxPosition != want[i] {
+				t.Errorf("entry %d position mismatch: have %v, want %v", i, entry.InnerTxPosition, want[i])
+			}
+			if entry.BlockHash != hash {
+				t.Errorf("entry %d hash mismatch: have %x, want %x", i, entry.BlockHash, hash)
+			}
+		}
+	}
+	check(InnerTxPosition{}, 1000, 10, positions)
+	check(InnerTxPosition{}, 1000, 2, positions[:2])
+	check(InnerTxPosition{Number: 1, CallIndex: 1}, 1000, 10, positions[1:])
+	check(InnerTxPosition{Number: 2}, 256, 10, positions[3:4])
+	check(InnerTxPosition{Number: 257}, 1000, 10, nil)
+}
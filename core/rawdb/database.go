@@ -283,16 +283,22 @@ func InspectDatabase(db ethdb.Database) error {
 		headers         stat
 		bodies          stat
 		receipts        stat
+		innerTxs        stat
+		balances        stat
 		tds             stat
 		numHashPairings stat
 		hashNumPairings stat
 		tries           stat
 		codes           stat
 		txLookups       stat
+		revertReasons   stat
 		accountSnaps    stat
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		innerTxIndex    stat
+		creationIndex   stat
+		tokenIndex      stat
 		cliqueSnaps     stat
 
 		// Ancient store statistics
@@ -327,6 +333,10 @@ func InspectDatabase(db ethdb.Database) error {
 			bodies.Add(size)
 		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
 			receipts.Add(size)
+		case bytes.HasPrefix(key, blockInnerTxsPrefix) && len(key) == (len(blockInnerTxsPrefix)+8+common.HashLength):
+			innerTxs.Add(size)
+		case bytes.HasPrefix(key, blockBalancesPrefix) && len(key) == (len(blockBalancesPrefix)+8+common.HashLength):
+			balances.Add(size)
 		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
 			tds.Add(size)
 		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
@@ -339,6 +349,8 @@ func InspectDatabase(db ethdb.Database) error {
 			codes.Add(size)
 		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
 			txLookups.Add(size)
+		case bytes.HasPrefix(key, revertReasonPrefix) && len(key) == (len(revertReasonPrefix)+common.HashLength):
+			revertReasons.Add(size)
 		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
 			accountSnaps.Add(size)
 		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
@@ -347,6 +359,12 @@ func InspectDatabase(db ethdb.Database) error {
 			preimages.Add(size)
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, innerTxAddressPrefix) && len(key) == (len(innerTxAddressPrefix)+common.AddressLength+16):
+			innerTxIndex.Add(size)
+		case bytes.HasPrefix(key, contractCreationPrefix) && len(key) == (len(contractCreationPrefix)+common.AddressLength):
+			creationIndex.Add(size)
+		case bytes.HasPrefix(key, tokenTransferPrefix) && len(key) == (len(tokenTransferPrefix)+common.AddressLength+16):
+			tokenIndex.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnaps.Add(size)
 		case bytes.HasPrefix(key, []byte("cht-")) && len(key) == 4+common.HashLength:
@@ -390,11 +408,17 @@ func InspectDatabase(db ethdb.Database) error {
 		{"Key-Value store", "Headers", headers.Size(), headers.Count()},
 		{"Key-Value store", "Bodies", bodies.Size(), bodies.Count()},
 		{"Key-Value store", "Receipt lists", receipts.Size(), receipts.Count()},
+		{"Key-Value store", "Inner tx lists", innerTxs.Size(), innerTxs.Count()},
+		{"Key-Value store", "Balance change lists", balances.Size(), balances.Count()},
 		{"Key-Value store", "Difficulties", tds.Size(), tds.Count()},
 		{"Key-Value store", "Block number->hash", numHashPairings.Size(), numHashPairings.Count()},
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
+		{"Key-Value store", "Revert reasons", revertReasons.Size(), revertReasons.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Inner tx address index", innerTxIndex.Size(), innerTxIndex.Count()},
+		{"Key-Value store", "Contract creation index", creationIndex.Size(), creationIndex.Count()},
+		{"Key-Value store", "Token transfer index", tokenIndex.Size(), tokenIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
@@ -396,6 +396,9 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 				for _, hash := range dangling {
 					log.Trace("Deleting side chain", "number", number, "hash", hash)
 					DeleteBlock(batch, hash, number)
+					if hash != ancients[number-first] {
+						DeleteInnerTxs(batch, hash, number)
+					}
 				}
 			}
 		}
@@ -429,6 +432,7 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 					// Delete all block data associated with the child
 					log.Debug("Deleting dangling block", "number", tip, "hash", children[i], "parent", child.ParentHash)
 					DeleteBlock(batch, children[i], tip)
+					DeleteInnerTxs(batch, children[i], tip)
 				}
 				dangling = children
 				tip++
@@ -66,6 +66,22 @@ var (
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
+	// innerTxBackfillKey tracks the next block whose inner transactions are to be
+	// backfilled, allowing an interrupted backfill to resume.
+	innerTxBackfillKey = []byte("InnerTxBackfill")
+
+	// revertReasonBackfillKey tracks the next block whose revert reasons are to be
+	// backfilled, allowing an interrupted backfill to resume.
+	revertReasonBackfillKey = []byte("RevertReasonBackfill")
+
+	// balanceChangesBackfillKey tracks the next block whose balance changes are to
+	// be backfilled, allowing an interrupted backfill to resume.
+	balanceChangesBackfillKey = []byte("BalanceChangesBackfill")
+
+	// balanceChangesBacklogKey tracks the blocks whose balance changes could not be
+	// recorded on import and are waiting to be backfilled.
+	balanceChangesBacklogKey = []byte("BalanceChangesBacklog")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -74,12 +90,18 @@ var (
 
 	blockBodyPrefix     = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
-
-	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
-	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
-	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
-	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
-	codePrefix            = []byte("c") // codePrefix + code hash -> account code
+	blockInnerTxsPrefix = []byte("x") // blockInnerTxsPrefix + num (uint64 big endian) + hash -> block inner transactions
+	blockBalancesPrefix = []byte("d") // blockBalancesPrefix + num (uint64 big endian) + hash -> block balance changes
+
+	txLookupPrefix         = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	revertReasonPrefix     = []byte("R") // revertReasonPrefix + hash -> revert reason of a failed transaction
+	bloomBitsPrefix        = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	innerTxAddressPrefix   = []byte("X") // innerTxAddressPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) + call index (uint32 big endian) -> block hash
+	contractCreationPrefix = []byte("C") // contractCreationPrefix + address -> contract creation
+	tokenTransferPrefix    = []byte("T") // tokenTransferPrefix + holder + num (uint64 big endian) + log index (uint32 big endian) + batch index (uint32 big endian) -> token transfer
+	SnapshotAccountPrefix  = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
+	SnapshotStoragePrefix  = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
+	codePrefix             = []byte("c") // codePrefix + code hash -> account code
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -87,7 +109,12 @@ var (
 	uncleanShutdownKey = []byte("unclean-shutdown") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix        = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	InnerTxIndexPrefix          = []byte("iX") // InnerTxIndexPrefix is the data table of a chain indexer to track its progress
+	ContractCreationIndexPrefix = []byte("iC") // ContractCreationIndexPrefix is the data table of a chain indexer to track its progress
+	TokenTransferIndexPrefix    = []byte("iT") // TokenTransferIndexPrefix is the data table of a chain indexer to track its progress
+
+	tokenTransferHoldersPrefix = []byte("holders") // tokenTransferHoldersPrefix + section (uint64 big endian) -> holders indexed in the section, within the token transfer index table
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -170,11 +197,26 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// blockInnerTxsKey = blockInnerTxsPrefix + num (uint64 big endian) + hash
+func blockInnerTxsKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockInnerTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// blockBalancesKey = blockBalancesPrefix + num (uint64 big endian) + hash
+func blockBalancesKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockBalancesPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
 }
 
+// revertReasonKey = revertReasonPrefix + hash
+func revertReasonKey(hash common.Hash) []byte {
+	return append(revertReasonPrefix, hash.Bytes()...)
+}
+
 // accountSnapshotKey = SnapshotAccountPrefix + hash
 func accountSnapshotKey(hash common.Hash) []byte {
 	return append(SnapshotAccountPrefix, hash.Bytes()...)
@@ -223,3 +265,35 @@ func IsCodeKey(key []byte) (bool, []byte) {
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
 }
+
+// innerTxAddressKey = innerTxAddressPrefix + address + num (uint64 big endian) + tx index (uint32 big endian) + call index (uint32 big endian)
+func innerTxAddressKey(address common.Address, pos InnerTxPosition) []byte {
+	key := append(append(innerTxAddressPrefix, address.Bytes()...), make([]byte, 16)...)
+
+	binary.BigEndian.PutUint64(key[len(innerTxAddressPrefix)+common.AddressLength:], pos.Number)
+	binary.BigEndian.PutUint32(key[len(innerTxAddressPrefix)+common.AddressLength+8:], pos.TxIndex)
+	binary.BigEndian.PutUint32(key[len(innerTxAddressPrefix)+common.AddressLength+12:], pos.CallIndex)
+
+	return key
+}
+
+// contractCreationKey = contractCreationPrefix + address
+func contractCreationKey(address common.Address) []byte {
+	return append(contractCreationPrefix, address.Bytes()...)
+}
+
+// tokenTransferKey = tokenTransferPrefix + holder + num (uint64 big endian) + log index (uint32 big endian) + batch index (uint32 big endian)
+func tokenTransferKey(holder common.Address, pos TokenTransferPosition) []byte {
+	key := append(append(tokenTransferPrefix, holder.Bytes()...), make([]byte, 16)...)
+
+	binary.BigEndian.PutUint64(key[len(tokenTransferPrefix)+common.AddressLength:], pos.Number)
+	binary.BigEndian.PutUint32(key[len(tokenTransferPrefix)+common.AddressLength+8:], pos.LogIndex)
+	binary.BigEndian.PutUint32(key[len(tokenTransferPrefix)+common.AddressLength+12:], pos.BatchIndex)
+
+	return key
+}
+
+// tokenTransferHoldersKey = tokenTransferHoldersPrefix + section (uint64 big endian)
+func tokenTransferHoldersKey(section uint64) []byte {
+	return append(tokenTransferHoldersPrefix, encodeBlockNumber(section)...)
+}
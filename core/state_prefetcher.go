@@ -53,6 +53,9 @@ func (p *statePrefetcher) Prefetch(block *types.Block, statedb *state.StateDB, c
 		header  = block.Header()
 		gaspool = new(GasPool).AddGas(block.GasLimit())
 	)
+	// Inner transactions of throwaway executions are never used
+	cfg.RecordInnerTxs = false
+
 	// Iterate over and process the individual transactions
 	byzantium := p.config.IsByzantium(block.Number())
 	for i, tx := range block.Transactions() {
@@ -46,6 +46,8 @@ type Prefetcher interface {
 type Processor interface {
 	// Process processes the state changes according to the Ethereum rules by running
 	// the transaction messages using the statedb and applying any rewards to both
-	// the processor (coinbase) and any included uncles.
-	Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error)
+	// the processor (coinbase) and any included uncles. If inner transaction
+	// recording is enabled in the vm config, the nested call frames of every
+	// transaction are returned too, nil otherwise.
+	Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, []types.InnerTxs, uint64, error)
 }
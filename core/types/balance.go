@@ -0,0 +1,97 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Causes of account balance changes.
+const (
+	BalanceChangeGasFee        = "gasFee"        // transaction fee paid by the sender
+	BalanceChangeMinerFee      = "minerFee"      // transaction fee received by the coinbase
+	BalanceChangeBlockReward   = "blockReward"   // mining reward of the block, uncle inclusion included
+	BalanceChangeUncleReward   = "uncleReward"   // mining reward of an included uncle
+	BalanceChangeTransfer      = "transfer"      // value sent by a transaction to its recipient
+	BalanceChangeInnerTransfer = "innerTransfer" // value sent by a nested call or contract creation
+	BalanceChangeSelfDestruct  = "selfDestruct"  // balance passed on or burnt by a selfdestruct
+	BalanceChangeDAODrain      = "daoDrain"      // balance moved by the DAO hard-fork
+)
+
+// BalanceChange is the part of an account's balance change within a block that is
+// attributed to a single cause.
+type BalanceChange struct {
+	Cause   string   // cause of the change, one of the BalanceChange constants
+	TxIndex uint64   // index of the transaction causing the change, if caused by one
+	Delta   *big.Int // signed amount the balance changed by, in wei
+}
+
+// InTx reports whether the change was caused by a transaction rather than by the
+// block itself, i.e. whether its transaction index is meaningful.
+func (c *BalanceChange) InTx() bool {
+	switch c.Cause {
+	case BalanceChangeBlockReward, BalanceChangeUncleReward, BalanceChangeDAODrain:
+		return false
+	}
+	return true
+}
+
+// balanceChangeRLP is the storage encoding of a balance change, RLP not being
+// able to encode negative integers.
+type balanceChangeRLP struct {
+	Cause    string
+	TxIndex  uint64
+	Amount   *big.Int
+	Negative bool
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (c *BalanceChange) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, &balanceChangeRLP{
+		Cause:    c.Cause,
+		TxIndex:  c.TxIndex,
+		Amount:   new(big.Int).Abs(c.Delta),
+		Negative: c.Delta.Sign() < 0,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (c *BalanceChange) DecodeRLP(s *rlp.Stream) error {
+	var dec balanceChangeRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	c.Cause, c.TxIndex, c.Delta = dec.Cause, dec.TxIndex, dec.Amount
+	if dec.Negative {
+		c.Delta.Neg(c.Delta)
+	}
+	return nil
+}
+
+// AccountBalanceChanges is the change of an account's balance within a block,
+// broken down by cause. The deltas of the changes add up to the difference of
+// the balances after and before the block.
+type AccountBalanceChanges struct {
+	Address common.Address
+	Before  *big.Int
+	After   *big.Int
+	Changes []*BalanceChange
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Types of contract creations, distinguishing contracts deployed by a transaction
+// from the ones created by another contract.
+const (
+	CreationTransaction = "TRANSACTION"
+	CreationCreate      = "CREATE"
+	CreationCreate2     = "CREATE2"
+)
+
+// ContractCreation is the deployment of a contract account within the chain.
+type ContractCreation struct {
+	// address of the created contract, not stored as it keys the creation
+	Address common.Address `rlp:"-"`
+	// sender of the transaction for top level creations, the creating
+	// contract for inner ones
+	Creator common.Address
+	// type of the creation, one of TRANSACTION, CREATE or CREATE2
+	Type string
+	// hash and index of the creating transaction
+	TxHash  common.Hash
+	TxIndex uint
+	// hash and number of the block the contract was created in
+	BlockHash   common.Hash
+	BlockNumber uint64
+}
+
+// ContractCreations returns the contracts successfully created by the transactions
+// of a block, in execution order, including the ones created by the nested calls
+// recorded as inner transactions. Creations made by frames which ended up being
+// reverted, directly or by one of their callers, are omitted.
+func ContractCreations(signer Signer, block *Block, receipts Receipts, innerTxs []InnerTxs) ([]*ContractCreation, error) {
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipt list mismatch: have %d, want %d", len(receipts), len(txs))
+	}
+	if len(innerTxs) != len(txs) {
+		return nil, fmt.Errorf("inner transaction lists mismatch: have %d, want %d", len(innerTxs), len(txs))
+	}
+	var creations []*ContractCreation
+	for i, tx := range txs {
+		if receipts[i].Status != ReceiptStatusSuccessful {
+			continue
+		}
+		newCreation := func(address, creator common.Address, typ string) *ContractCreation {
+			return &ContractCreation{
+				Address:     address,
+				Creator:     creator,
+				Type:        typ,
+				TxHash:      tx.Hash(),
+				TxIndex:     uint(i),
+				BlockHash:   block.Hash(),
+				BlockNumber: block.NumberU64(),
+			}
+		}
+		if tx.To() == nil {
+			from, err := Sender(signer, tx)
+			if err != nil {
+				return nil, err
+			}
+			address := crypto.CreateAddress(from, tx.Nonce())
+			creations = append(creations, newCreation(address, from, CreationTransaction))
+		}
+		reverted := innerTxs[i].Reverted()
+		for j, inner := range innerTxs[i] {
+			if reverted[j] || (inner.Type != CreationCreate && inner.Type != CreationCreate2) {
+				continue
+			}
+			creations = append(creations, newCreation(inner.To, inner.From, inner.Type))
+		}
+	}
+	return creations, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//go:generate gencodec -type InnerTx -field-override innerTxMarshaling -out gen_innertx_json.go
+
+// InnerTx represents a nested call frame (internal transaction) entered while
+// executing a transaction: a message call, a contract creation or a selfdestruct
+// sending the remaining balance to a beneficiary. The top level call of the
+// transaction itself is not an inner transaction.
+//
+// The JSON encoding uses the field names and encodings of the RPC transactions,
+// with the hash, index and gas price being those of the enclosing transaction.
+type InnerTx struct {
+	// type of the frame, one of CALL, CALLCODE, DELEGATECALL, STATICCALL,
+	// CREATE, CREATE2 or SELFDESTRUCT
+	Type string `json:"callType" gencodec:"required"`
+	// account initiating the frame
+	From common.Address `json:"from" gencodec:"required"`
+	// account called, created or receiving the selfdestructed balance
+	To common.Address `json:"to" gencodec:"required"`
+	// wei transferred from From to To (zero for DELEGATECALL and STATICCALL)
+	Value *big.Int `json:"value" gencodec:"required"`
+	// gas made available to the frame and the amount of it consumed
+	Gas     uint64 `json:"gas" gencodec:"required"`
+	GasUsed uint64 `json:"gasUsed" gencodec:"required"`
+	// call data or init code and the returned data or deployed code
+	Input  []byte `json:"input" gencodec:"required"`
+	Output []byte `json:"output" gencodec:"required"`
+	// call depth of the frame, the first level of inner calls being 1
+	Depth uint64 `json:"depth" gencodec:"required"`
+	// position of the frame in the transaction's call tree, e.g. [0 2] is the
+	// third sub-call of the first sub-call of the top level call
+	TraceAddress []uint64 `json:"traceAddress" gencodec:"required"`
+	// error message if the frame failed, empty otherwise
+	Error string `json:"error,omitempty"`
+
+	// Derived fields. These fields are filled in by the node
+	// but not stored.
+	// hash of the block in which the transaction was included
+	BlockHash common.Hash `json:"blockHash" rlp:"-"`
+	// block in which the transaction was included
+	BlockNumber *big.Int `json:"blockNumber" rlp:"-"`
+	// hash of the transaction
+	TxHash common.Hash `json:"hash" rlp:"-"`
+	// index of the transaction in the block
+	TxIndex uint64 `json:"transactionIndex" rlp:"-"`
+	// gas price paid by the transaction
+	GasPrice *big.Int `json:"gasPrice" rlp:"-"`
+}
+
+type innerTxMarshaling struct {
+	Value        *hexutil.Big
+	Gas          hexutil.Uint64
+	GasUsed      hexutil.Uint64
+	Input        hexutil.Bytes
+	Output       hexutil.Bytes
+	Depth        hexutil.Uint64
+	TraceAddress []hexutil.Uint64
+	BlockNumber  *hexutil.Big
+	TxIndex      hexutil.Uint64
+	GasPrice     *hexutil.Big
+}
+
+// InnerTxs is a list of inner transactions belonging to a single transaction,
+// in the order their frames were entered.
+type InnerTxs []*InnerTx
+
+// DeriveFields fills the inner transactions with their computed fields based on
+// the transaction they belong to.
+func (txs InnerTxs) DeriveFields(hash common.Hash, number uint64, tx *Transaction, txIndex uint) {
+	for _, inner := range txs {
+		inner.BlockHash = hash
+		inner.BlockNumber = new(big.Int).SetUint64(number)
+		inner.TxHash = tx.Hash()
+		inner.TxIndex = uint64(txIndex)
+		inner.GasPrice = tx.GasPrice()
+	}
+}
+
+// IsValueTransfer reports whether the inner transaction moves ether between
+// accounts or creates a contract.
+func (tx *InnerTx) IsValueTransfer() bool {
+	return tx.Type == "CREATE" || tx.Type == "CREATE2" || (tx.Value != nil && tx.Value.Sign() > 0)
+}
+
+// ValueTransfers returns the inner transactions moving ether between accounts
+// or creating contracts, omitting plain calls without value.
+func (txs InnerTxs) ValueTransfers() InnerTxs {
+	transfers := make(InnerTxs, 0, len(txs))
+	for _, tx := range txs {
+		if tx.IsValueTransfer() {
+			transfers = append(transfers, tx)
+		}
+	}
+	return transfers
+}
+
+// Reverted reports for each inner transaction whether its effects were reverted,
+// because either its own frame or one of the frames it was nested in failed.
+func (txs InnerTxs) Reverted() []bool {
+	var (
+		reverted = make([]bool, len(txs))
+		failed   [][]uint64
+	)
+	// Frames are listed before their nested calls, so any failed ancestor of a
+	// frame is known by the time the frame is checked
+	for i, tx := range txs {
+		if tx.Error != "" {
+			reverted[i] = true
+			failed = append(failed, tx.TraceAddress)
+			continue
+		}
+		for _, parent := range failed {
+			if len(parent) < len(tx.TraceAddress) && equalPath(parent, tx.TraceAddress[:len(parent)]) {
+				reverted[i] = true
+				break
+			}
+		}
+	}
+	return reverted
+}
+
+// equalPath reports whether two trace addresses are the same.
+func equalPath(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
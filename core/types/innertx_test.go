@@ -0,0 +1,84 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Tests that inner transactions are encoded with the field names and encodings
+// of the RPC transactions, and survive a JSON roundtrip.
+func TestInnerTxJSON(t *testing.T) {
+	var (
+		block = common.HexToHash("0x656c34545f90a730a19008c0e7a7cd4fb3895064b48d6d69761bd5abad681056")
+		tx    = NewTransaction(5, common.HexToAddress("0xc0"), big.NewInt(1), 100000, big.NewInt(7), nil)
+		inner = &InnerTx{
+			Type:         "CALL",
+			From:         common.HexToAddress("0xc0"),
+			To:           common.HexToAddress("0xc1"),
+			Value:        big.NewInt(16),
+			Gas:          90000,
+			GasUsed:      21000,
+			Input:        []byte{0x01},
+			Output:       []byte{},
+			Depth:        2,
+			TraceAddress: []uint64{0, 10},
+		}
+	)
+	InnerTxs{inner}.DeriveFields(block, 2019236, tx, 3)
+
+	enc, err := json.Marshal(inner)
+	if err != nil {
+		t.Fatalf("failed to encode inner tx: %v", err)
+	}
+	var have map[string]interface{}
+	if err := json.Unmarshal(enc, &have); err != nil {
+		t.Fatalf("failed to decode inner tx fields: %v", err)
+	}
+	want := map[string]interface{}{
+		"callType":         "CALL",
+		"from":             "0x00000000000000000000000000000000000000c0",
+		"to":               "0x00000000000000000000000000000000000000c1",
+		"value":            "0x10",
+		"gas":              "0x15f90",
+		"gasUsed":          "0x5208",
+		"input":            "0x01",
+		"output":           "0x",
+		"depth":            "0x2",
+		"traceAddress":     []interface{}{"0x0", "0xa"},
+		"blockHash":        block.Hex(),
+		"blockNumber":      "0x1ecfa4",
+		"hash":             tx.Hash().Hex(),
+		"transactionIndex": "0x3",
+		"gasPrice":         "0x7",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("encoded fields mismatch:\nhave %v\nwant %v", have, want)
+	}
+	var dec InnerTx
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatalf("failed to decode inner tx: %v", err)
+	}
+	if !reflect.DeepEqual(&dec, inner) {
+		t.Errorf("decoded inner tx mismatch:\nhave %+v\nwant %+v", &dec, inner)
+	}
+}
@@ -60,6 +60,9 @@ type Receipt struct {
 	TxHash          common.Hash    `json:"transactionHash" gencodec:"required"`
 	ContractAddress common.Address `json:"contractAddress"`
 	GasUsed         uint64         `json:"gasUsed" gencodec:"required"`
+	// The data returned by a transaction aborted by REVERT. It is stored in a side
+	// table keyed by transaction hash rather than in the receipt.
+	RevertReason []byte `json:"revertReason,omitempty"`
 
 	// Inclusion information: These fields provide information about the inclusion of the
 	// transaction corresponding to this receipt.
@@ -73,6 +76,7 @@ type receiptMarshaling struct {
 	Status            hexutil.Uint64
 	CumulativeGasUsed hexutil.Uint64
 	GasUsed           hexutil.Uint64
+	RevertReason      hexutil.Bytes
 	BlockNumber       *hexutil.Big
 	TransactionIndex  hexutil.Uint
 }
@@ -0,0 +1,157 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Token standards of the transfers decoded from logs.
+const (
+	TokenERC20   = "ERC20"
+	TokenERC721  = "ERC721"
+	TokenERC1155 = "ERC1155"
+)
+
+var (
+	// TransferEventTopic is the topic of the ERC-20 and ERC-721 Transfer event.
+	TransferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	// TransferSingleEventTopic is the topic of the ERC-1155 TransferSingle event.
+	TransferSingleEventTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+
+	// TransferBatchEventTopic is the topic of the ERC-1155 TransferBatch event.
+	TransferBatchEventTopic = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// TokenTransfer is a token transfer decoded from a standard transfer event.
+type TokenTransfer struct {
+	// token standard of the event, one of ERC20, ERC721 or ERC1155
+	Standard string
+	// contract emitting the event, sender and recipient of the tokens
+	Token common.Address
+	From  common.Address
+	To    common.Address
+	// identifier of the transferred token, zero for ERC-20 transfers
+	TokenID *big.Int
+	// amount of tokens transferred, always one for ERC-721 transfers
+	Value *big.Int
+	// hash and index of the transaction emitting the event
+	TxHash  common.Hash
+	TxIndex uint
+	// hash of the block the event was emitted in
+	BlockHash common.Hash
+
+	// Derived fields. These fields are stored in the key of the index entries.
+	// block the event was emitted in
+	BlockNumber uint64 `rlp:"-"`
+	// index of the event in the block
+	LogIndex uint `rlp:"-"`
+	// index of the transfer in an ERC-1155 batch event, zero otherwise
+	BatchIndex uint `rlp:"-"`
+}
+
+// DecodeTokenTransfers decodes the token transfers of a standard ERC-20, ERC-721
+// or ERC-1155 transfer event. Nil is returned for any other log, including the
+// transfer events not conforming to the standards' encoding.
+func DecodeTokenTransfers(log *Log) []*TokenTransfer {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	newTransfer := func(standard string, from, to common.Hash, id, value *big.Int) *TokenTransfer {
+		return &TokenTransfer{
+			Standard:    standard,
+			Token:       log.Address,
+			From:        common.BytesToAddress(from.Bytes()),
+			To:          common.BytesToAddress(to.Bytes()),
+			TokenID:     id,
+			Value:       value,
+			TxHash:      log.TxHash,
+			TxIndex:     log.TxIndex,
+			BlockHash:   log.BlockHash,
+			BlockNumber: log.BlockNumber,
+			LogIndex:    log.Index,
+		}
+	}
+	switch log.Topics[0] {
+	case TransferEventTopic:
+		// ERC-20 transfers carry the value as data, ERC-721 ones index the token
+		switch {
+		case len(log.Topics) == 3 && len(log.Data) == 32:
+			value := new(big.Int).SetBytes(log.Data)
+			return []*TokenTransfer{newTransfer(TokenERC20, log.Topics[1], log.Topics[2], new(big.Int), value)}
+		case len(log.Topics) == 4 && len(log.Data) == 0:
+			id := new(big.Int).SetBytes(log.Topics[3].Bytes())
+			return []*TokenTransfer{newTransfer(TokenERC721, log.Topics[1], log.Topics[2], id, big.NewInt(1))}
+		}
+	case TransferSingleEventTopic:
+		if len(log.Topics) == 4 && len(log.Data) == 64 {
+			id, value := new(big.Int).SetBytes(log.Data[:32]), new(big.Int).SetBytes(log.Data[32:])
+			return []*TokenTransfer{newTransfer(TokenERC1155, log.Topics[2], log.Topics[3], id, value)}
+		}
+	case TransferBatchEventTopic:
+		if len(log.Topics) != 4 || len(log.Data) < 64 {
+			return nil
+		}
+		ids, values := decodeUintArray(log.Data, 0), decodeUintArray(log.Data, 32)
+		if ids == nil || values == nil || len(ids) != len(values) {
+			return nil
+		}
+		transfers := make([]*TokenTransfer, len(ids))
+		for i := range ids {
+			transfers[i] = newTransfer(TokenERC1155, log.Topics[2], log.Topics[3], ids[i], values[i])
+			transfers[i].BatchIndex = uint(i)
+		}
+		return transfers
+	}
+	return nil
+}
+
+// decodeUintArray decodes an ABI encoded uint256[] whose offset is stored in
+// the given head slot of the data. Nil is returned if the encoding is invalid.
+func decodeUintArray(data []byte, slot int) []*big.Int {
+	word := func(pos uint64) (uint64, bool) {
+		if pos+32 > uint64(len(data)) {
+			return 0, false
+		}
+		n := new(big.Int).SetBytes(data[pos : pos+32])
+		if !n.IsUint64() {
+			return 0, false
+		}
+		return n.Uint64(), true
+	}
+	offset, ok := word(uint64(slot))
+	if !ok {
+		return nil
+	}
+	length, ok := word(offset)
+	if !ok || length > uint64(len(data))/32 {
+		return nil
+	}
+	start := offset + 32
+	if start+length*32 > uint64(len(data)) {
+		return nil
+	}
+	values := make([]*big.Int, length)
+	for i := uint64(0); i < length; i++ {
+		values[i] = new(big.Int).SetBytes(data[start+i*32 : start+(i+1)*32])
+	}
+	return values
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// Tests that the standard transfer events are decoded, while the ones violating
+// the standards' encoding are ignored.
+func TestDecodeTokenTransfers(t *testing.T) {
+	var (
+		token = common.HexToAddress("0x70")
+		from  = common.BytesToHash(common.HexToAddress("0xf0").Bytes())
+		to    = common.BytesToHash(common.HexToAddress("0x10").Bytes())
+		op    = common.BytesToHash(common.HexToAddress("0x0b").Bytes())
+	)
+	word := func(n uint64) []byte { return math.U256Bytes(new(big.Int).SetUint64(n)) }
+	concat := func(words ...[]byte) []byte {
+		var data []byte
+		for _, w := range words {
+			data = append(data, w...)
+		}
+		return data
+	}
+	tests := []struct {
+		topics []common.Hash
+		data   []byte
+		want   []string // standard:id:value per transfer
+	}{
+		// ERC-20 and ERC-721 transfers
+		{[]common.Hash{TransferEventTopic, from, to}, word(5), []string{"ERC20:0:5"}},
+		{[]common.Hash{TransferEventTopic, from, to, common.BigToHash(big.NewInt(7))}, nil, []string{"ERC721:7:1"}},
+		// ERC-1155 transfers
+		{[]common.Hash{TransferSingleEventTopic, op, from, to}, concat(word(3), word(4)), []string{"ERC1155:3:4"}},
+		{[]common.Hash{TransferBatchEventTopic, op, from, to}, concat(word(64), word(160), word(2), word(1), word(2), word(2), word(10), word(20)), []string{"ERC1155:1:10", "ERC1155:2:20"}},
+		// Malformed events
+		{[]common.Hash{TransferEventTopic, from}, word(5), nil},
+		{[]common.Hash{TransferEventTopic, from, to}, nil, nil},
+		{[]common.Hash{TransferSingleEventTopic, op, from, to}, word(3), nil},
+		{[]common.Hash{TransferBatchEventTopic, op, from, to}, concat(word(64), word(160), word(2), word(1), word(2), word(1), word(10)), nil},
+		{[]common.Hash{TransferBatchEventTopic, op, from, to}, concat(word(64), word(1<<40)), nil},
+		{[]common.Hash{common.Hash{}, from, to}, word(5), nil},
+	}
+	for i, tt := range tests {
+		transfers := DecodeTokenTransfers(&Log{Address: token, Topics: tt.topics, Data: tt.data, Index: 3})
+		if len(transfers) != len(tt.want) {
+			t.Errorf("test %d: transfer count mismatch: have %d, want %d", i, len(transfers), len(tt.want))
+			continue
+		}
+		for j, transfer := range transfers {
+			if have := fmt.Sprintf("%s:%v:%v", transfer.Standard, transfer.TokenID, transfer.Value); have != tt.want[j] {
+				t.Errorf("test %d, transfer %d: have %s, want %s", i, j, have, tt.want[j])
+			}
+			if transfer.Token != token || transfer.From != common.HexToAddress("0xf0") || transfer.To != common.HexToAddress("0x10") {
+				t.Errorf("test %d, transfer %d: parties mismatch: %x %x -> %x", i, j, transfer.Token, transfer.From, transfer.To)
+			}
+			if transfer.LogIndex != 3 || transfer.BatchIndex != uint(j) {
+				t.Errorf("test %d, transfer %d: position mismatch: log %d, batch %d", i, j, transfer.LogIndex, transfer.BatchIndex)
+			}
+		}
+	}
+}
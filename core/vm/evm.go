@@ -154,6 +154,9 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// innerTxs records the nested call frames of the current transaction if
+	// enabled in the vm configuration, nil otherwise.
+	innerTxs *innerTxRecorder
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -190,6 +193,9 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 	evm.interpreters = append(evm.interpreters, NewEVMInterpreter(evm, vmConfig))
 	evm.interpreter = evm.interpreters[0]
 
+	if vmConfig.RecordInnerTxs {
+		evm.innerTxs = new(innerTxRecorder)
+	}
 	return evm
 }
 
@@ -198,6 +204,9 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 func (evm *EVM) Reset(txCtx TxContext, statedb StateDB) {
 	evm.TxContext = txCtx
 	evm.StateDB = statedb
+	if evm.innerTxs != nil {
+		evm.innerTxs = new(innerTxRecorder)
+	}
 }
 
 // Cancel cancels any running EVM operation. This may be called concurrently and
@@ -224,6 +233,17 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.innerTxs != nil {
+		tx := evm.innerTxs.enter(CALL, caller.Address(), addr, input, gas, value)
+		defer func() { evm.innerTxs.exit(tx, ret, leftOverGas, err) }()
+	}
+	// Capture the tracer enter/exit events of nested frames in debug mode
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(CALL, caller.Address(), addr, input, gas, value)
+		defer func(startGas uint64) { // Lazy evaluation of the parameters
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -300,6 +320,17 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.innerTxs != nil {
+		tx := evm.innerTxs.enter(CALLCODE, caller.Address(), addr, input, gas, value)
+		defer func() { evm.innerTxs.exit(tx, ret, leftOverGas, err) }()
+	}
+	// Capture the tracer enter/exit events of nested frames in debug mode
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(CALLCODE, caller.Address(), addr, input, gas, value)
+		defer func(startGas uint64) { // Lazy evaluation of the parameters
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -343,6 +374,17 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.innerTxs != nil {
+		tx := evm.innerTxs.enter(DELEGATECALL, caller.Address(), addr, input, gas, nil)
+		defer func() { evm.innerTxs.exit(tx, ret, leftOverGas, err) }()
+	}
+	// Capture the tracer enter/exit events of nested frames in debug mode
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(DELEGATECALL, caller.Address(), addr, input, gas, nil)
+		defer func(startGas uint64) { // Lazy evaluation of the parameters
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -377,6 +419,17 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, gas, nil
 	}
+	if evm.innerTxs != nil {
+		tx := evm.innerTxs.enter(STATICCALL, caller.Address(), addr, input, gas, nil)
+		defer func() { evm.innerTxs.exit(tx, ret, leftOverGas, err) }()
+	}
+	// Capture the tracer enter/exit events of nested frames in debug mode
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(STATICCALL, caller.Address(), addr, input, gas, nil)
+		defer func(startGas uint64) { // Lazy evaluation of the parameters
+			evm.vmConfig.Tracer.CaptureExit(ret, startGas-leftOverGas, err)
+		}(gas)
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -516,6 +569,14 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 // Create creates a new contract using code as deployment code.
 func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
 	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
+	if evm.innerTxs != nil {
+		tx := evm.innerTxs.enter(CREATE, caller.Address(), contractAddr, code, gas, value)
+		defer func() { evm.innerTxs.exit(tx, ret, leftOverGas, err) }()
+	}
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(CREATE, caller.Address(), contractAddr, code, gas, value)
+		defer func() { evm.vmConfig.Tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 	return evm.create(caller, &codeAndHash{code: code}, gas, value, contractAddr)
 }
 
@@ -526,6 +587,14 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *big.Int, salt *uint256.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
 	codeAndHash := &codeAndHash{code: code}
 	contractAddr = crypto.CreateAddress2(caller.Address(), salt.Bytes32(), codeAndHash.Hash().Bytes())
+	if evm.innerTxs != nil {
+		tx := evm.innerTxs.enter(CREATE2, caller.Address(), contractAddr, code, gas, endowment)
+		defer func() { evm.innerTxs.exit(tx, ret, leftOverGas, err) }()
+	}
+	if evm.vmConfig.Debug && evm.depth > 0 {
+		evm.vmConfig.Tracer.CaptureEnter(CREATE2, caller.Address(), contractAddr, code, gas, endowment)
+		defer func() { evm.vmConfig.Tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 	return evm.create(caller, codeAndHash, gas, endowment, contractAddr)
 }
 
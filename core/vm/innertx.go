@@ -0,0 +1,90 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// innerTxRecorder collects the nested call frames entered while executing a
+// single transaction.
+type innerTxRecorder struct {
+	txs      types.InnerTxs
+	path     []uint64 // trace address of the innermost active inner frame
+	children []uint64 // number of sub-frames entered so far by each active frame
+}
+
+// enter is called when a new call frame is entered. The top level frame of the
+// transaction is tracked to maintain the call path, but isn't recorded, in which
+// case nil is returned.
+func (r *innerTxRecorder) enter(typ OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) *types.InnerTx {
+	if len(r.children) == 0 {
+		r.children = append(r.children, 0)
+		return nil
+	}
+	parent := len(r.children) - 1
+	r.path = append(r.path, r.children[parent])
+	r.children[parent]++
+	r.children = append(r.children, 0)
+
+	tx := &types.InnerTx{
+		Type:         typ.String(),
+		From:         from,
+		To:           to,
+		Value:        new(big.Int),
+		Gas:          gas,
+		Input:        common.CopyBytes(input),
+		Depth:        uint64(len(r.path)),
+		TraceAddress: append([]uint64{}, r.path...),
+	}
+	if value != nil {
+		tx.Value.Set(value)
+	}
+	r.txs = append(r.txs, tx)
+	return tx
+}
+
+// exit is called when the call frame previously returned by enter is left.
+func (r *innerTxRecorder) exit(tx *types.InnerTx, output []byte, gasLeft uint64, err error) {
+	r.children = r.children[:len(r.children)-1]
+	if tx == nil {
+		return
+	}
+	r.path = r.path[:len(r.path)-1]
+
+	tx.Output = common.CopyBytes(output)
+	tx.GasUsed, _ = math.SafeSub(tx.Gas, gasLeft)
+	if err != nil {
+		tx.Error = err.Error()
+	}
+}
+
+// InnerTxs returns the inner transactions recorded since the last Reset, or nil
+// if recording is not enabled in the EVM's configuration.
+func (evm *EVM) InnerTxs() types.InnerTxs {
+	if evm.innerTxs == nil {
+		return nil
+	}
+	if evm.innerTxs.txs == nil {
+		return types.InnerTxs{}
+	}
+	return evm.innerTxs.txs
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that nested calls, precompile calls and selfdestructs are recorded with
+// the correct depth and call path, while the top level call is not.
+func TestInnerTxRecording(t *testing.T) {
+	var (
+		origin      = common.HexToAddress("0x0a")
+		caller      = common.HexToAddress("0x0b")
+		callee      = common.HexToAddress("0x0c")
+		beneficiary = common.HexToAddress("0x0d")
+		identity    = common.BytesToAddress([]byte{4})
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	// The caller sends 1 wei to the callee, then calls the identity precompile
+	code := []byte{
+		byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 1, byte(PUSH20),
+	}
+	code = append(code, callee.Bytes()...)
+	code = append(code, []byte{
+		byte(GAS), byte(CALL), byte(POP),
+		byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 0, byte(PUSH1), 4,
+		byte(GAS), byte(STATICCALL), byte(POP), byte(STOP),
+	}...)
+	statedb.SetCode(caller, code)
+	statedb.SetBalance(caller, big.NewInt(1))
+
+	// The callee selfdestructs, sending its balance to the beneficiary
+	statedb.SetCode(callee, append(append([]byte{byte(PUSH20)}, beneficiary.Bytes()...), byte(SELFDESTRUCT)))
+
+	vmctx := BlockContext{
+		CanTransfer: func(db StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer: func(db StateDB, sender, recipient common.Address, amount *big.Int) {
+			db.SubBalance(sender, amount)
+			db.AddBalance(recipient, amount)
+		},
+		BlockNumber: new(big.Int),
+	}
+	vmenv := NewEVM(vmctx, TxContext{Origin: origin}, statedb, params.AllEthashProtocolChanges, Config{RecordInnerTxs: true})
+	if _, _, err := vmenv.Call(AccountRef(origin), caller, nil, 100000, new(big.Int)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	txs := vmenv.InnerTxs()
+	if len(txs) != 3 {
+		t.Fatalf("inner tx count mismatch: have %d, want 3", len(txs))
+	}
+	want := []struct {
+		typ      string
+		from, to common.Address
+		value    int64
+		depth    uint64
+		path     []uint64
+	}{
+		{"CALL", caller, callee, 1, 1, []uint64{0}},
+		{"SELFDESTRUCT", callee, beneficiary, 1, 2, []uint64{0, 0}},
+		{"STATICCALL", caller, identity, 0, 1, []uint64{1}},
+	}
+	for i, tt := range want {
+		tx := txs[i]
+		if tx.Type != tt.typ || tx.From != tt.from || tx.To != tt.to {
+			t.Errorf("inner tx %d: frame mismatch: have %s %x->%x, want %s %x->%x", i, tx.Type, tx.From, tx.To, tt.typ, tt.from, tt.to)
+		}
+		if tx.Value.Int64() != tt.value {
+			t.Errorf("inner tx %d: value mismatch: have %v, want %v", i, tx.Value, tt.value)
+		}
+		if tx.Depth != tt.depth || !reflect.DeepEqual(tx.TraceAddress, tt.path) {
+			t.Errorf("inner tx %d: position mismatch: have %d %v, want %d %v", i, tx.Depth, tx.TraceAddress, tt.depth, tt.path)
+		}
+		if tx.Error != "" {
+			t.Errorf("inner tx %d: unexpected error: %v", i, tx.Error)
+		}
+	}
+	// Resetting the EVM for a new transaction should drop the recorded frames
+	vmenv.Reset(TxContext{Origin: origin}, statedb)
+	if txs := vmenv.InnerTxs(); len(txs) != 0 {
+		t.Fatalf("inner txs retained after reset: %v", txs)
+	}
+}
@@ -817,6 +817,14 @@ func opStop(pc *uint64, interpreter *EVMInterpreter, callContext *callCtx) ([]by
 func opSuicide(pc *uint64, interpreter *EVMInterpreter, callContext *callCtx) ([]byte, error) {
 	beneficiary := callContext.stack.pop()
 	balance := interpreter.evm.StateDB.GetBalance(callContext.contract.Address())
+	if recorder := interpreter.evm.innerTxs; recorder != nil {
+		tx := recorder.enter(SELFDESTRUCT, callContext.contract.Address(), beneficiary.Bytes20(), nil, 0, balance)
+		recorder.exit(tx, nil, 0, nil)
+	}
+	if interpreter.cfg.Debug {
+		interpreter.cfg.Tracer.CaptureEnter(SELFDESTRUCT, callContext.contract.Address(), beneficiary.Bytes20(), nil, 0, balance)
+		interpreter.cfg.Tracer.CaptureExit(nil, 0, nil)
+	}
 	interpreter.evm.StateDB.AddBalance(beneficiary.Bytes20(), balance)
 	interpreter.evm.StateDB.Suicide(callContext.contract.Address())
 	return nil, nil
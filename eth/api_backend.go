@@ -175,6 +175,34 @@ func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (type
 	return b.eth.blockchain.GetReceiptsByHash(hash), nil
 }
 
+func (b *EthAPIBackend) GetInnerTxs(ctx context.Context, hash common.Hash) ([]types.InnerTxs, error) {
+	return b.eth.blockchain.GetInnerTxsByHash(hash), nil
+}
+
+func (b *EthAPIBackend) InnerTxIndexStatus() (uint64, uint64) {
+	if b.eth.innerTxIndexer == nil {
+		return innerTxIndexSectionSize, 0
+	}
+	sections, _, _ := b.eth.innerTxIndexer.Sections()
+	return innerTxIndexSectionSize, sections
+}
+
+func (b *EthAPIBackend) ContractCreationIndexStatus() (uint64, uint64) {
+	if b.eth.creationIndexer == nil {
+		return 0, 0
+	}
+	sections, _, _ := b.eth.creationIndexer.Sections()
+	return creationIndexSectionSize, sections
+}
+
+func (b *EthAPIBackend) TokenTransferIndexStatus() (uint64, uint64) {
+	if b.eth.tokenIndexer == nil {
+		return 0, 0
+	}
+	sections, _, _ := b.eth.tokenIndexer.Sections()
+	return tokenIndexSectionSize, sections
+}
+
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
 	if receipts == nil {
@@ -276,6 +304,18 @@ func (b *EthAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *EthAPIBackend) FeeStatistics(ctx context.Context, first, last uint64) ([]*gasprice.BlockFees, error) {
+	return b.gpo.FeeStatistics(ctx, first, last)
+}
+
+func (b *EthAPIBackend) SuggestPriceTiers(ctx context.Context) (*gasprice.PriceTiers, error) {
+	pending, err := b.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	return b.gpo.SuggestTiers(ctx, pending)
+}
+
 func (b *EthAPIBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
@@ -0,0 +1,131 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BalanceChangesConfig holds the extra parameters of a balance change query.
+type BalanceChangesConfig struct {
+	Reexec *uint64
+}
+
+// balanceChangeResult is the part of an account's balance change attributed to
+// a single cause. Changes caused by the block itself have no transaction.
+type balanceChangeResult struct {
+	Cause   string          `json:"cause"`
+	TxHash  *common.Hash    `json:"transactionHash,omitempty"`
+	TxIndex *hexutil.Uint64 `json:"transactionIndex,omitempty"`
+	Delta   *hexutil.Big    `json:"delta"`
+}
+
+// accountBalanceResult is the change of an account's balance within a block,
+// broken down by cause.
+type accountBalanceResult struct {
+	Address common.Address         `json:"address"`
+	Before  *hexutil.Big           `json:"before"`
+	After   *hexutil.Big           `json:"after"`
+	Delta   *hexutil.Big           `json:"delta"`
+	Changes []*balanceChangeResult `json:"changes"`
+}
+
+// blockBalancesResult is the list of balance changes of all the accounts touched
+// by a block.
+type blockBalancesResult struct {
+	BlockHash   common.Hash             `json:"blockHash"`
+	BlockNumber hexutil.Uint64          `json:"blockNumber"`
+	Accounts    []*accountBalanceResult `json:"accounts"`
+}
+
+// BalanceChanges returns the balance change of every account touched by a block,
+// broken down by cause: gas fees paid and received, block and uncle rewards,
+// value transfers of transactions and nested calls, selfdestructs and the DAO
+// hard-fork drain. The changes of an account add up exactly to its balance
+// difference. Recorded changes are returned directly, otherwise the block is
+// re-executed on top of its parent's state.
+func (api *PrivateDebugAPI) BalanceChanges(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *BalanceChangesConfig) (*blockBalancesResult, error) {
+	var block *types.Block
+	if number, ok := blockNrOrHash.Number(); ok {
+		block = api.blockByNumber(number)
+	} else if hash, ok := blockNrOrHash.Hash(); ok {
+		block = api.eth.blockchain.GetBlockByHash(hash)
+	} else {
+		return nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	if block == nil {
+		return nil, errors.New("block not found")
+	}
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not executable")
+	}
+	changes := rawdb.ReadBalanceChanges(api.eth.ChainDb(), block.Hash(), block.NumberU64())
+	if changes == nil {
+		parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+		if parent == nil {
+			return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+		}
+		reexec := defaultTraceReexec
+		if config != nil && config.Reexec != nil {
+			reexec = *config.Reexec
+		}
+		statedb, err := api.computeStateDB(parent, reexec)
+		if err != nil {
+			return nil, err
+		}
+		if changes, err = attributeBalanceChanges(api.eth.blockchain, block, statedb); err != nil {
+			return nil, err
+		}
+	}
+	result := &blockBalancesResult{
+		BlockHash:   block.Hash(),
+		BlockNumber: hexutil.Uint64(block.NumberU64()),
+		Accounts:    make([]*accountBalanceResult, 0, len(changes)),
+	}
+	txs := block.Transactions()
+	for _, account := range changes {
+		res := &accountBalanceResult{
+			Address: account.Address,
+			Before:  (*hexutil.Big)(account.Before),
+			After:   (*hexutil.Big)(account.After),
+			Delta:   (*hexutil.Big)(new(big.Int).Sub(account.After, account.Before)),
+			Changes: make([]*balanceChangeResult, 0, len(account.Changes)),
+		}
+		for _, change := range account.Changes {
+			c := &balanceChangeResult{Cause: change.Cause, Delta: (*hexutil.Big)(change.Delta)}
+			if change.InTx() {
+				if change.TxIndex >= uint64(len(txs)) {
+					return nil, fmt.Errorf("balance changes of block #%d corrupted", block.NumberU64())
+				}
+				hash, index := txs[change.TxIndex].Hash(), hexutil.Uint64(change.TxIndex)
+				c.TxHash, c.TxIndex = &hash, &index
+			}
+			res.Changes = append(res.Changes, c)
+		}
+		result.Accounts = append(result.Accounts, res)
+	}
+	return result, nil
+}
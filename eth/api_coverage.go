@@ -0,0 +1,102 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// CoverageConfig holds the extra parameters of a code coverage collection.
+type CoverageConfig struct {
+	Reexec *uint64
+}
+
+// coverageResult is the code coverage collected over a range of blocks.
+type coverageResult struct {
+	Contracts map[common.Hash]*tracers.CodeCoverage `json:"contracts"`      // Raw per program counter hit counts, keyed by code hash
+	LCOV      string                                `json:"lcov,omitempty"` // Line coverage of the code with registered source artifacts
+}
+
+// Coverage re-executes all the blocks between start and end (both included) and
+// returns the code coverage of all the contracts executed. Line coverage in lcov
+// format is included for the contracts registered via RegisterSourceArtifact.
+func (api *PrivateDebugAPI) Coverage(ctx context.Context, start, end rpc.BlockNumber, config *CoverageConfig) (*coverageResult, error) {
+	from, to := api.blockByNumber(start), api.blockByNumber(end)
+	if from == nil {
+		return nil, fmt.Errorf("starting block #%d not found", start)
+	}
+	if to == nil {
+		return nil, fmt.Errorf("end block #%d not found", end)
+	}
+	if from.Number().Cmp(to.Number()) > 0 {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", end, start)
+	}
+	if from.NumberU64() == 0 {
+		return nil, fmt.Errorf("genesis is not executable")
+	}
+	parent := api.eth.blockchain.GetBlock(from.ParentHash(), from.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", from.ParentHash())
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, err := api.computeStateDB(parent, reexec)
+	if err != nil {
+		return nil, err
+	}
+	// Process the blocks one after the other on top of the same state. Nothing is
+	// committed, the state is only ever discarded at the end.
+	var (
+		coverage = tracers.NewCoverageTracer()
+		vmconf   = vm.Config{Debug: true, Tracer: coverage}
+		block    = from
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if _, _, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vmconf); err != nil {
+			return nil, fmt.Errorf("processing block %d failed: %v", block.NumberU64(), err)
+		}
+		if block.NumberU64() == to.NumberU64() {
+			break
+		}
+		// Use the end block directly if next, it may be the pending one
+		if next := block.NumberU64() + 1; next == to.NumberU64() {
+			block = to
+		} else if block = api.eth.blockchain.GetBlockByNumber(next); block == nil {
+			return nil, fmt.Errorf("block #%d not found", next)
+		}
+	}
+	result := &coverageResult{Contracts: coverage.Coverage()}
+
+	lcov := new(bytes.Buffer)
+	if err := coverage.WriteLCOV(lcov, api.eth.sources); err != nil {
+		return nil, err
+	}
+	result.LCOV = lcov.String()
+	return result, nil
+}
@@ -0,0 +1,210 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+)
+
+// ReplayConfig holds the modifications to apply to a historical transaction
+// before replaying it, along with the trace configuration to replay it with.
+type ReplayConfig struct {
+	TraceConfig
+	StateOverrides *ethapi.StateOverride // Code replacements and storage patches
+	Data           *hexutil.Bytes        // Replacement calldata of the transaction
+	Gas            *hexutil.Uint64       // Replacement gas allowance of the transaction
+	Value          *hexutil.Big          // Replacement value of the transaction
+}
+
+// replayOutcome is the receipt-like summary of a single transaction execution.
+type replayOutcome struct {
+	Status  hexutil.Uint64 `json:"status"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Logs    []*types.Log   `json:"logs"`
+	Output  hexutil.Bytes  `json:"output,omitempty"`
+	Revert  string         `json:"revert,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// replayDiff is the difference between the original and the replayed execution
+// of a transaction.
+type replayDiff struct {
+	StatusChanged bool         `json:"statusChanged"`
+	GasDelta      int64        `json:"gasDelta"`
+	LogsAdded     []*types.Log `json:"logsAdded"`
+	LogsRemoved   []*types.Log `json:"logsRemoved"`
+}
+
+// replayResult is the result of replaying a modified historical transaction.
+type replayResult struct {
+	Original *replayOutcome `json:"original"`
+	Replayed *replayOutcome `json:"replayed"`
+	Diff     *replayDiff    `json:"diff"`
+	Trace    interface{}    `json:"trace"`
+}
+
+// ReplayModifiedTransaction rebuilds the state right before the given transaction
+// within its original block, applies the requested state and transaction
+// modifications, and traces the modified transaction on top. The outcome is
+// compared against the original receipt of the transaction.
+func (api *PrivateDebugAPI) ReplayModifiedTransaction(ctx context.Context, hash common.Hash, config *ReplayConfig) (*replayResult, error) {
+	// Retrieve the transaction, its block and its original receipt
+	tx, blockHash, _, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	receipts := api.eth.blockchain.GetReceiptsByHash(blockHash)
+	if int(index) >= len(receipts) {
+		return nil, fmt.Errorf("receipt of transaction %#x not found", hash)
+	}
+	receipt := receipts[index]
+
+	// Recreate the state right before the transaction and apply the changes
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	msg, vmctx, statedb, err := api.computeTxEnv(block, int(index), reexec)
+	if err != nil {
+		return nil, err
+	}
+	var traceConfig *TraceConfig
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+		var (
+			data  = msg.Data()
+			gas   = msg.Gas()
+			value = msg.Value()
+		)
+		if config.Data != nil {
+			data = *config.Data
+		}
+		if config.Gas != nil {
+			gas = uint64(*config.Gas)
+		}
+		if config.Value != nil {
+			value = config.Value.ToInt()
+		}
+		msg = types.NewMessage(msg.From(), msg.To(), msg.Nonce(), value, gas, msg.GasPrice(), data, msg.CheckNonce())
+		traceConfig = &config.TraceConfig
+	}
+	// Replay the modified transaction and compare it with the original
+	statedb.Prepare(hash, blockHash, int(index))
+
+	trace, result, err := api.traceMessage(ctx, msg, vmctx, statedb, traceConfig)
+	if err != nil {
+		return nil, err
+	}
+	logs := statedb.GetLogs(hash)
+	for _, log := range logs {
+		log.BlockNumber = block.NumberU64()
+	}
+	replayed := &replayOutcome{
+		Status:  hexutil.Uint64(types.ReceiptStatusSuccessful),
+		GasUsed: hexutil.Uint64(result.UsedGas),
+		Logs:    logs,
+		Output:  result.ReturnData,
+	}
+	if result.Failed() {
+		replayed.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+		replayed.Error = result.Err.Error()
+	}
+	if revert := result.Revert(); len(revert) > 0 {
+		if reason, err := abi.UnpackRevert(revert); err == nil {
+			replayed.Revert = reason
+		}
+	}
+	original := &replayOutcome{
+		Status:  hexutil.Uint64(receipt.Status),
+		GasUsed: hexutil.Uint64(receipt.GasUsed),
+		Logs:    receipt.Logs,
+	}
+	if original.Logs == nil {
+		original.Logs = []*types.Log{}
+	}
+	if replayed.Logs == nil {
+		replayed.Logs = []*types.Log{}
+	}
+	added, removed := diffLogs(original.Logs, replayed.Logs)
+	return &replayResult{
+		Original: original,
+		Replayed: replayed,
+		Diff: &replayDiff{
+			StatusChanged: original.Status != replayed.Status,
+			GasDelta:      int64(result.UsedGas) - int64(receipt.GasUsed),
+			LogsAdded:     added,
+			LogsRemoved:   removed,
+		},
+		Trace: trace,
+	}, nil
+}
+
+// diffLogs matches up the logs of two executions by their emitter, topics and
+// data, returning the ones only present in the replayed and only present in the
+// original execution respectively.
+func diffLogs(original, replayed []*types.Log) (added []*types.Log, removed []*types.Log) {
+	added, removed = []*types.Log{}, []*types.Log{}
+
+	matched := make([]bool, len(replayed))
+	for _, want := range original {
+		found := false
+		for i, have := range replayed {
+			if !matched[i] && sameLog(want, have) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, want)
+		}
+	}
+	for i, have := range replayed {
+		if !matched[i] {
+			added = append(added, have)
+		}
+	}
+	return added, removed
+}
+
+// sameLog reports whether two logs carry the same content, disregarding their
+// position within the chain.
+func sameLog(a, b *types.Log) bool {
+	if a.Address != b.Address || len(a.Topics) != len(b.Topics) || !bytes.Equal(a.Data, b.Data) {
+		return false
+	}
+	for i := range a.Topics {
+		if a.Topics[i] != b.Topics[i] {
+			return false
+		}
+	}
+	return true
+}
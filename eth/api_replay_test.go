@@ -0,0 +1,183 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the logs of an original and a replayed execution are matched up by
+// content, regardless of their position in the chain.
+func TestReplayDiffLogs(t *testing.T) {
+	var (
+		a = &types.Log{Address: common.Address{0x01}, Topics: []common.Hash{{0x01}}, Data: []byte{0x01}}
+		b = &types.Log{Address: common.Address{0x01}, Topics: []common.Hash{{0x02}}, Data: []byte{0x01}}
+		c = &types.Log{Address: common.Address{0x02}, Topics: []common.Hash{{0x01}}, Data: []byte{0x01}}
+		d = &types.Log{Address: common.Address{0x01}, Topics: []common.Hash{{0x01}}, Data: []byte{0x02}}
+	)
+	// Same content at a different position should match up
+	moved := *a
+	moved.Index, moved.TxIndex = 5, 3
+
+	tests := []struct {
+		original, replayed []*types.Log
+		added, removed     []*types.Log
+	}{
+		{nil, nil, nil, nil},
+		{[]*types.Log{a, b}, []*types.Log{&moved, b}, nil, nil},
+		{[]*types.Log{a, b}, []*types.Log{b}, nil, []*types.Log{a}},
+		{[]*types.Log{a}, []*types.Log{a, c, d}, []*types.Log{c, d}, nil},
+		{[]*types.Log{a, a}, []*types.Log{a}, nil, []*types.Log{a}},
+		{[]*types.Log{a, b}, []*types.Log{c, d}, []*types.Log{c, d}, []*types.Log{a, b}},
+	}
+	for i, tt := range tests {
+		added, removed := diffLogs(tt.original, tt.replayed)
+		if len(added) != len(tt.added) {
+			t.Errorf("test %d: added logs mismatch: have %d, want %d", i, len(added), len(tt.added))
+		}
+		for j := 0; j < len(added) && j < len(tt.added); j++ {
+			if added[j] != tt.added[j] {
+				t.Errorf("test %d: added log %d mismatch: have %v, want %v", i, j, added[j], tt.added[j])
+			}
+		}
+		if len(removed) != len(tt.removed) {
+			t.Errorf("test %d: removed logs mismatch: have %d, want %d", i, len(removed), len(tt.removed))
+		}
+		for j := 0; j < len(removed) && j < len(tt.removed); j++ {
+			if removed[j] != tt.removed[j] {
+				t.Errorf("test %d: removed log %d mismatch: have %v, want %v", i, j, removed[j], tt.removed[j])
+			}
+		}
+	}
+}
+
+// Tests that a transaction is replayed on top of the state left by the ones
+// before it in its block, and that the effects of the modifications are reported.
+func TestReplayModifiedTransaction(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender  = crypto.PubkeyToAddress(key.PublicKey)
+		counter = common.HexToAddress("0xc0")
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				counter: {Balance: new(big.Int), Code: []byte{
+					// Increments the counter in slot 0 and logs it
+					byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD),
+					byte(vm.DUP1), byte(vm.PUSH1), 0, byte(vm.SSTORE),
+					byte(vm.PUSH1), 0, byte(vm.MSTORE),
+					byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG0),
+				}},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	// Call the counter twice in the same block, so the second call depends on the first
+	var txs []*types.Transaction
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, b *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), counter, nil, 100000, big.NewInt(1), nil), signer, key)
+			b.AddTx(tx)
+			txs = append(txs, tx)
+		}
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{blockchain: chain, chainDb: db, sources: tracers.NewSourceRegistry()}
+	api := NewPrivateDebugAPI(eth)
+
+	var (
+		reverter = hexutil.Bytes{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)}
+		logger   = hexutil.Bytes{
+			byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0, byte(vm.MSTORE),
+			byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG0),
+		}
+		gas = hexutil.Uint64(25000)
+	)
+	tests := []struct {
+		config        *ReplayConfig
+		status        uint64
+		statusChanged bool
+		gasChanged    bool
+		added         int
+		removed       int
+	}{
+		// Unmodified replay matches the original execution
+		{config: nil, status: types.ReceiptStatusSuccessful},
+		// Replaced code reverting drops the log
+		{
+			config:        &ReplayConfig{StateOverrides: &ethapi.StateOverride{counter: ethapi.OverrideAccount{Code: &reverter}}},
+			status:        types.ReceiptStatusFailed,
+			statusChanged: true, gasChanged: true, removed: 1,
+		},
+		// Replaced code logging something else swaps the log
+		{
+			config:     &ReplayConfig{StateOverrides: &ethapi.StateOverride{counter: ethapi.OverrideAccount{Code: &logger}}},
+			status:     types.ReceiptStatusSuccessful,
+			gasChanged: true, added: 1, removed: 1,
+		},
+		// Lowered gas allowance runs out of gas
+		{
+			config:        &ReplayConfig{Gas: &gas},
+			status:        types.ReceiptStatusFailed,
+			statusChanged: true, gasChanged: true, removed: 1,
+		},
+	}
+	for i, tt := range tests {
+		res, err := api.ReplayModifiedTransaction(context.Background(), txs[1].Hash(), tt.config)
+		if err != nil {
+			t.Fatalf("test %d: failed to replay: %v", i, err)
+		}
+		if res.Original.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) || len(res.Original.Logs) != 1 {
+			t.Fatalf("test %d: original outcome mismatch: have %+v", i, res.Original)
+		}
+		if uint64(res.Replayed.Status) != tt.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, res.Replayed.Status, tt.status)
+		}
+		if res.Diff.StatusChanged != tt.statusChanged {
+			t.Errorf("test %d: status change mismatch: have %v, want %v", i, res.Diff.StatusChanged, tt.statusChanged)
+		}
+		if delta := int64(res.Replayed.GasUsed) - int64(res.Original.GasUsed); res.Diff.GasDelta != delta || (delta != 0) != tt.gasChanged {
+			t.Errorf("test %d: gas delta mismatch: have %d, replayed %d, original %d", i, res.Diff.GasDelta, res.Replayed.GasUsed, res.Original.GasUsed)
+		}
+		if len(res.Diff.LogsAdded) != tt.added || len(res.Diff.LogsRemoved) != tt.removed {
+			t.Errorf("test %d: log diff mismatch: have %d added, %d removed, want %d added, %d removed",
+				i, len(res.Diff.LogsAdded), len(res.Diff.LogsRemoved), tt.added, tt.removed)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// PrivateTraceAPI is the collection of OpenEthereum compatible tracing APIs
+// exposed over the private trace endpoint.
+type PrivateTraceAPI struct {
+	eth   *Ethereum
+	debug *PrivateDebugAPI
+}
+
+// NewPrivateTraceAPI creates a new API definition for the OpenEthereum compatible
+// trace methods of the Ethereum service.
+func NewPrivateTraceAPI(eth *Ethereum) *PrivateTraceAPI {
+	return &PrivateTraceAPI{eth: eth, debug: NewPrivateDebugAPI(eth)}
+}
+
+// localizedTrace is a call trace annotated with its position within the chain.
+// Block reward traces are not associated with any transaction.
+type localizedTrace struct {
+	*tracers.ParityTrace
+	BlockHash           common.Hash  `json:"blockHash"`
+	BlockNumber         uint64       `json:"blockNumber"`
+	TransactionHash     *common.Hash `json:"transactionHash"`
+	TransactionPosition *uint64      `json:"transactionPosition"`
+}
+
+// traceResults contains the requested kinds of traces of a replayed transaction,
+// the ones not requested are left empty.
+type traceResults struct {
+	Output    hexutil.Bytes           `json:"output"`
+	StateDiff tracers.ParityStateDiff `json:"stateDiff"`
+	Trace     []*tracers.ParityTrace  `json:"trace"`
+	VMTrace   *tracers.ParityVMTrace  `json:"vmTrace"`
+}
+
+// traceModes are the kinds of traces requested when replaying a transaction.
+type traceModes struct {
+	trace     bool
+	vmTrace   bool
+	stateDiff bool
+}
+
+// parseTraceModes converts the trace types requested via RPC into trace modes.
+func parseTraceModes(types []string) (traceModes, error) {
+	var modes traceModes
+	for _, typ := range types {
+		switch typ {
+		case "trace":
+			modes.trace = true
+		case "vmTrace":
+			modes.vmTrace = true
+		case "stateDiff":
+			modes.stateDiff = true
+		default:
+			return traceModes{}, fmt.Errorf("unknown trace type %q", typ)
+		}
+	}
+	return modes, nil
+}
+
+// traceFilterArgs are the criteria of trace_filter. Empty address lists match
+// every trace, missing block numbers default to the current head.
+type traceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"` // Number of matching traces to skip
+	Count       *uint64          `json:"count"` // Maximum number of traces to return
+}
+
+// Block returns the call traces of all the transactions within a block, followed
+// by the traces of the mining rewards.
+func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*localizedTrace, error) {
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceBlock(ctx, block)
+}
+
+// Transaction returns the call traces of a single transaction.
+func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*localizedTrace, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.eth.blockchain.GetBlock(blockHash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	msg, vmctx, statedb, err := api.debug.computeTxEnv(block, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	statedb.Prepare(hash, blockHash, int(index))
+	res, err := api.replayTx(msg, vmctx, statedb, traceModes{trace: true})
+	if err != nil {
+		return nil, err
+	}
+	return localizeTraces(res.Trace, block, tx.Hash(), index), nil
+}
+
+// ReplayTransaction re-executes a transaction, returning the requested kinds of
+// traces out of "trace", "vmTrace" and "stateDiff".
+func (api *PrivateTraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*traceResults, error) {
+	modes, err := parseTraceModes(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	block := api.eth.blockchain.GetBlock(blockHash, blockNumber)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	msg, vmctx, statedb, err := api.debug.computeTxEnv(block, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	statedb.Prepare(hash, blockHash, int(index))
+	return api.replayTx(msg, vmctx, statedb, modes)
+}
+
+// Call executes the given call on top of the requested block, or the latest one
+// if none is specified, returning the requested kinds of traces out of "trace",
+// "vmTrace" and "stateDiff".
+func (api *PrivateTraceAPI) Call(ctx context.Context, args ethapi.CallArgs, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash) (*traceResults, error) {
+	modes, err := parseTraceModes(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	statedb, header, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	msg := args.ToMessage(api.eth.APIBackend.RPCGasCap())
+	vmctx := core.NewEVMBlockContext(header, api.eth.blockchain, nil)
+	return api.replayTx(msg, vmctx, statedb, modes)
+}
+
+// Filter returns the call and reward traces within a range of blocks matching
+// the given sender and recipient addresses, paginated by skipping the first
+// after matches and returning at most count of the rest.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args traceFilterArgs) ([]*localizedTrace, error) {
+	head := api.eth.blockchain.CurrentBlock().NumberU64()
+	resolve := func(number *rpc.BlockNumber) uint64 {
+		switch {
+		case number == nil || *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber:
+			return head
+		case *number == rpc.EarliestBlockNumber:
+			return 0
+		default:
+			return uint64(*number)
+		}
+	}
+	from, to := resolve(args.FromBlock), resolve(args.ToBlock)
+	if from > to {
+		return nil, fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to, from)
+	}
+	if to > head {
+		return nil, fmt.Errorf("end block (#%d) is beyond the current head (#%d)", to, head)
+	}
+	var (
+		fromAddrs = make(map[common.Address]bool)
+		toAddrs   = make(map[common.Address]bool)
+		skip      uint64
+		matches   = []*localizedTrace{}
+	)
+	for _, addr := range args.FromAddress {
+		fromAddrs[addr] = true
+	}
+	for _, addr := range args.ToAddress {
+		toAddrs[addr] = true
+	}
+	if args.After != nil {
+		skip = *args.After
+	}
+	for number := from; number <= to; number++ {
+		block := api.eth.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		traces, err := api.traceBlock(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			if !matchTrace(trace.ParityTrace, fromAddrs, toAddrs) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			matches = append(matches, trace)
+			if args.Count != nil && uint64(len(matches)) >= *args.Count {
+				return matches, nil
+			}
+		}
+	}
+	return matches, nil
+}
+
+// blockByNumber retrieves a canonical block, resolving the special block numbers.
+func (api *PrivateTraceAPI) blockByNumber(number rpc.BlockNumber) (*types.Block, error) {
+	var block *types.Block
+	switch number {
+	case rpc.PendingBlockNumber, rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	case rpc.EarliestBlockNumber:
+		block = api.eth.blockchain.GetBlockByNumber(0)
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// traceBlock re-executes all the transactions within a block, returning their
+// call traces followed by the traces of the mining rewards.
+func (api *PrivateTraceAPI) traceBlock(ctx context.Context, block *types.Block) ([]*localizedTrace, error) {
+	traces := []*localizedTrace{}
+	if txs := block.Transactions(); len(txs) > 0 {
+		parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+		if parent == nil {
+			return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+		}
+		statedb, err := api.debug.computeStateDB(parent, defaultTraceReexec)
+		if err != nil {
+			return nil, err
+		}
+		var (
+			signer   = types.MakeSigner(api.eth.blockchain.Config(), block.Number())
+			blockCtx = core.NewEVMBlockContext(block.Header(), api.eth.blockchain, nil)
+		)
+		for i, tx := range txs {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			msg, _ := tx.AsMessage(signer)
+			statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+			res, err := api.replayTx(msg, blockCtx, statedb, traceModes{trace: true})
+			if err != nil {
+				return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+			}
+			traces = append(traces, localizeTraces(res.Trace, block, tx.Hash(), uint64(i))...)
+		}
+	}
+	return append(traces, api.rewardTraces(block)...), nil
+}
+
+// replayTx executes the given message on top of the provided state, assembling
+// the requested kinds of traces. The state is finalised afterwards, so further
+// transactions can be executed on top.
+func (api *PrivateTraceAPI) replayTx(message core.Message, vmctx vm.BlockContext, statedb *state.StateDB, modes traceModes) (*traceResults, error) {
+	var (
+		tracer = tracers.NewParityTracer(modes.vmTrace)
+		config = api.eth.blockchain.Config()
+		pre    *state.StateDB
+	)
+	if modes.stateDiff {
+		pre = statedb.Copy()
+	}
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(message), statedb, config, vm.Config{Debug: true, Tracer: tracer})
+
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	statedb.Finalise(config.IsEIP158(vmctx.BlockNumber))
+
+	res := &traceResults{Output: result.ReturnData}
+	if res.Output == nil {
+		res.Output = hexutil.Bytes{}
+	}
+	if modes.trace {
+		res.Trace = tracer.Traces()
+	}
+	if modes.vmTrace {
+		res.VMTrace = tracer.VMTrace()
+	}
+	if modes.stateDiff {
+		res.StateDiff = tracer.StateDiff(pre, statedb, vmctx.Coinbase)
+	}
+	return res, nil
+}
+
+// rewardTraces assembles the traces of the mining rewards of a block, if the
+// chain is rewarding any.
+func (api *PrivateTraceAPI) rewardTraces(block *types.Block) []*localizedTrace {
+	if _, ok := api.eth.engine.(*ethash.Ethash); !ok || block.NumberU64() == 0 {
+		return nil
+	}
+	reward := func(author common.Address, typ string, value *big.Int) *localizedTrace {
+		return &localizedTrace{
+			ParityTrace: &tracers.ParityTrace{
+				Action: &tracers.ParityTraceAction{
+					Author:     &author,
+					RewardType: typ,
+					Value:      (*hexutil.Big)(value),
+				},
+				TraceAddress: []int{},
+				Type:         "reward",
+			},
+			BlockHash:   block.Hash(),
+			BlockNumber: block.NumberU64(),
+		}
+	}
+	minerReward, uncleRewards := ethash.BlockRewards(api.eth.blockchain.Config(), block.Header(), block.Uncles())
+
+	traces := []*localizedTrace{reward(block.Coinbase(), "block", minerReward)}
+	for i, uncle := range block.Uncles() {
+		traces = append(traces, reward(uncle.Coinbase, "uncle", uncleRewards[i]))
+	}
+	return traces
+}
+
+// localizeTraces annotates the call traces of a transaction with its position.
+func localizeTraces(traces []*tracers.ParityTrace, block *types.Block, hash common.Hash, index uint64) []*localizedTrace {
+	localized := make([]*localizedTrace, len(traces))
+	for i, trace := range traces {
+		localized[i] = &localizedTrace{
+			ParityTrace:         trace,
+			BlockHash:           block.Hash(),
+			BlockNumber:         block.NumberU64(),
+			TransactionHash:     &hash,
+			TransactionPosition: &index,
+		}
+	}
+	return localized
+}
+
+// matchTrace checks whether the sender and recipient of a trace are contained
+// within the given address sets. Empty sets match any address.
+func matchTrace(trace *tracers.ParityTrace, fromAddrs, toAddrs map[common.Address]bool) bool {
+	var from, to *common.Address
+	switch trace.Type {
+	case "call":
+		from, to = trace.Action.From, trace.Action.To
+	case "create":
+		from = trace.Action.From
+		if trace.Result != nil {
+			to = trace.Result.Address
+		}
+	case "suicide":
+		from, to = trace.Action.Address, trace.Action.RefundAddress
+	case "reward":
+		to = trace.Action.Author
+	}
+	if len(fromAddrs) > 0 && (from == nil || !fromAddrs[*from]) {
+		return false
+	}
+	if len(toAddrs) > 0 && (to == nil || !toAddrs[*to]) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,599 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// defaultTraceJobShardSize is the number of blocks traced into a single output
+	// file by default. Shards are the unit of work and of checkpointing.
+	defaultTraceJobShardSize = 1000
+
+	// traceJobCheckpoint is the name of the file the state of a job is persisted in.
+	traceJobCheckpoint = "job.json"
+)
+
+// Trace job statuses.
+const (
+	traceJobRunning     = "running"
+	traceJobCompleted   = "completed"
+	traceJobFailed      = "failed"
+	traceJobCancelled   = "cancelled"
+	traceJobInterrupted = "interrupted" // Stopped by a node shutdown
+)
+
+var (
+	errTraceJobsDisabled = errors.New("trace jobs need a data directory")
+	errTraceJobNotFound  = errors.New("trace job not found")
+)
+
+// TraceJobConfig holds the parameters of a block range tracing job.
+type TraceJobConfig struct {
+	TraceConfig
+	Workers   int    // Number of shards traced concurrently, defaults to the number of CPUs
+	ShardSize uint64 // Number of blocks traced into each output file
+}
+
+// TraceJobStatus is the progress report of a block range tracing job.
+type TraceJobStatus struct {
+	ID           string         `json:"id"`
+	Status       string         `json:"status"`
+	Error        string         `json:"error,omitempty"`
+	Start        hexutil.Uint64 `json:"start"`
+	End          hexutil.Uint64 `json:"end"`
+	Dir          string         `json:"dir"`
+	Shards       int            `json:"shards"`
+	ShardsDone   int            `json:"shardsDone"`
+	BlocksDone   hexutil.Uint64 `json:"blocksDone"`
+	Transactions hexutil.Uint64 `json:"transactions"` // Transactions traced since the job was last started
+}
+
+// traceJobLine is a single line of a trace job's output, holding the trace of
+// one transaction.
+type traceJobLine struct {
+	Block     hexutil.Uint64 `json:"block"`
+	BlockHash common.Hash    `json:"blockHash"`
+	TxHash    common.Hash    `json:"txHash"`
+	TxIndex   int            `json:"txIndex"`
+	Result    interface{}    `json:"result,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// traceJob is a block range split into shards of consecutive blocks, traced into
+// one JSONL file each. The completed shards are checkpointed on disk along with
+// the job configuration, so that an interrupted job can be resumed.
+type traceJob struct {
+	blocks uint64 // Blocks traced in the shards in progress (atomic, first for 64-bit alignment)
+	txs    uint64 // Transactions traced since the job was started (atomic)
+
+	ID     string          `json:"id"`
+	Start  uint64          `json:"start"`
+	End    uint64          `json:"end"`
+	Config *TraceJobConfig `json:"config"`
+	Status string          `json:"status"`
+	Error  string          `json:"error,omitempty"`
+	Done   []int           `json:"done"` // Indexes of the completed shards
+
+	dir    string
+	done   map[int]bool
+	cancel context.CancelFunc // Stops the job if it is running
+	ended  chan struct{}      // Closed when a running job stops
+	lock   sync.Mutex
+}
+
+// shards returns the number of shards the job's block range is split into.
+func (job *traceJob) shards() int {
+	return int((job.End-job.Start)/job.Config.ShardSize) + 1
+}
+
+// shardRange returns the first and last block of a shard.
+func (job *traceJob) shardRange(shard int) (uint64, uint64) {
+	first := job.Start + uint64(shard)*job.Config.ShardSize
+	last := first + job.Config.ShardSize - 1
+	if last > job.End {
+		last = job.End
+	}
+	return first, last
+}
+
+// status assembles the progress report of the job.
+func (job *traceJob) status() *TraceJobStatus {
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	blocks := atomic.LoadUint64(&job.blocks)
+	for shard := range job.done {
+		first, last := job.shardRange(shard)
+		blocks += last - first + 1
+	}
+	return &TraceJobStatus{
+		ID:           job.ID,
+		Status:       job.Status,
+		Error:        job.Error,
+		Start:        hexutil.Uint64(job.Start),
+		End:          hexutil.Uint64(job.End),
+		Dir:          job.dir,
+		Shards:       job.shards(),
+		ShardsDone:   len(job.done),
+		BlocksDone:   hexutil.Uint64(blocks),
+		Transactions: hexutil.Uint64(atomic.LoadUint64(&job.txs)),
+	}
+}
+
+// persist writes the checkpoint of the job to disk. The caller must hold the lock.
+func (job *traceJob) persist() error {
+	job.Done = job.Done[:0]
+	for shard := range job.done {
+		job.Done = append(job.Done, shard)
+	}
+	sort.Ints(job.Done)
+
+	blob, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(job.dir, traceJobCheckpoint)
+	if err := ioutil.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// traceJobManager runs block range tracing jobs and keeps track of all the jobs
+// ever started on the node.
+type traceJobManager struct {
+	eth  *Ethereum
+	root string // Directory holding one subdirectory per job, empty if disabled
+
+	jobs map[string]*traceJob
+	lock sync.Mutex
+}
+
+// newTraceJobManager creates a trace job manager storing its jobs in the given
+// directory, loading the ones persisted by earlier runs of the node.
+func newTraceJobManager(eth *Ethereum, root string) *traceJobManager {
+	m := &traceJobManager{
+		eth:  eth,
+		root: root,
+		jobs: make(map[string]*traceJob),
+	}
+	if root == "" {
+		return m
+	}
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warn("Failed to list trace jobs", "dir", root, "err", err)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(root, dir.Name())
+		blob, err := ioutil.ReadFile(filepath.Join(path, traceJobCheckpoint))
+		if err != nil {
+			continue
+		}
+		job := &traceJob{dir: path, done: make(map[int]bool)}
+		if err := json.Unmarshal(blob, job); err != nil || job.Config == nil {
+			log.Warn("Failed to load trace job", "dir", path, "err", err)
+			continue
+		}
+		for _, shard := range job.Done {
+			job.done[shard] = true
+		}
+		// The node went down without stopping the job properly
+		if job.Status == traceJobRunning {
+			job.Status = traceJobInterrupted
+		}
+		m.jobs[job.ID] = job
+	}
+	return m
+}
+
+// start creates a new tracing job over the given range of blocks and runs it.
+func (m *traceJobManager) start(start, end uint64, config *TraceJobConfig) (*traceJob, error) {
+	if m.root == "" {
+		return nil, errTraceJobsDisabled
+	}
+	if config == nil {
+		config = new(TraceJobConfig)
+	}
+	if config.ShardSize == 0 {
+		config.ShardSize = defaultTraceJobShardSize
+	}
+	id := string(rpc.NewID())
+	job := &traceJob{
+		ID:     id,
+		Start:  start,
+		End:    end,
+		Config: config,
+		dir:    filepath.Join(m.root, id),
+		done:   make(map[int]bool),
+	}
+	if err := os.MkdirAll(job.dir, 0755); err != nil {
+		return nil, err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.run(job); err != nil {
+		return nil, err
+	}
+	m.jobs[id] = job
+	return job, nil
+}
+
+// resume restarts a stopped job from its last checkpoint.
+func (m *traceJobManager) resume(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return errTraceJobNotFound
+	}
+	job.lock.Lock()
+	status := job.Status
+	job.lock.Unlock()
+
+	switch status {
+	case traceJobRunning:
+		return errors.New("trace job already running")
+	case traceJobCompleted:
+		return errors.New("trace job already completed")
+	}
+	return m.run(job)
+}
+
+// cancel stops a running job, keeping its checkpoint so it can be resumed.
+func (m *traceJobManager) cancel(id string) error {
+	m.lock.Lock()
+	job, ok := m.jobs[id]
+	m.lock.Unlock()
+
+	if !ok {
+		return errTraceJobNotFound
+	}
+	return job.stop(traceJobCancelled)
+}
+
+// stop terminates the job with the given status and waits until all its workers
+// have exited.
+func (job *traceJob) stop(status string) error {
+	job.lock.Lock()
+	if job.Status != traceJobRunning {
+		job.lock.Unlock()
+		return errors.New("trace job not running")
+	}
+	job.Status = status
+	cancel, ended := job.cancel, job.ended
+	job.lock.Unlock()
+
+	cancel()
+	<-ended
+	return nil
+}
+
+// status returns the progress report of a job.
+func (m *traceJobManager) status(id string) (*TraceJobStatus, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, errTraceJobNotFound
+	}
+	return job.status(), nil
+}
+
+// list returns the progress reports of all the jobs, ordered by id.
+func (m *traceJobManager) list() []*TraceJobStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	statuses := make([]*TraceJobStatus, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		statuses = append(statuses, job.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// close interrupts all the running jobs.
+func (m *traceJobManager) close() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, job := range m.jobs {
+		job.stop(traceJobInterrupted)
+	}
+}
+
+// run marks the job as running and starts tracing its remaining shards in the
+// background. The caller must hold the manager lock.
+func (m *traceJobManager) run(job *traceJob) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	job.lock.Lock()
+	job.Status, job.Error = traceJobRunning, ""
+	job.cancel, job.ended = cancel, make(chan struct{})
+	atomic.StoreUint64(&job.txs, 0)
+	err := job.persist()
+	job.lock.Unlock()
+
+	if err != nil {
+		cancel()
+		return err
+	}
+	workers := job.Config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var (
+		pend   sync.WaitGroup
+		shards = make(chan int)
+		failed error
+		once   sync.Once
+	)
+	for i := 0; i < workers; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+
+			for shard := range shards {
+				if err := m.traceShard(ctx, job, shard); err != nil {
+					if ctx.Err() == nil {
+						once.Do(func() { failed = err })
+						cancel()
+					}
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(job.ended)
+
+		begin := time.Now()
+	feed:
+		for shard := 0; shard < job.shards(); shard++ {
+			job.lock.Lock()
+			done := job.done[shard]
+			job.lock.Unlock()
+
+			if done {
+				continue
+			}
+			select {
+			case shards <- shard:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(shards)
+		pend.Wait()
+		cancel()
+
+		job.lock.Lock()
+		defer job.lock.Unlock()
+
+		atomic.StoreUint64(&job.blocks, 0)
+		switch {
+		case failed != nil:
+			job.Status, job.Error = traceJobFailed, failed.Error()
+			log.Warn("Trace job failed", "id", job.ID, "elapsed", common.PrettyDuration(time.Since(begin)), "err", failed)
+		case job.Status == traceJobRunning:
+			job.Status = traceJobCompleted
+			log.Info("Trace job completed", "id", job.ID, "elapsed", common.PrettyDuration(time.Since(begin)))
+		default:
+			log.Info("Trace job stopped", "id", job.ID, "status", job.Status, "elapsed", common.PrettyDuration(time.Since(begin)))
+		}
+		if err := job.persist(); err != nil {
+			log.Error("Failed to persist trace job", "id", job.ID, "err", err)
+		}
+	}()
+	return nil
+}
+
+// traceShard traces all the transactions in the blocks of a shard into the
+// shard's output file and checkpoints its completion.
+func (m *traceJobManager) traceShard(ctx context.Context, job *traceJob, shard int) error {
+	var (
+		api         = NewPrivateDebugAPI(m.eth)
+		chain       = m.eth.blockchain
+		first, last = job.shardRange(shard)
+	)
+	block := chain.GetBlockByNumber(first)
+	if block == nil {
+		return fmt.Errorf("block #%d not found", first)
+	}
+	parent := chain.GetBlock(block.ParentHash(), first-1)
+	if parent == nil {
+		return fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	reexec := defaultTraceReexec
+	if job.Config.Reexec != nil {
+		reexec = *job.Config.Reexec
+	}
+	// Trace on top of a private trie database, so the state of every traced block
+	// can be released as soon as the next one is done, like in traceChain
+	database := state.NewDatabaseWithConfig(m.eth.ChainDb(), &trie.Config{Cache: 16, Preimages: true})
+	statedb, err := state.New(parent.Root(), database, nil)
+	if err != nil {
+		if statedb, err = api.computeStateDB(parent, reexec); err != nil {
+			return err
+		}
+		database = statedb.Database()
+	}
+	// Trace into a temporary file, only completed shards are made visible
+	path := filepath.Join(job.dir, fmt.Sprintf("shard-%09d-%09d.jsonl", first, last))
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var (
+		out   = bufio.NewWriter(file)
+		enc   = json.NewEncoder(out)
+		proot common.Hash
+	)
+	for number := first; number <= last; number++ {
+		if number > first {
+			if block = chain.GetBlockByNumber(number); block == nil {
+				return fmt.Errorf("block #%d not found", number)
+			}
+		}
+		var (
+			signer   = types.MakeSigner(chain.Config(), block.Number())
+			blockCtx = core.NewEVMBlockContext(block.Header(), chain, nil)
+		)
+		for i, tx := range block.Transactions() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			msg, _ := tx.AsMessage(signer)
+			statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+			line := &traceJobLine{
+				Block:     hexutil.Uint64(number),
+				BlockHash: block.Hash(),
+				TxHash:    tx.Hash(),
+				TxIndex:   i,
+			}
+			if res, err := api.traceTx(ctx, msg, blockCtx, statedb, &job.Config.TraceConfig); err != nil {
+				line.Error = err.Error()
+			} else {
+				line.Result = res
+			}
+			if err := enc.Encode(line); err != nil {
+				return err
+			}
+			// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
+			statedb.Finalise(chain.Config().IsEIP158(block.Number()))
+			atomic.AddUint64(&job.txs, 1)
+		}
+		// Apply the block rewards on top of the traced transactions and move the
+		// state forward, checking it against the block
+		chain.Engine().Finalize(chain, block.Header(), statedb, block.Transactions(), block.Uncles())
+
+		root, err := statedb.Commit(chain.Config().IsEIP158(block.Number()))
+		if err != nil {
+			return err
+		}
+		if root != block.Root() {
+			return fmt.Errorf("block #%d state root mismatch: have %x, want %x", number, root, block.Root())
+		}
+		if err := statedb.Reset(root); err != nil {
+			return fmt.Errorf("state reset after block %d failed: %v", number, err)
+		}
+		// Dereference the state of the previous block, it's no longer needed
+		database.TrieDB().Reference(root, common.Hash{})
+		if proot != (common.Hash{}) {
+			database.TrieDB().Dereference(proot)
+		}
+		proot = root
+		atomic.AddUint64(&job.blocks, 1)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	job.lock.Lock()
+	defer job.lock.Unlock()
+
+	// Move the blocks of the shard from the in-progress to the completed count
+	job.done[shard] = true
+	atomic.AddUint64(&job.blocks, ^(last - first))
+	return job.persist()
+}
+
+// StartTraceJob traces all the transactions in the given block range (both ends
+// included) into sharded JSONL files on the node's disk, using the configured
+// number of concurrent workers. The returned job id can be used to query the
+// progress of the job, to cancel it and to resume it, even after a restart.
+func (api *PrivateDebugAPI) StartTraceJob(start, end rpc.BlockNumber, config *TraceJobConfig) (string, error) {
+	if start == rpc.PendingBlockNumber || end == rpc.PendingBlockNumber {
+		return "", errors.New("pending block cannot be traced")
+	}
+	from, to := api.blockByNumber(start), api.blockByNumber(end)
+	if from == nil {
+		return "", fmt.Errorf("starting block #%d not found", start)
+	}
+	if to == nil {
+		return "", fmt.Errorf("end block #%d not found", end)
+	}
+	if from.NumberU64() == 0 {
+		return "", errors.New("genesis is not traceable")
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return "", fmt.Errorf("end block (#%d) needs to come after start block (#%d)", end, start)
+	}
+	job, err := api.eth.traceJobs.start(from.NumberU64(), to.NumberU64(), config)
+	if err != nil {
+		return "", err
+	}
+	log.Info("Trace job started", "id", job.ID, "start", job.Start, "end", job.End, "dir", job.dir)
+	return job.ID, nil
+}
+
+// TraceJobStatus returns the progress of a block range tracing job.
+func (api *PrivateDebugAPI) TraceJobStatus(id string) (*TraceJobStatus, error) {
+	return api.eth.traceJobs.status(id)
+}
+
+// TraceJobs returns the progress of all the block range tracing jobs.
+func (api *PrivateDebugAPI) TraceJobs() []*TraceJobStatus {
+	return api.eth.traceJobs.list()
+}
+
+// CancelTraceJob stops a running block range tracing job. The shards traced so
+// far are kept and the job may be resumed later.
+func (api *PrivateDebugAPI) CancelTraceJob(id string) error {
+	return api.eth.traceJobs.cancel(id)
+}
+
+// ResumeTraceJob restarts a cancelled, failed or interrupted block range tracing
+// job from its last checkpoint.
+func (api *PrivateDebugAPI) ResumeTraceJob(id string) error {
+	return api.eth.traceJobs.resume(id)
+}
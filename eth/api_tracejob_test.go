@@ -0,0 +1,122 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a block range is traced into sharded files, and that an interrupted
+// job only retraces its unfinished shards when resumed.
+func TestTraceJob(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 8, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "tracejobs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	eth := &Ethereum{blockchain: chain, chainDb: db, sources: tracers.NewSourceRegistry()}
+	manager := newTraceJobManager(eth, dir)
+
+	job, err := manager.start(1, 8, &TraceJobConfig{Workers: 2, ShardSize: 3})
+	if err != nil {
+		t.Fatalf("failed to start job: %v", err)
+	}
+	<-job.ended
+
+	status := job.status()
+	if status.Status != traceJobCompleted || status.ShardsDone != 3 || status.BlocksDone != 8 || status.Transactions != 8 {
+		t.Fatalf("status mismatch: have %+v", status)
+	}
+	shards := map[string]int{
+		"shard-000000001-000000003.jsonl": 3,
+		"shard-000000004-000000006.jsonl": 3,
+		"shard-000000007-000000008.jsonl": 2,
+	}
+	for name, lines := range shards {
+		blob, err := ioutil.ReadFile(filepath.Join(job.dir, name))
+		if err != nil {
+			t.Fatalf("missing shard %s: %v", name, err)
+		}
+		if have := bytes.Count(blob, []byte("\n")); have != lines {
+			t.Errorf("shard %s: line count mismatch: have %d, want %d", name, have, lines)
+		}
+	}
+	// Simulate a crash while tracing the middle shard and restart the manager
+	job.lock.Lock()
+	job.Status = traceJobRunning
+	delete(job.done, 1)
+	job.persist()
+	job.lock.Unlock()
+	os.Remove(filepath.Join(job.dir, "shard-000000004-000000006.jsonl"))
+
+	manager = newTraceJobManager(eth, dir)
+	if status, _ := manager.status(job.ID); status.Status != traceJobInterrupted || status.ShardsDone != 2 {
+		t.Fatalf("reloaded status mismatch: have %+v", status)
+	}
+	if err := manager.resume(job.ID); err != nil {
+		t.Fatalf("failed to resume job: %v", err)
+	}
+	<-manager.jobs[job.ID].ended
+
+	status, _ = manager.status(job.ID)
+	if status.Status != traceJobCompleted || status.ShardsDone != 3 || status.Transactions != 3 {
+		t.Fatalf("resumed status mismatch: have %+v", status)
+	}
+	if _, err := os.Stat(filepath.Join(job.dir, "shard-000000004-000000006.jsonl")); err != nil {
+		t.Errorf("retraced shard missing: %v", err)
+	}
+	if err := manager.resume(job.ID); err == nil {
+		t.Errorf("resumed completed job")
+	}
+}
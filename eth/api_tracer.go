@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -63,6 +64,14 @@ type TraceConfig struct {
 	Reexec  *uint64
 }
 
+// TraceCallConfig is the config for traceCall API. It holds one more
+// field to override the state and block context for tracing.
+type TraceCallConfig struct {
+	TraceConfig
+	StateOverrides *ethapi.StateOverride
+	BlockOverrides *ethapi.BlockOverrides
+}
+
 // StdTraceConfig holds extra parameters to standard-json trace functions.
 type StdTraceConfig struct {
 	vm.LogConfig
@@ -104,24 +113,8 @@ type txTraceTask struct {
 // between two blocks (excluding start) and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.BlockNumber, config *TraceConfig) (*rpc.Subscription, error) {
 	// Fetch the block interval that we want to trace
-	var from, to *types.Block
+	from, to := api.blockByNumber(start), api.blockByNumber(end)
 
-	switch start {
-	case rpc.PendingBlockNumber:
-		from = api.eth.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		from = api.eth.blockchain.CurrentBlock()
-	default:
-		from = api.eth.blockchain.GetBlockByNumber(uint64(start))
-	}
-	switch end {
-	case rpc.PendingBlockNumber:
-		to = api.eth.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		to = api.eth.blockchain.CurrentBlock()
-	default:
-		to = api.eth.blockchain.GetBlockByNumber(uint64(end))
-	}
 	// Trace the chain if we've found all our blocks
 	if from == nil {
 		return nil, fmt.Errorf("starting block #%d not found", start)
@@ -135,6 +128,19 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 	return api.traceChain(ctx, from, to, config)
 }
 
+// blockByNumber retrieves a block by number, resolving the pending and latest
+// block tags.
+func (api *PrivateDebugAPI) blockByNumber(number rpc.BlockNumber) *types.Block {
+	switch number {
+	case rpc.PendingBlockNumber:
+		return api.eth.miner.PendingBlock()
+	case rpc.LatestBlockNumber:
+		return api.eth.blockchain.CurrentBlock()
+	default:
+		return api.eth.blockchain.GetBlockByNumber(uint64(number))
+	}
+}
+
 // traceChain configures a new tracer according to the provided configuration, and
 // executes all the transactions contained within. The return value will be one item
 // per transaction, dependent on the requested tracer.
@@ -288,7 +294,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 				traced += uint64(len(txs))
 			}
 			// Generate the next state snapshot fast without tracing
-			_, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
+			_, _, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
 			if err != nil {
 				failed = err
 				break
@@ -650,17 +656,24 @@ func containsTx(block *types.Block, hash common.Hash) bool {
 // If no state is locally available for the given block, a number of blocks are
 // attempted to be reexecuted to generate the desired state.
 func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*state.StateDB, error) {
+	return regenerateStateDB(api.eth.blockchain, api.eth.ChainDb(), block, reexec)
+}
+
+// regenerateStateDB retrieves the state database associated with a certain block
+// of the given chain, reexecuting at most reexec blocks on top of the most recent
+// available state if the block's own state is missing.
+func regenerateStateDB(chain *core.BlockChain, db ethdb.Database, block *types.Block, reexec uint64) (*state.StateDB, error) {
 	// If we have the state fully available, use that
-	statedb, err := api.eth.blockchain.StateAt(block.Root())
+	statedb, err := chain.StateAt(block.Root())
 	if err == nil {
 		return statedb, nil
 	}
 	// Otherwise try to reexec blocks until we find a state or reach our limit
 	origin := block.NumberU64()
-	database := state.NewDatabaseWithConfig(api.eth.ChainDb(), &trie.Config{Cache: 16, Preimages: true})
+	database := state.NewDatabaseWithConfig(db, &trie.Config{Cache: 16, Preimages: true})
 
 	for i := uint64(0); i < reexec; i++ {
-		block = api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+		block = chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
 		if block == nil {
 			break
 		}
@@ -689,15 +702,15 @@ func (api *PrivateDebugAPI) computeStateDB(block *types.Block, reexec uint64) (*
 			logged = time.Now()
 		}
 		// Retrieve the next block to regenerate and process it
-		if block = api.eth.blockchain.GetBlockByNumber(block.NumberU64() + 1); block == nil {
+		if block = chain.GetBlockByNumber(block.NumberU64() + 1); block == nil {
 			return nil, fmt.Errorf("block #%d not found", block.NumberU64()+1)
 		}
-		_, _, _, err := api.eth.blockchain.Processor().Process(block, statedb, vm.Config{})
+		_, _, _, _, err := chain.Processor().Process(block, statedb, vm.Config{})
 		if err != nil {
 			return nil, fmt.Errorf("processing block %d failed: %v", block.NumberU64(), err)
 		}
 		// Finalize the state so any modifications are written to the trie
-		root, err := statedb.Commit(api.eth.blockchain.Config().IsEIP158(block.Number()))
+		root, err := statedb.Commit(chain.Config().IsEIP158(block.Number()))
 		if err != nil {
 			return nil, err
 		}
@@ -742,8 +755,9 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 
 // TraceCall lets you trace a given eth_call. It collects the structured logs created during the execution of EVM
 // if the given transaction was added on top of the provided block and returns them as a JSON object.
-// You can provide -2 as a block number to trace on top of the pending block.
-func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceConfig) (interface{}, error) {
+// You can provide -2 as a block number to trace on top of the pending block. The state and block
+// context the call is executed in can be altered with the overrides in the config.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
 	// First try to retrieve the state
 	statedb, header, err := api.eth.APIBackend.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if err != nil {
@@ -766,19 +780,43 @@ func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs,
 		if err != nil {
 			return nil, err
 		}
+		header = block.Header()
 	}
+	// Apply the customized state and block overrides if any
+	vmctx := core.NewEVMBlockContext(header, api.eth.blockchain, nil)
 
+	var traceConfig *TraceConfig
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+		config.BlockOverrides.Apply(&vmctx)
+		traceConfig = &config.TraceConfig
+	}
 	// Execute the trace
 	msg := args.ToMessage(api.eth.APIBackend.RPCGasCap())
-	vmctx := core.NewEVMBlockContext(header, api.eth.blockchain, nil)
-	return api.traceTx(ctx, msg, vmctx, statedb, config)
+	return api.traceTx(ctx, msg, vmctx, statedb, traceConfig)
+}
+
+// RegisterSourceArtifact registers the compiler output of a contract, annotating
+// the structured logs and reverts of subsequent traces executing its code with
+// the source locations they map to.
+func (api *PrivateDebugAPI) RegisterSourceArtifact(artifact tracers.SourceArtifact) error {
+	return api.eth.sources.Register(&artifact)
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	trace, _, err := api.traceMessage(ctx, message, vmctx, statedb, config)
+	return trace, err
+}
+
+// traceMessage is the workhorse of traceTx, additionally returning the raw
+// execution result of the traced message.
+func (api *PrivateDebugAPI) traceMessage(ctx context.Context, message core.Message, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, *core.ExecutionResult, error) {
+	// Assemble the structured logger, the native or the JavaScript tracer
 	var (
 		tracer    vm.Tracer
 		err       error
@@ -790,18 +828,18 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		timeout := defaultTraceTimeout
 		if config.Timeout != nil {
 			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer, txContext); err != nil {
-			return nil, err
+		// Constuct the native or JavaScript tracer to execute with
+		if tracer, err = tracers.NewTxTracer(*config.Tracer, txContext); err != nil {
+			return nil, nil, err
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			tracer.(tracers.TxTracer).Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -811,12 +849,21 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	default:
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
+	// Resolve the structured logs against any registered compiler artifacts
+	var sources *tracers.SourceTracer
+	if logger, ok := tracer.(*vm.StructLogger); ok {
+		sources = tracers.NewSourceTracer(logger, api.eth.sources)
+	}
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.eth.blockchain.Config(), vm.Config{Debug: true, Tracer: tracer})
+	vmconf := vm.Config{Debug: true, Tracer: tracer}
+	if sources != nil {
+		vmconf.Tracer = sources
+	}
+	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.eth.blockchain.Config(), vmconf)
 
 	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
-		return nil, fmt.Errorf("tracing failed: %v", err)
+		return nil, nil, fmt.Errorf("tracing failed: %v", err)
 	}
 	// Depending on the tracer type, format and return the output
 	switch tracer := tracer.(type) {
@@ -826,15 +873,23 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		if len(result.Revert()) > 0 {
 			returnVal = fmt.Sprintf("%x", result.Revert())
 		}
+		logs := ethapi.FormatLogs(tracer.StructLogs())
+		for i := range logs {
+			if i < len(sources.Steps) {
+				logs[i].Source = sources.Steps[i]
+			}
+		}
 		return &ethapi.ExecutionResult{
 			Gas:         result.UsedGas,
 			Failed:      result.Failed(),
 			ReturnValue: returnVal,
-			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
-		}, nil
+			StructLogs:  logs,
+			Reverts:     sources.Reverts,
+		}, result, nil
 
-	case *tracers.Tracer:
-		return tracer.GetResult()
+	case tracers.TxTracer:
+		trace, err := tracer.GetResult()
+		return trace, result, err
 
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
@@ -0,0 +1,119 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that debug_traceCall executes within the overridden block context and state.
+func TestTraceCallOverrides(t *testing.T) {
+	var (
+		reporter = common.HexToAddress("0xc0")
+		coinbase = common.HexToAddress("0xc01b")
+		db       = rawdb.NewMemoryDatabase()
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{reporter: {Balance: new(big.Int), Code: []byte{
+				// Returns the block number, timestamp and coinbase of the context
+				byte(vm.NUMBER), byte(vm.PUSH1), 0, byte(vm.MSTORE),
+				byte(vm.TIMESTAMP), byte(vm.PUSH1), 32, byte(vm.MSTORE),
+				byte(vm.COINBASE), byte(vm.PUSH1), 64, byte(vm.MSTORE),
+				byte(vm.PUSH1), 96, byte(vm.PUSH1), 0, byte(vm.RETURN),
+			}}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, b *core.BlockGen) {})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{blockchain: chain, chainDb: db, sources: tracers.NewSourceRegistry(), config: &Config{RPCGasCap: 25000000}}
+	eth.APIBackend = &EthAPIBackend{false, eth, nil}
+	api := NewPrivateDebugAPI(eth)
+
+	var (
+		head   = chain.CurrentBlock()
+		number = (*hexutil.Big)(big.NewInt(1000))
+		time   = (*hexutil.Big)(big.NewInt(12345))
+		code   = hexutil.Bytes{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN)}
+	)
+	tests := []struct {
+		config *TraceCallConfig
+		want   []common.Hash
+		failed bool
+	}{
+		{
+			want: []common.Hash{common.BigToHash(head.Number()), common.BigToHash(new(big.Int).SetUint64(head.Time())), common.BytesToHash(head.Coinbase().Bytes())},
+		},
+		{
+			config: &TraceCallConfig{BlockOverrides: &ethapi.BlockOverrides{Number: number}},
+			want:   []common.Hash{common.BigToHash(number.ToInt()), common.BigToHash(new(big.Int).SetUint64(head.Time())), common.BytesToHash(head.Coinbase().Bytes())},
+		},
+		{
+			config: &TraceCallConfig{BlockOverrides: &ethapi.BlockOverrides{Time: time, Coinbase: &coinbase}},
+			want:   []common.Hash{common.BigToHash(head.Number()), common.BigToHash(time.ToInt()), common.BytesToHash(coinbase.Bytes())},
+		},
+		{
+			config: &TraceCallConfig{StateOverrides: &ethapi.StateOverride{reporter: ethapi.OverrideAccount{Code: &code}}},
+			want:   []common.Hash{{31: 0x2a}},
+		},
+		{
+			config: &TraceCallConfig{StateOverrides: &ethapi.StateOverride{reporter: ethapi.OverrideAccount{
+				State:     &map[common.Hash]common.Hash{},
+				StateDiff: &map[common.Hash]common.Hash{},
+			}}},
+			failed: true,
+		},
+	}
+	for i, tt := range tests {
+		res, err := api.TraceCall(context.Background(), ethapi.CallArgs{To: &reporter}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), tt.config)
+		if tt.failed {
+			if err == nil {
+				t.Errorf("test %d: trace with invalid overrides succeeded", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: trace failed: %v", i, err)
+		}
+		var want string
+		for _, word := range tt.want {
+			want += fmt.Sprintf("%x", word)
+		}
+		if have := res.(*ethapi.ExecutionResult).ReturnValue; have != want {
+			t.Errorf("test %d: return value mismatch: have %s, want %s", i, have, want)
+		}
+	}
+}
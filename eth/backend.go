@@ -42,6 +42,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -77,6 +78,14 @@ type Ethereum struct {
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
 
+	innerTxIndexer  *core.ChainIndexer // Inner transaction address indexer, nil if recording is disabled
+	creationIndexer *core.ChainIndexer // Contract creation indexer, nil if recording is disabled
+	tokenIndexer    *core.ChainIndexer // Token transfer indexer, nil if indexing is disabled
+	balanceRecorder *balanceRecorder   // Balance change recorder, nil if recording is disabled
+
+	sources   *tracers.SourceRegistry // Compiler artifacts to annotate traces with
+	traceJobs *traceJobManager        // Block range tracing jobs writing to disk
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -140,6 +149,7 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		bloomRequests:     make(chan chan *bloombits.Retrieval),
 		bloomIndexer:      NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 		p2pServer:         stack.Server(),
+		sources:           tracers.NewSourceRegistry(),
 	}
 
 	bcVersion := rawdb.ReadDatabaseVersion(chainDb)
@@ -160,6 +170,7 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	var (
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
+			RecordInnerTxs:          config.EnableInnerTxs,
 			EWASMInterpreter:        config.EWASMInterpreter,
 			EVMInterpreter:          config.EVMInterpreter,
 		}
@@ -173,6 +184,7 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			RevertReasons:       config.EnableRevertReasons,
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
@@ -186,6 +198,19 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.EnableInnerTxs {
+		eth.innerTxIndexer = NewInnerTxIndexer(chainDb, innerTxIndexSectionSize, innerTxIndexConfirms)
+		eth.innerTxIndexer.Start(eth.blockchain)
+		eth.creationIndexer = NewContractCreationIndexer(chainDb, chainConfig, creationIndexSectionSize, creationIndexConfirms)
+		eth.creationIndexer.Start(eth.blockchain)
+	}
+	if config.EnableTokenTransfers {
+		eth.tokenIndexer = NewTokenTransferIndexer(chainDb, chainConfig, tokenIndexSectionSize, tokenIndexConfirms)
+		eth.tokenIndexer.Start(eth.blockchain)
+	}
+	if config.EnableBalanceChanges {
+		eth.balanceRecorder = newBalanceRecorder(eth.blockchain, chainDb)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
@@ -215,6 +240,7 @@ func New(stack *node.Node, config *Config) (*Ethereum, error) {
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
 	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), eth, nil}
+	eth.traceJobs = newTraceJobManager(eth, stack.ResolvePath("tracejobs"))
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
@@ -350,6 +376,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(s),
+		}, {
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewPrivateTraceAPI(s),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -562,8 +592,21 @@ func (s *Ethereum) Stop() error {
 	s.handler.Stop()
 
 	// Then stop everything else.
+	s.traceJobs.close()
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	if s.innerTxIndexer != nil {
+		s.innerTxIndexer.Close()
+	}
+	if s.creationIndexer != nil {
+		s.creationIndexer.Close()
+	}
+	if s.tokenIndexer != nil {
+		s.tokenIndexer.Close()
+	}
+	if s.balanceRecorder != nil {
+		s.balanceRecorder.close()
+	}
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.blockchain.Stop()
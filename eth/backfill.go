@@ -0,0 +1,221 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// errBackfillInterrupted is returned if a backfill is stopped before reaching the
+// end of the requested range.
+var errBackfillInterrupted = errors.New("interrupted")
+
+// backfillTask represents a single block whose data is to be recorded by
+// re-executing it on top of its parent state.
+type backfillTask struct {
+	statedb *state.StateDB // Intermediate state prepped for re-execution
+	block   *types.Block   // Block to re-execute
+	rootref common.Hash    // Trie root reference held for this task
+	err     error          // Failure encountered while re-executing the block
+}
+
+// backfillSpec describes the data recorded by a backfill: how to record it for a
+// single block and where to checkpoint the progress.
+type backfillSpec struct {
+	name             string                                                                     // Name of the backfilled data for logging
+	process          func(*core.BlockChain, ethdb.Database, *types.Block, *state.StateDB) error // Re-executes a block, storing its data
+	readCheckpoint   func(ethdb.KeyValueReader) *uint64                                         // Retrieves the next block of an interrupted backfill
+	writeCheckpoint  func(ethdb.KeyValueWriter, uint64)                                         // Stores the next block to backfill
+	deleteCheckpoint func(ethdb.KeyValueWriter)                                                 // Marks the backfill finished
+}
+
+// backfill re-executes the canonical blocks in the [from, to] range concurrently,
+// recording the data described by spec for each of them and checkpointing the
+// progress as blocks finish. See BackfillInnerTxs for the details.
+func backfill(chain *core.BlockChain, db ethdb.Database, from, to uint64, workers int, reexec uint64, stop <-chan struct{}, spec *backfillSpec) error {
+	// The genesis block has no transactions, nothing to backfill there
+	if from == 0 {
+		from = 1
+	}
+	if to < from {
+		return fmt.Errorf("end block (#%d) needs to come after start block (#%d)", to, from)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	// Resume an interrupted backfill if the checkpoint falls inside the range
+	if next := spec.readCheckpoint(db); next != nil && *next > from && *next <= to+1 {
+		log.Info("Resuming backfill", "data", spec.name, "checkpoint", *next, "from", from, "to", to)
+		from = *next
+	}
+	if from > to {
+		log.Info("Backfill already finished", "data", spec.name, "to", to)
+		spec.deleteCheckpoint(db)
+		return nil
+	}
+	// Ensure we have a valid starting state before doing any work
+	parent := chain.GetBlockByNumber(from - 1)
+	if parent == nil {
+		return fmt.Errorf("parent block #%d not found", from-1)
+	}
+	statedb, err := regenerateStateDB(chain, db, parent, reexec)
+	if err != nil {
+		return err
+	}
+	database := statedb.Database()
+
+	var (
+		pend    = new(sync.WaitGroup)
+		tasks   = make(chan *backfillTask, workers)
+		results = make(chan *backfillTask, workers)
+		abort   = make(chan struct{})
+		failed  error
+	)
+	for th := 0; th < workers; th++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+
+			// Fetch and re-execute the next block backfill tasks
+			for task := range tasks {
+				task.err = spec.process(chain, db, task.block, task.statedb)
+
+				// Report the result back or abort on teardown
+				select {
+				case results <- task:
+				case <-abort:
+					return
+				}
+			}
+		}()
+	}
+	// Start a goroutine to feed all the blocks into the workers
+	go func() {
+		// Ensure everything is properly cleaned up on any exit path
+		defer func() {
+			close(tasks)
+			pend.Wait()
+			close(results)
+		}()
+		proot := common.Hash{}
+		for number := from; number <= to; number++ {
+			// Stop feeding if interruption was requested
+			select {
+			case <-stop:
+				failed = errBackfillInterrupted
+				return
+			case <-abort:
+				return
+			default:
+			}
+			// Retrieve the next block to backfill
+			block := chain.GetBlockByNumber(number)
+			if block == nil {
+				failed = fmt.Errorf("block #%d not found", number)
+				return
+			}
+			select {
+			case tasks <- &backfillTask{statedb: statedb.Copy(), block: block, rootref: proot}:
+			case <-abort:
+				return
+			}
+			// Generate the next state snapshot fast without recording
+			if _, _, _, _, err := chain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+				failed = fmt.Errorf("processing block %d failed: %v", number, err)
+				return
+			}
+			// Finalize the state so any modifications are written to the trie
+			root, err := statedb.Commit(chain.Config().IsEIP158(block.Number()))
+			if err != nil {
+				failed = err
+				return
+			}
+			if err := statedb.Reset(root); err != nil {
+				failed = fmt.Errorf("state reset after block %d failed: %v", number, err)
+				return
+			}
+			// Reference the trie twice, once for us, once for the next task
+			database.TrieDB().Reference(root, common.Hash{})
+			database.TrieDB().Reference(root, common.Hash{})
+
+			// Dereference all past tries we ourselves are done working with
+			if proot != (common.Hash{}) {
+				database.TrieDB().Dereference(proot)
+			}
+			proot = root
+		}
+	}()
+
+	// Keep collecting the results, advancing the checkpoint over finished blocks
+	var (
+		begin  = time.Now()
+		logged = time.Now()
+		done   = make(map[uint64]bool)
+		next   = from
+		errs   error
+	)
+	for task := range results {
+		// Dereference the parent trie held in memory by this task
+		if task.rootref != (common.Hash{}) {
+			database.TrieDB().Dereference(task.rootref)
+		}
+		if task.err != nil {
+			if errs == nil {
+				errs = task.err
+				close(abort)
+			}
+			continue
+		}
+		done[task.block.NumberU64()] = true
+
+		// Persist the checkpoint past all the contiguously finished blocks
+		advanced := false
+		for ; done[next]; next++ {
+			delete(done, next)
+			advanced = true
+		}
+		if advanced {
+			spec.writeCheckpoint(db, next)
+		}
+		// Print progress logs if long enough time elapsed
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Backfilling", "data", spec.name, "from", from, "to", to, "current", next, "remaining", to+1-next, "elapsed", common.PrettyDuration(time.Since(begin)))
+			logged = time.Now()
+		}
+	}
+	if errs == nil {
+		errs = failed
+	}
+	if errs != nil {
+		log.Warn("Backfill stopped", "data", spec.name, "from", from, "to", to, "checkpoint", next, "elapsed", common.PrettyDuration(time.Since(begin)), "err", errs)
+		return errs
+	}
+	spec.deleteCheckpoint(db)
+	log.Info("Backfill finished", "data", spec.name, "from", from, "to", to, "elapsed", common.PrettyDuration(time.Since(begin)))
+	return nil
+}
@@ -0,0 +1,388 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// balanceRecorderChanSize is the size of the channel listening to the chain
+	// events of the balance change recorder.
+	balanceRecorderChanSize = 256
+
+	// balanceRecorderQueueLimit is the maximum number of imported blocks waiting to
+	// be recorded. Blocks imported while the queue is full go into the backlog.
+	balanceRecorderQueueLimit = 1024
+
+	// balanceRecorderReexec is the maximum number of blocks the balance change
+	// recorder re-executes to regenerate a parent state no longer available.
+	balanceRecorderReexec = 128
+)
+
+// balanceAttributor accumulates the balance changes of the accounts touched by a
+// block, tracking the balances they imply to catch any change left unattributed.
+type balanceAttributor struct {
+	pre      *state.StateDB                                  // State before the block to look up initial balances in
+	accounts map[common.Address]*types.AccountBalanceChanges // Attributed changes of the touched accounts
+	expected map[common.Address]*big.Int                     // Balances implied by the changes attributed so far
+}
+
+// account returns the balance changes of an account, starting to track it if
+// it wasn't touched yet.
+func (a *balanceAttributor) account(addr common.Address) *types.AccountBalanceChanges {
+	account, ok := a.accounts[addr]
+	if !ok {
+		account = &types.AccountBalanceChanges{
+			Address: addr,
+			Before:  new(big.Int).Set(a.pre.GetBalance(addr)),
+		}
+		a.accounts[addr] = account
+		a.expected[addr] = new(big.Int).Set(account.Before)
+	}
+	return account
+}
+
+// add attributes a change of an account's balance to the given cause.
+func (a *balanceAttributor) add(addr common.Address, cause string, txIndex int, delta *big.Int) {
+	account := a.account(addr)
+	if delta.Sign() == 0 {
+		return
+	}
+	account.Changes = append(account.Changes, &types.BalanceChange{
+		Cause:   cause,
+		TxIndex: uint64(txIndex),
+		Delta:   new(big.Int).Set(delta),
+	})
+	a.expected[addr].Add(a.expected[addr], delta)
+}
+
+// transfer attributes a value transfer between two accounts to the given cause.
+// Transfers of an account to itself don't change its balance and are omitted.
+func (a *balanceAttributor) transfer(from, to common.Address, cause string, txIndex int, value *big.Int) {
+	if from == to {
+		a.account(from)
+		return
+	}
+	a.add(from, cause, txIndex, new(big.Int).Neg(value))
+	a.add(to, cause, txIndex, value)
+}
+
+// reconcile verifies that the balances of all the tracked accounts match the
+// changes attributed to them. Any difference on the accounts destructed by the
+// transaction is the balance burnt with them, anything else is an error.
+func (a *balanceAttributor) reconcile(statedb *state.StateDB, txIndex int, destructed map[common.Address]bool) error {
+	for addr := range a.accounts {
+		residual := new(big.Int).Sub(statedb.GetBalance(addr), a.expected[addr])
+		if residual.Sign() == 0 {
+			continue
+		}
+		if !destructed[addr] {
+			return fmt.Errorf("unattributed balance change of %x: %v", addr, residual)
+		}
+		a.add(addr, types.BalanceChangeSelfDestruct, txIndex, residual)
+	}
+	return nil
+}
+
+// attributeBalanceChanges processes a block on top of the state of its parent,
+// attributing every change of an account balance to its cause: transaction fees,
+// mining rewards, value transfers of transactions and of their nested calls,
+// selfdestructs and the DAO hard-fork. The attributed changes of every account
+// add up exactly to its balance difference, otherwise an error is returned.
+func attributeBalanceChanges(chain *core.BlockChain, block *types.Block, statedb *state.StateDB) ([]*types.AccountBalanceChanges, error) {
+	var (
+		config = chain.Config()
+		header = block.Header()
+		signer = types.MakeSigner(config, header.Number)
+		a      = &balanceAttributor{
+			pre:      statedb.Copy(),
+			accounts: make(map[common.Address]*types.AccountBalanceChanges),
+			expected: make(map[common.Address]*big.Int),
+		}
+	)
+	// Mutate the block and state according to any hard-fork specs
+	if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(block.Number()) == 0 {
+		for _, addr := range params.DAODrainList() {
+			a.transfer(addr, params.DAORefundContract, types.BalanceChangeDAODrain, 0, statedb.GetBalance(addr))
+		}
+		misc.ApplyDAOHardFork(statedb)
+	}
+	if err := a.reconcile(statedb, 0, nil); err != nil {
+		return nil, err
+	}
+	// Execute the transactions, recording their nested calls for the transfers
+	var (
+		blockCtx = core.NewEVMBlockContext(header, chain, nil)
+		vmenv    = vm.NewEVM(blockCtx, vm.TxContext{}, statedb, config, vm.Config{RecordInnerTxs: true})
+		gp       = new(core.GasPool).AddGas(block.GasLimit())
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			return nil, err
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		vmenv.Reset(core.NewEVMTxContext(msg), statedb)
+
+		result, err := core.ApplyMessage(vmenv, msg, gp)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		statedb.Finalise(config.IsEIP158(block.Number()))
+
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(result.UsedGas), msg.GasPrice())
+		a.add(msg.From(), types.BalanceChangeGasFee, i, new(big.Int).Neg(fee))
+		a.add(blockCtx.Coinbase, types.BalanceChangeMinerFee, i, fee)
+
+		destructed := make(map[common.Address]bool)
+		if !result.Failed() {
+			to := crypto.CreateAddress(msg.From(), msg.Nonce())
+			if msg.To() != nil {
+				to = *msg.To()
+			}
+			a.transfer(msg.From(), to, types.BalanceChangeTransfer, i, msg.Value())
+
+			innerTxs := vmenv.InnerTxs()
+			reverted := innerTxs.Reverted()
+			for j, inner := range innerTxs {
+				if reverted[j] {
+					continue
+				}
+				switch inner.Type {
+				case "CALL", "CREATE", "CREATE2":
+					a.transfer(inner.From, inner.To, types.BalanceChangeInnerTransfer, i, inner.Value)
+				case "SELFDESTRUCT":
+					a.transfer(inner.From, inner.To, types.BalanceChangeSelfDestruct, i, inner.Value)
+					destructed[inner.From] = true
+				}
+			}
+		}
+		if err := a.reconcile(statedb, i, destructed); err != nil {
+			return nil, fmt.Errorf("tx %d [%v]: %v", i, tx.Hash().Hex(), err)
+		}
+	}
+	// Credit the mining rewards, which only the ethash engine pays out
+	if _, ok := chain.Engine().(*ethash.Ethash); ok {
+		reward, uncleRewards := ethash.BlockRewards(config, header, block.Uncles())
+		for i, uncle := range block.Uncles() {
+			a.add(uncle.Coinbase, types.BalanceChangeUncleReward, 0, uncleRewards[i])
+		}
+		a.add(header.Coinbase, types.BalanceChangeBlockReward, 0, reward)
+	}
+	chain.Engine().Finalize(chain, header, statedb, block.Transactions(), block.Uncles())
+	if err := a.reconcile(statedb, 0, nil); err != nil {
+		return nil, err
+	}
+	changes := make([]*types.AccountBalanceChanges, 0, len(a.accounts))
+	for addr, account := range a.accounts {
+		if len(account.Changes) == 0 {
+			continue
+		}
+		account.After = new(big.Int).Set(statedb.GetBalance(addr))
+		changes = append(changes, account)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Address[:], changes[j].Address[:]) < 0
+	})
+	return changes, nil
+}
+
+// balanceRecorder attributes and persists the balance changes of every block
+// imported into the canonical chain. Blocks are queued up as they are imported
+// and recorded in the background, without holding up the import. Blocks which
+// can't be recorded, e.g. because their parent state is gone, or which are
+// imported while the recorder is too far behind, are added to a persistent
+// backlog to be backfilled later.
+type balanceRecorder struct {
+	chain *core.BlockChain
+	db    ethdb.Database
+
+	queue   []*types.Block // Imported blocks waiting to be recorded
+	lagging bool           // Whether blocks were postponed since the queue was last drained
+	lock    sync.Mutex     // Protects the queue and the lagging flag
+
+	backlogLock sync.Mutex // Serializes the updates of the persistent backlog
+
+	wake chan struct{}  // Notification channel for newly queued blocks
+	quit chan struct{}  // Termination channel to stop the recorder
+	wg   sync.WaitGroup // Tracks the event and recording loops
+}
+
+// newBalanceRecorder creates a balance change recorder and starts listening for
+// chain events.
+func newBalanceRecorder(chain *core.BlockChain, db ethdb.Database) *balanceRecorder {
+	r := &balanceRecorder{
+		chain: chain,
+		db:    db,
+		wake:  make(chan struct{}, 1),
+		quit:  make(chan struct{}),
+	}
+	if backlog := rawdb.ReadBalanceChangesBacklog(db); len(backlog) > 0 {
+		log.Warn("Blocks missing balance changes, run geth balances backfill", "count", len(backlog), "first", backlog[0], "last", backlog[len(backlog)-1])
+	}
+	events := make(chan core.ChainEvent, balanceRecorderChanSize)
+	sub := chain.SubscribeChainEvent(events)
+
+	r.wg.Add(2)
+	go r.eventLoop(events, sub)
+	go r.recordLoop()
+	return r
+}
+
+// eventLoop queues up the blocks of the chain events for recording, never
+// blocking the chain event feed on the recording itself.
+func (r *balanceRecorder) eventLoop(events chan core.ChainEvent, sub event.Subscription) {
+	defer r.wg.Done()
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-events:
+			r.enqueue(ev.Block)
+		case <-sub.Err():
+			return
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// enqueue queues up a block for recording and wakes up the recording loop. If the
+// queue is full, the block is added to the backlog instead, warning once until
+// the recorder catches up.
+func (r *balanceRecorder) enqueue(block *types.Block) {
+	r.lock.Lock()
+	if len(r.queue) >= balanceRecorderQueueLimit {
+		lagging := r.lagging
+		r.lagging = true
+		r.lock.Unlock()
+
+		if !lagging {
+			log.Warn("Balance change recorder falling behind, queueing blocks for backfill", "number", block.NumberU64(), "queued", balanceRecorderQueueLimit)
+		}
+		r.postpone(block.NumberU64())
+		return
+	}
+	r.queue = append(r.queue, block)
+	r.lock.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// recordLoop records the queued blocks one by one, moving the ones failing to be
+// recorded into the backlog.
+func (r *balanceRecorder) recordLoop() {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-r.wake:
+		case <-r.quit:
+			return
+		}
+		for {
+			r.lock.Lock()
+			if len(r.queue) == 0 {
+				r.lagging = false
+				r.lock.Unlock()
+				break
+			}
+			block := r.queue[0]
+			r.queue = r.queue[1:]
+			r.lock.Unlock()
+
+			if err := r.record(block); err != nil {
+				log.Warn("Balance changes not recorded, queued for backfill", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+				r.postpone(block.NumberU64())
+			}
+			select {
+			case <-r.quit:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// record attributes the balance changes of a block and persists them, unless
+// they were already recorded. If the state of the parent block is not available
+// any more, it's regenerated by re-executing a limited number of blocks.
+func (r *balanceRecorder) record(block *types.Block) error {
+	number, hash := block.NumberU64(), block.Hash()
+	if number == 0 || rawdb.ReadBalanceChanges(r.db, hash, number) != nil {
+		return nil
+	}
+	parent := r.chain.GetBlock(block.ParentHash(), number-1)
+	if parent == nil {
+		return fmt.Errorf("parent block #%d [%x…] not found", number-1, block.ParentHash().Bytes()[:4])
+	}
+	statedb, err := regenerateStateDB(r.chain, r.db, parent, balanceRecorderReexec)
+	if err != nil {
+		return err
+	}
+	changes, err := attributeBalanceChanges(r.chain, block, statedb)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteBalanceChanges(r.db, hash, number, changes)
+	return nil
+}
+
+// postpone adds a block to the backlog of blocks whose balance changes are to be
+// backfilled.
+func (r *balanceRecorder) postpone(number uint64) {
+	r.backlogLock.Lock()
+	defer r.backlogLock.Unlock()
+
+	backlog := rawdb.ReadBalanceChangesBacklog(r.db)
+	i := sort.Search(len(backlog), func(i int) bool { return backlog[i] >= number })
+	if i < len(backlog) && backlog[i] == number {
+		return
+	}
+	backlog = append(backlog, 0)
+	copy(backlog[i+1:], backlog[i:])
+	backlog[i] = number
+	rawdb.WriteBalanceChangesBacklog(r.db, backlog)
+}
+
+// close stops the recorder, waiting for the block being recorded to finish.
+func (r *balanceRecorder) close() {
+	close(r.quit)
+	r.wg.Wait()
+}
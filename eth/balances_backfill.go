@@ -0,0 +1,73 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// BackfillBalanceChanges re-executes the canonical blocks in the [from, to] range
+// and stores the attributed balance changes of the accounts they touched, for
+// blocks imported before recording was enabled or queued up in the backlog of
+// blocks the recorder failed on. Blocks already recorded are skipped.
+//
+// Progress is checkpointed the same way as for BackfillInnerTxs. Once the whole
+// range is finished, its blocks are removed from the backlog.
+func BackfillBalanceChanges(chain *core.BlockChain, db ethdb.Database, from, to uint64, workers int, reexec uint64, stop <-chan struct{}) error {
+	err := backfill(chain, db, from, to, workers, reexec, stop, &backfillSpec{
+		name:             "balance changes",
+		process:          backfillBlockBalanceChanges,
+		readCheckpoint:   rawdb.ReadBalanceChangesBackfillCheckpoint,
+		writeCheckpoint:  rawdb.WriteBalanceChangesBackfillCheckpoint,
+		deleteCheckpoint: rawdb.DeleteBalanceChangesBackfillCheckpoint,
+	})
+	if err != nil {
+		return err
+	}
+	var backlog []uint64
+	for _, number := range rawdb.ReadBalanceChangesBacklog(db) {
+		if number < from || number > to {
+			backlog = append(backlog, number)
+		}
+	}
+	rawdb.WriteBalanceChangesBacklog(db, backlog)
+	return nil
+}
+
+// backfillBlockBalanceChanges re-executes a block on top of its parent state,
+// attributing and storing its balance changes. Blocks which already have their
+// balance changes recorded are skipped.
+func backfillBlockBalanceChanges(chain *core.BlockChain, db ethdb.Database, block *types.Block, statedb *state.StateDB) error {
+	if rawdb.ReadBalanceChanges(db, block.Hash(), block.NumberU64()) != nil {
+		return nil
+	}
+	changes, err := attributeBalanceChanges(chain, block, statedb)
+	if err != nil {
+		return fmt.Errorf("attributing block %d failed: %v", block.NumberU64(), err)
+	}
+	if root := statedb.IntermediateRoot(chain.Config().IsEIP158(block.Number())); root != block.Root() {
+		return fmt.Errorf("state root mismatch in block %d: have %x, want %x", block.NumberU64(), root, block.Root())
+	}
+	rawdb.WriteBalanceChanges(db, block.Hash(), block.NumberU64(), changes)
+	return nil
+}
@@ -0,0 +1,344 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the balance changes of a block are attributed to their causes, and
+// that they add up to the balance differences.
+func TestAttributeBalanceChanges(t *testing.T) {
+	var (
+		key, _      = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender      = crypto.PubkeyToAddress(key.PublicKey)
+		forwarder   = common.HexToAddress("0xf0")
+		beneficiary = common.HexToAddress("0xbe")
+		destructor  = common.HexToAddress("0xde")
+		miner       = common.HexToAddress("0xaa")
+		db          = rawdb.NewMemoryDatabase()
+	)
+	// The forwarder passes any received value on to the beneficiary, while the
+	// destructor selfdestructs to itself, burning its balance
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLVALUE), byte(vm.PUSH20),
+	}
+	code = append(code, beneficiary.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender:     {Balance: big.NewInt(params.Ether)},
+			forwarder:  {Code: code, Balance: new(big.Int)},
+			destructor: {Code: []byte{byte(vm.ADDRESS), byte(vm.SELFDESTRUCT)}, Balance: big.NewInt(100)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(miner)
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), forwarder, big.NewInt(10), 100000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(sender), destructor, new(big.Int), 100000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	statedb, err := chain.StateAt(genesis.Root())
+	if err != nil {
+		t.Fatalf("failed to retrieve genesis state: %v", err)
+	}
+	changes, err := attributeBalanceChanges(chain, blocks[0], statedb)
+	if err != nil {
+		t.Fatalf("failed to attribute balance changes: %v", err)
+	}
+	// Summarize the changes per account and verify them against the state
+	poststate, _ := chain.StateAt(blocks[0].Root())
+	receipts := chain.GetReceiptsByHash(blocks[0].Hash())
+
+	have := make(map[common.Address][]string)
+	for _, account := range changes {
+		sum := new(big.Int).Set(account.Before)
+		for _, change := range account.Changes {
+			sum.Add(sum, change.Delta)
+			have[account.Address] = append(have[account.Address], fmt.Sprintf("%s/%d:%v", change.Cause, change.TxIndex, change.Delta))
+		}
+		if sum.Cmp(account.After) != 0 || account.After.Cmp(poststate.GetBalance(account.Address)) != 0 {
+			t.Errorf("account %x: balance mismatch: before %v, after %v, changes %v, state %v", account.Address, account.Before, account.After, have[account.Address], poststate.GetBalance(account.Address))
+		}
+	}
+	fee0, fee1 := int64(receipts[0].GasUsed), int64(receipts[1].GasUsed)
+	want := map[common.Address][]string{
+		sender:      {fmt.Sprintf("gasFee/0:%d", -fee0), "transfer/0:-10", fmt.Sprintf("gasFee/1:%d", -fee1)},
+		forwarder:   {"transfer/0:10", "innerTransfer/0:-10"},
+		beneficiary: {"innerTransfer/0:10"},
+		destructor:  {"selfDestruct/1:-100"},
+		miner:       {fmt.Sprintf("minerFee/0:%d", fee0), fmt.Sprintf("minerFee/1:%d", fee1), fmt.Sprintf("blockReward/0:%v", ethash.ConstantinopleBlockReward)},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("balance changes mismatch:\nhave %v\nwant %v", have, want)
+	}
+	// Record the changes and ensure they survive the database roundtrip
+	recorder := &balanceRecorder{chain: chain, db: db}
+	if err := recorder.record(blocks[0]); err != nil {
+		t.Fatalf("failed to record balance changes: %v", err)
+	}
+	if stored := rawdb.ReadBalanceChanges(db, blocks[0].Hash(), 1); !reflect.DeepEqual(stored, changes) {
+		t.Errorf("stored balance changes mismatch:\nhave %v\nwant %v", stored, changes)
+	}
+}
+
+// summarizeBalanceChanges attributes the balance changes of a block, verifies
+// that they add up to the state differences and summarizes them per account.
+func summarizeBalanceChanges(t *testing.T, chain *core.BlockChain, block *types.Block) map[common.Address][]string {
+	t.Helper()
+
+	parent := chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		t.Fatalf("failed to retrieve parent state: %v", err)
+	}
+	changes, err := attributeBalanceChanges(chain, block, statedb)
+	if err != nil {
+		t.Fatalf("failed to attribute balance changes: %v", err)
+	}
+	prestate, _ := chain.StateAt(parent.Root())
+	poststate, _ := chain.StateAt(block.Root())
+
+	summary := make(map[common.Address][]string)
+	for _, account := range changes {
+		sum := new(big.Int).Set(account.Before)
+		for _, change := range account.Changes {
+			sum.Add(sum, change.Delta)
+			summary[account.Address] = append(summary[account.Address], fmt.Sprintf("%s/%d:%v", change.Cause, change.TxIndex, change.Delta))
+		}
+		if account.Before.Cmp(prestate.GetBalance(account.Address)) != 0 || sum.Cmp(account.After) != 0 || account.After.Cmp(poststate.GetBalance(account.Address)) != 0 {
+			t.Errorf("account %x: balance mismatch: before %v, after %v, changes %v, state %v", account.Address, account.Before, account.After, summary[account.Address], poststate.GetBalance(account.Address))
+		}
+	}
+	return summary
+}
+
+// Tests that the balances moved by the DAO hard-fork are attributed to it.
+func TestAttributeDAODrain(t *testing.T) {
+	var (
+		miner  = common.HexToAddress("0xaa")
+		drain  = params.DAODrainList()
+		config = &params.ChainConfig{ChainID: big.NewInt(1), HomesteadBlock: big.NewInt(0), DAOForkBlock: big.NewInt(1), DAOForkSupport: true, Ethash: new(params.EthashConfig)}
+		db     = rawdb.NewMemoryDatabase()
+	)
+	gspec := &core.Genesis{
+		Config: config,
+		Alloc: core.GenesisAlloc{
+			drain[0]:                 {Balance: big.NewInt(100)},
+			drain[1]:                 {Balance: big.NewInt(200)},
+			params.DAORefundContract: {Balance: big.NewInt(1)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 1, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(miner)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	have := summarizeBalanceChanges(t, chain, blocks[0])
+	want := map[common.Address][]string{
+		drain[0]:                 {"daoDrain/0:-100"},
+		drain[1]:                 {"daoDrain/0:-200"},
+		params.DAORefundContract: {"daoDrain/0:100", "daoDrain/0:200"},
+		miner:                    {fmt.Sprintf("blockReward/0:%v", ethash.FrontierBlockReward)},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("balance changes mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
+
+// Tests the attribution of the balance changes not caused by plain transfers:
+// uncle rewards, failed transactions only paying fees and contract creations
+// with an endowment.
+func TestAttributeIrregularBalanceChanges(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		reverter = common.HexToAddress("0xdead")
+		miner    = common.HexToAddress("0xaa")
+		uncler   = common.HexToAddress("0xbb")
+		db       = rawdb.NewMemoryDatabase()
+	)
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender:   {Balance: big.NewInt(params.Ether)},
+			reverter: {Code: []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)}, Balance: new(big.Int)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 2, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(miner)
+		if i == 0 {
+			return
+		}
+		uncle := b.PrevBlock(0).Header()
+		uncle.Extra, uncle.Coinbase = []byte("uncle"), uncler
+		b.AddUncle(uncle)
+
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), reverter, big.NewInt(5), 100000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewContractCreation(b.TxNonce(sender), big.NewInt(7), 100000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	block := blocks[1]
+	have := summarizeBalanceChanges(t, chain, block)
+
+	var (
+		receipts        = chain.GetReceiptsByHash(block.Hash())
+		fee0, fee1      = int64(receipts[0].GasUsed), int64(receipts[1].GasUsed)
+		reward, rewards = ethash.BlockRewards(gspec.Config, block.Header(), block.Uncles())
+		created         = crypto.CreateAddress(sender, 1)
+	)
+	want := map[common.Address][]string{
+		sender:  {fmt.Sprintf("gasFee/0:%d", -fee0), fmt.Sprintf("gasFee/1:%d", -fee1), "transfer/1:-7"},
+		created: {"transfer/1:7"},
+		miner:   {fmt.Sprintf("minerFee/0:%d", fee0), fmt.Sprintf("minerFee/1:%d", fee1), fmt.Sprintf("blockReward/0:%v", reward)},
+		uncler:  {fmt.Sprintf("uncleReward/0:%v", rewards[0])},
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("balance changes mismatch:\nhave %v\nwant %v", have, want)
+	}
+}
+
+// Tests that blocks imported while the recorder runs get recorded, and that the
+// ones failing to be recorded are queued up for a backfill.
+func TestBalanceRecorderBacklog(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		db     = rawdb.NewMemoryDatabase()
+	)
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 4, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	// Import half the chain without recording, and the rest while recording
+	if _, err := chain.InsertChain(blocks[:2]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	recorder := newBalanceRecorder(chain, db)
+	if _, err := chain.InsertChain(blocks[2:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for i := 0; i < 100 && rawdb.ReadBalanceChanges(db, blocks[3].Hash(), 4) == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	recorder.close()
+
+	for i, block := range blocks {
+		if recorded := rawdb.ReadBalanceChanges(db, block.Hash(), block.NumberU64()) != nil; recorded != (i >= 2) {
+			t.Errorf("block %d: recorded mismatch: have %v, want %v", block.NumberU64(), recorded, i >= 2)
+		}
+	}
+	// Blocks without their parent can't be recorded, queue them up in order
+	orphan := types.NewBlockWithHeader(&types.Header{ParentHash: common.Hash{0x01}, Number: big.NewInt(9)})
+	if err := recorder.record(orphan); err == nil {
+		t.Fatalf("recorded block without parent")
+	}
+	recorder.postpone(9)
+	recorder.postpone(1)
+	recorder.postpone(9)
+	if backlog := rawdb.ReadBalanceChangesBacklog(db); !reflect.DeepEqual(backlog, []uint64{1, 9}) {
+		t.Fatalf("backlog mismatch: have %v, want %v", backlog, []uint64{1, 9})
+	}
+	// Backfill the blocks left out, which drops them from the backlog
+	if err := BackfillBalanceChanges(chain, db, 1, 2, 2, 0, nil); err != nil {
+		t.Fatalf("failed to backfill: %v", err)
+	}
+	for _, block := range blocks {
+		if rawdb.ReadBalanceChanges(db, block.Hash(), block.NumberU64()) == nil {
+			t.Errorf("block %d: balance changes missing after backfill", block.NumberU64())
+		}
+	}
+	if backlog := rawdb.ReadBalanceChangesBacklog(db); !reflect.DeepEqual(backlog, []uint64{9}) {
+		t.Errorf("backlog mismatch after backfill: have %v, want %v", backlog, []uint64{9})
+	}
+}
+
+// Tests that blocks imported while the recorder is too far behind are added to
+// the backlog instead of growing the queue without bound.
+func TestBalanceRecorderQueueLimit(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	recorder := &balanceRecorder{db: db, wake: make(chan struct{}, 1)}
+
+	block := func(number int64) *types.Block {
+		return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(number)})
+	}
+	for i := 0; i < balanceRecorderQueueLimit; i++ {
+		recorder.enqueue(block(int64(i + 1)))
+	}
+	if backlog := rawdb.ReadBalanceChangesBacklog(db); len(backlog) != 0 {
+		t.Fatalf("backlog mismatch before overflow: have %v, want none", backlog)
+	}
+	recorder.enqueue(block(balanceRecorderQueueLimit + 2))
+	recorder.enqueue(block(balanceRecorderQueueLimit + 1))
+
+	if len(recorder.queue) != balanceRecorderQueueLimit {
+		t.Errorf("queue length mismatch: have %d, want %d", len(recorder.queue), balanceRecorderQueueLimit)
+	}
+	if !recorder.lagging {
+		t.Errorf("recorder not flagged as lagging")
+	}
+	want := []uint64{balanceRecorderQueueLimit + 1, balanceRecorderQueueLimit + 2}
+	if backlog := rawdb.ReadBalanceChangesBacklog(db); !reflect.DeepEqual(backlog, want) {
+		t.Errorf("backlog mismatch: have %v, want %v", backlog, want)
+	}
+}
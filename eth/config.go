@@ -167,6 +167,18 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables recording of inner transactions while importing blocks
+	EnableInnerTxs bool
+
+	// Enables storing the revert reasons of the failed transactions of imported blocks
+	EnableRevertReasons bool
+
+	// Enables recording of the attributed balance changes of imported blocks
+	EnableBalanceChanges bool
+
+	// Enables indexing of the token transfers of the canonical chain by holder
+	EnableTokenTransfers bool
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
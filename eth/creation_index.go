@@ -0,0 +1,128 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// creationIndexSectionSize is the number of blocks in a single section of the
+	// contract creation index. Blocks in the last unfinished section are searched
+	// without the index, so it's kept small.
+	creationIndexSectionSize = 256
+
+	// creationIndexConfirms is the number of confirmation blocks before a contract
+	// creation index section is considered probably final and gets indexed.
+	creationIndexConfirms = 64
+
+	// creationIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	creationIndexThrottling = 100 * time.Millisecond
+)
+
+// ContractCreationIndexer implements a core.ChainIndexer, building up an index
+// from the address of every contract deployed on the canonical chain to its
+// creation, be it by a transaction or by an inner CREATE or CREATE2.
+//
+// Entries of reorged sections are overwritten or left behind when the section
+// is reprocessed. They record the hash of the block they were indexed from, so
+// readers can filter out stale entries.
+type ContractCreationIndexer struct {
+	db     ethdb.Database      // database instance to write index data into
+	config *params.ChainConfig // chain configuration to recover transaction senders with
+	batch  ethdb.Batch         // batch accumulating the index entries of the current section
+}
+
+// NewContractCreationIndexer returns a chain indexer that maintains the creation
+// index of the contracts deployed on the canonical chain. The creations made by
+// nested calls are taken from the recorded inner transactions.
+func NewContractCreationIndexer(db ethdb.Database, config *params.ChainConfig, size, confirms uint64) *core.ChainIndexer {
+	backend := &ContractCreationIndexer{
+		db:     db,
+		config: config,
+	}
+	table := rawdb.NewTable(db, string(rawdb.ContractCreationIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, confirms, creationIndexThrottling, "creations")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new contract creation
+// index section.
+func (b *ContractCreationIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.batch = b.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the contracts created in a
+// block into the index. Blocks imported without recording their inner
+// transactions fail the section, leaving it unindexed until they are backfilled.
+func (b *ContractCreationIndexer) Process(ctx context.Context, header *types.Header) error {
+	number, hash := header.Number.Uint64(), header.Hash()
+	if number == 0 {
+		return nil // Genesis allocations are not created by anyone
+	}
+	block := rawdb.ReadBlock(b.db, hash, number)
+	if block == nil {
+		return fmt.Errorf("block #%d [%x…] not found", number, hash[:4])
+	}
+	if len(block.Transactions()) == 0 {
+		return nil
+	}
+	receipts := rawdb.ReadRawReceipts(b.db, hash, number)
+	if receipts == nil {
+		return fmt.Errorf("receipts of block #%d [%x…] not found", number, hash[:4])
+	}
+	innerTxs := rawdb.ReadInnerTxs(b.db, hash, number)
+	if innerTxs == nil {
+		return fmt.Errorf("block #%d [%x…]: %w", number, hash[:4], errInnerTxsNotRecorded)
+	}
+	creations, err := types.ContractCreations(types.MakeSigner(b.config, header.Number), block, receipts, innerTxs)
+	if err != nil {
+		return fmt.Errorf("block #%d [%x…]: %v", number, hash[:4], err)
+	}
+	for _, creation := range creations {
+		rawdb.WriteContractCreation(b.batch, creation)
+	}
+	if b.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := b.batch.Write(); err != nil {
+			return err
+		}
+		b.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the remaining index
+// entries of the section into the database.
+func (b *ContractCreationIndexer) Commit() error {
+	return b.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *ContractCreationIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that contracts deployed by transactions and by other contracts are both
+// indexed, while the ones created by reverted frames are not.
+func TestContractCreationIndexer(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		factory  = common.HexToAddress("0xfa")
+		reverter = common.HexToAddress("0xfb")
+		caller   = common.HexToAddress("0xca")
+		db       = rawdb.NewMemoryDatabase()
+	)
+	// The factory creates an empty contract, the reverter does the same but then
+	// reverts, and the caller calls into the reverter, succeeding regardless
+	create := []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CREATE)}
+	revert := append(append([]byte{}, create...), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT))
+	call := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH20),
+	}
+	call = append(call, reverter.Bytes()...)
+	call = append(call, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender:   {Balance: big.NewInt(params.Ether)},
+			factory:  {Code: append(create, byte(vm.STOP)), Balance: new(big.Int)},
+			reverter: {Code: revert, Balance: new(big.Int)},
+			caller:   {Code: call, Balance: new(big.Int)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, b *core.BlockGen) {
+		var tx *types.Transaction
+		switch i {
+		case 0:
+			tx = types.NewContractCreation(b.TxNonce(sender), new(big.Int), 100000, big.NewInt(1), nil)
+		case 1:
+			tx = types.NewTransaction(b.TxNonce(sender), factory, new(big.Int), 100000, big.NewInt(1), nil)
+		case 2:
+			tx = types.NewTransaction(b.TxNonce(sender), caller, new(big.Int), 100000, big.NewInt(1), nil)
+		}
+		tx, _ = types.SignTx(tx, signer, key)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{RecordInnerTxs: true}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	indexer := &ContractCreationIndexer{db: db, config: gspec.Config}
+
+	// Blocks imported before recording was enabled stall the index until backfilled
+	recorded := rawdb.ReadInnerTxs(db, blocks[1].Hash(), blocks[1].NumberU64())
+	rawdb.DeleteInnerTxs(db, blocks[1].Hash(), blocks[1].NumberU64())
+
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	if err := indexer.Process(context.Background(), blocks[1].Header()); !errors.Is(err, errInnerTxsNotRecorded) {
+		t.Fatalf("unrecorded block error mismatch: have %v, want %v", err, errInnerTxsNotRecorded)
+	}
+	rawdb.WriteInnerTxs(db, blocks[1].Hash(), blocks[1].NumberU64(), recorded)
+
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	for _, block := range append([]*types.Block{genesis}, blocks...) {
+		if err := indexer.Process(context.Background(), block.Header()); err != nil {
+			t.Fatalf("failed to index block %d: %v", block.NumberU64(), err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit index: %v", err)
+	}
+	tests := []struct {
+		address common.Address
+		creator common.Address
+		typ     string
+		block   *types.Block
+	}{
+		{crypto.CreateAddress(sender, 0), sender, types.CreationTransaction, blocks[0]},
+		{crypto.CreateAddress(factory, 0), factory, types.CreationCreate, blocks[1]},
+		{crypto.CreateAddress(reverter, 0), common.Address{}, "", nil},
+	}
+	for i, tt := range tests {
+		creation := rawdb.ReadContractCreation(db, tt.address)
+		if tt.block == nil {
+			if creation != nil {
+				t.Errorf("test %d: unexpected creation %+v", i, creation)
+			}
+			continue
+		}
+		if creation == nil {
+			t.Errorf("test %d: creation of %x not indexed", i, tt.address)
+			continue
+		}
+		if creation.Creator != tt.creator || creation.Type != tt.typ {
+			t.Errorf("test %d: creator mismatch: have %x (%s), want %x (%s)", i, creation.Creator, creation.Type, tt.creator, tt.typ)
+		}
+		if creation.BlockHash != tt.block.Hash() || creation.BlockNumber != tt.block.NumberU64() || creation.TxHash != tt.block.Transactions()[0].Hash() {
+			t.Errorf("test %d: position mismatch: have %+v", i, creation)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// feeCacheLimit is the number of blocks whose fee statistics are cached.
+const feeCacheLimit = 1024
+
+// BlockFees contains the gas prices paid in a block along with its gas usage.
+type BlockFees struct {
+	Number   uint64
+	Hash     common.Hash
+	GasUsed  uint64
+	GasLimit uint64
+	Prices   []*big.Int // Gas prices of the transactions not sent by the miner, ascending
+}
+
+// GasUsedRatio returns the portion of the block's gas limit used by its
+// transactions.
+func (f *BlockFees) GasUsedRatio() float64 {
+	if f.GasLimit == 0 {
+		return 0
+	}
+	return float64(f.GasUsed) / float64(f.GasLimit)
+}
+
+// Percentile returns the gas price below which the given percentage of the
+// sampled transactions were priced, or nil if the block has no samples.
+func (f *BlockFees) Percentile(percent float64) *big.Int {
+	if len(f.Prices) == 0 {
+		return nil
+	}
+	return f.Prices[int(float64(len(f.Prices)-1)*percent/100)]
+}
+
+// Min returns the lowest sampled gas price, or nil if the block has no samples.
+func (f *BlockFees) Min() *big.Int {
+	return f.Percentile(0)
+}
+
+// Median returns the median sampled gas price, or nil if the block has no samples.
+func (f *BlockFees) Median() *big.Int {
+	return f.Percentile(50)
+}
+
+// Max returns the highest sampled gas price, or nil if the block has no samples.
+func (f *BlockFees) Max() *big.Int {
+	return f.Percentile(100)
+}
+
+// BlockFees returns the fee statistics of the canonical block with the given
+// number. Results are cached by block hash, so they remain valid across reorgs.
+func (gpo *Oracle) BlockFees(ctx context.Context, number uint64) (*BlockFees, error) {
+	header, err := gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	if fees, ok := gpo.feeCache.Get(header.Hash()); ok {
+		return fees.(*BlockFees), nil
+	}
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	signer := types.MakeSigner(gpo.backend.ChainConfig(), block.Number())
+	fees := &BlockFees{
+		Number:   number,
+		Hash:     block.Hash(),
+		GasUsed:  block.GasUsed(),
+		GasLimit: block.GasLimit(),
+		Prices:   blockPrices(signer, block, len(block.Transactions())),
+	}
+	gpo.feeCache.Add(fees.Hash, fees)
+	return fees, nil
+}
+
+// FeeStatistics returns the fee statistics of the canonical blocks in the range
+// [first, last], ordered by block number.
+func (gpo *Oracle) FeeStatistics(ctx context.Context, first, last uint64) ([]*BlockFees, error) {
+	if first > last {
+		return nil, fmt.Errorf("invalid block range #%d-#%d", first, last)
+	}
+	stats := make([]*BlockFees, 0, last-first+1)
+	for number := first; number <= last; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fees, err := gpo.BlockFees(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, fees)
+	}
+	return stats, nil
+}
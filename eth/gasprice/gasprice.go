@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
+	lru "github.com/hashicorp/golang-lru"
 )
 
 const sampleNumber = 3 // Number of transactions sampled in a block
@@ -56,6 +57,7 @@ type Oracle struct {
 	maxPrice  *big.Int
 	cacheLock sync.RWMutex
 	fetchLock sync.Mutex
+	feeCache  *lru.Cache // Fee statistics of recently requested blocks, keyed by hash
 
 	checkBlocks int
 	percentile  int
@@ -83,8 +85,10 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		maxPrice = DefaultMaxPrice
 		log.Warn("Sanitizing invalid gasprice oracle price cap", "provided", params.MaxPrice, "updated", maxPrice)
 	}
+	feeCache, _ := lru.New(feeCacheLimit)
 	return &Oracle{
 		backend:     backend,
+		feeCache:    feeCache,
 		lastPrice:   params.Default,
 		maxPrice:    maxPrice,
 		checkBlocks: blocks,
@@ -192,6 +196,15 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 		}
 		return
 	}
+	select {
+	case result <- getBlockPricesResult{blockPrices(signer, block, limit), nil}:
+	case <-quit:
+	}
+}
+
+// blockPrices returns the lowest limit gas prices of the transactions in a block
+// in ascending order, skipping the ones sent by the miner itself.
+func blockPrices(signer types.Signer, block *types.Block, limit int) []*big.Int {
 	blockTxs := block.Transactions()
 	txs := make([]*types.Transaction, len(blockTxs))
 	copy(txs, blockTxs)
@@ -207,10 +220,7 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 			}
 		}
 	}
-	select {
-	case result <- getBlockPricesResult{prices, nil}:
-	case <-quit:
-	}
+	return prices
 }
 
 type bigIntArray []*big.Int
@@ -21,6 +21,7 @@ import (
 	"math"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -116,3 +117,80 @@ func TestSuggestPrice(t *testing.T) {
 		t.Fatalf("Gas price mismatch, want %d, got %d", expect, got)
 	}
 }
+
+func TestFeeStatistics(t *testing.T) {
+	backend := newTestBackend(t)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+
+	// Every block contains a single transaction priced at its number in gwei
+	stats, err := oracle.FeeStatistics(context.Background(), 30, 32)
+	if err != nil {
+		t.Fatalf("Failed to retrieve fee statistics: %v", err)
+	}
+	if len(stats) != 3 {
+		t.Fatalf("Block count mismatch, want 3, got %d", len(stats))
+	}
+	for i, fees := range stats {
+		number := uint64(30 + i)
+		block := backend.GetBlockByNumber(number)
+		if fees.Number != number || fees.Hash != block.Hash() {
+			t.Errorf("Block %d: position mismatch, got #%d [%x]", number, fees.Number, fees.Hash)
+		}
+		if want := float64(21000) / float64(block.GasLimit()); fees.GasUsedRatio() != want {
+			t.Errorf("Block %d: gas used ratio mismatch, want %v, got %v", number, want, fees.GasUsedRatio())
+		}
+		expect := big.NewInt(params.GWei * int64(number))
+		for _, price := range []*big.Int{fees.Min(), fees.Median(), fees.Max(), fees.Percentile(25)} {
+			if price == nil || price.Cmp(expect) != 0 {
+				t.Errorf("Block %d: price mismatch, want %d, got %d", number, expect, price)
+			}
+		}
+		if cached, _ := oracle.BlockFees(context.Background(), number); cached != fees {
+			t.Errorf("Block %d: statistics not cached", number)
+		}
+	}
+	// The genesis block has no transactions to sample
+	fees, err := oracle.BlockFees(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Failed to retrieve genesis fee statistics: %v", err)
+	}
+	if fees.Median() != nil || fees.GasUsedRatio() != 0 {
+		t.Errorf("Genesis statistics mismatch, got median %v, ratio %v", fees.Median(), fees.GasUsedRatio())
+	}
+}
+
+func TestSuggestTiers(t *testing.T) {
+	backend := newTestBackend(t)
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, Default: big.NewInt(params.GWei)})
+	gasLimit := backend.CurrentHeader().GasLimit
+
+	type tier struct {
+		price  int64 // in gwei
+		blocks uint64
+	}
+	check := func(name string, pending types.Transactions, want [4]tier) {
+		tiers, err := oracle.SuggestTiers(context.Background(), pending)
+		if err != nil {
+			t.Fatalf("%s: failed to suggest price tiers: %v", name, err)
+		}
+		for i, have := range []*PriceTier{tiers.Slow, tiers.Standard, tiers.Fast, tiers.Instant} {
+			if have.Price.Cmp(big.NewInt(want[i].price*params.GWei)) != 0 || have.Blocks != want[i].blocks {
+				t.Errorf("%s: tier %d mismatch: have %v wei in %d blocks, want %d gwei in %d blocks", name, i, have.Price, have.Blocks, want[i].price, want[i].blocks)
+			}
+			// Test chain blocks are 10 seconds apart
+			if have.Wait != time.Duration(have.Blocks)*10*time.Second {
+				t.Errorf("%s: tier %d wait mismatch: have %v, want %d blocks", name, i, have.Wait, have.Blocks)
+			}
+		}
+	}
+	// The minimum prices of the sampled blocks are 30G, 31G and 32G
+	check("empty pool", nil, [4]tier{{30, 3}, {31, 2}, {31, 2}, {32, 1}})
+
+	// Two blocks worth of backlog at 100G and another block at 50G
+	pending := types.Transactions{
+		types.NewTransaction(0, common.Address{}, nil, gasLimit, big.NewInt(100*params.GWei), nil),
+		types.NewTransaction(1, common.Address{}, nil, gasLimit, big.NewInt(50*params.GWei), nil),
+		types.NewTransaction(2, common.Address{}, nil, gasLimit, big.NewInt(100*params.GWei), nil),
+	}
+	check("congested pool", pending, [4]tier{{30, 4}, {50, 3}, {50, 3}, {100, 1}})
+}
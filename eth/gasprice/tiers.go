@@ -0,0 +1,173 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Block sample percentiles of the non-standard price tiers. The standard tier
+// uses the percentile configured for the oracle.
+const (
+	slowPercentile    = 25
+	fastPercentile    = 90
+	instantPercentile = 100
+)
+
+// PriceTier is a gas price recommendation along with the expected delay until a
+// transaction paying it gets included.
+type PriceTier struct {
+	Price  *big.Int
+	Blocks uint64        // Expected number of blocks until inclusion
+	Wait   time.Duration // Expected time until inclusion
+}
+
+// PriceTiers are gas price recommendations for increasing inclusion speeds.
+type PriceTiers struct {
+	Slow     *PriceTier
+	Standard *PriceTier
+	Fast     *PriceTier
+	Instant  *PriceTier
+}
+
+// SuggestTiers returns gas price recommendations for four inclusion speeds. The
+// prices are based on the minimum prices accepted by recent blocks, raised where
+// needed to outbid the backlog of the given executable pool transactions within
+// the tier's expected delay.
+//
+// A block sample percentile p is expected to be accepted by one in every 100/p
+// blocks, while a price is expected to wait for the blocks filled by the pending
+// transactions paying more. The expected delay of a tier is the larger of the two.
+func (gpo *Oracle) SuggestTiers(ctx context.Context, pending types.Transactions) (*PriceTiers, error) {
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	// Collect the lowest price accepted by each of the recent non-empty blocks
+	var (
+		number = head.Number.Uint64()
+		oldest = number
+		mins   []*big.Int
+	)
+	for n := number; n > 0 && number-n < uint64(gpo.checkBlocks); n-- {
+		fees, err := gpo.BlockFees(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		if min := fees.Min(); min != nil {
+			mins = append(mins, min)
+		}
+		oldest = n
+	}
+	sort.Sort(bigIntArray(mins))
+
+	// Measure the average block time over the sampled blocks
+	var blockTime time.Duration
+	if oldest < number {
+		header, err := gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(oldest))
+		if err != nil {
+			return nil, err
+		}
+		if header != nil && head.Time > header.Time {
+			blockTime = time.Duration(head.Time-header.Time) * time.Second / time.Duration(number-oldest)
+		}
+	}
+	// Sort the pending transactions by decreasing price to measure the backlog
+	txs := make(types.Transactions, len(pending))
+	copy(txs, pending)
+	sort.Sort(sort.Reverse(transactionsByGasPrice(txs)))
+
+	gasLimit := head.GasLimit
+	if gasLimit == 0 {
+		gasLimit = 1
+	}
+	// backlog returns the gas used by the pending transactions paying more than
+	// the given price
+	backlog := func(price *big.Int) uint64 {
+		var gas uint64
+		for _, tx := range txs {
+			if tx.GasPrice().Cmp(price) <= 0 {
+				break
+			}
+			gas += tx.Gas()
+		}
+		return gas
+	}
+	// outbid returns the price needed to be included within the given number of
+	// blocks considering the pending transactions only, nil if they fit
+	outbid := func(blocks uint64) *big.Int {
+		var gas uint64
+		for _, tx := range txs {
+			if gas += tx.Gas(); gas > blocks*gasLimit {
+				return tx.GasPrice()
+			}
+		}
+		return nil
+	}
+	gpo.cacheLock.RLock()
+	lastPrice := gpo.lastPrice
+	gpo.cacheLock.RUnlock()
+	if lastPrice == nil {
+		lastPrice = new(big.Int)
+	}
+	tier := func(percentile int, floor *big.Int) *PriceTier {
+		// Start from the block samples, falling back to the last suggestion
+		price, blocks := lastPrice, uint64(1)
+		if len(mins) > 0 {
+			price = mins[(len(mins)-1)*percentile/100]
+		}
+		if percentile > 0 {
+			blocks = uint64((100 + percentile - 1) / percentile)
+		}
+		if bid := outbid(blocks); bid != nil && bid.Cmp(price) > 0 {
+			price = bid
+		}
+		if floor != nil && floor.Cmp(price) > 0 {
+			price = floor
+		}
+		if price.Cmp(gpo.maxPrice) > 0 {
+			price = gpo.maxPrice
+		}
+		price = new(big.Int).Set(price)
+
+		// Estimate the delay of the final price from both sources
+		if len(mins) > 0 {
+			accepting := sort.Search(len(mins), func(i int) bool { return mins[i].Cmp(price) > 0 })
+			if accepting > 0 {
+				blocks = uint64((len(mins) + accepting - 1) / accepting)
+			} else {
+				blocks = uint64(len(mins))
+			}
+		}
+		if queued := backlog(price)/gasLimit + 1; queued > blocks {
+			blocks = queued
+		}
+		return &PriceTier{Price: price, Blocks: blocks, Wait: time.Duration(blocks) * blockTime}
+	}
+	tiers := new(PriceTiers)
+	tiers.Slow = tier(slowPercentile, nil)
+	tiers.Standard = tier(gpo.percentile, tiers.Slow.Price)
+	tiers.Fast = tier(fastPercentile, tiers.Standard.Price)
+	tiers.Instant = tier(instantPercentile, tiers.Fast.Price)
+	return tiers, nil
+}
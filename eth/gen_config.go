@@ -49,6 +49,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		EnableInnerTxs          bool
+		EnableRevertReasons     bool
+		EnableBalanceChanges    bool
+		EnableTokenTransfers    bool
 		DocRoot                 string `toml:"-"`
 		EWASMInterpreter        string
 		EVMInterpreter          string
@@ -90,6 +94,10 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.EnableInnerTxs = c.EnableInnerTxs
+	enc.EnableRevertReasons = c.EnableRevertReasons
+	enc.EnableBalanceChanges = c.EnableBalanceChanges
+	enc.EnableTokenTransfers = c.EnableTokenTransfers
 	enc.DocRoot = c.DocRoot
 	enc.EWASMInterpreter = c.EWASMInterpreter
 	enc.EVMInterpreter = c.EVMInterpreter
@@ -135,6 +143,10 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		EnableInnerTxs          *bool
+		EnableRevertReasons     *bool
+		EnableBalanceChanges    *bool
+		EnableTokenTransfers    *bool
 		DocRoot                 *string `toml:"-"`
 		EWASMInterpreter        *string
 		EVMInterpreter          *string
@@ -243,6 +255,18 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.EnableInnerTxs != nil {
+		c.EnableInnerTxs = *dec.EnableInnerTxs
+	}
+	if dec.EnableRevertReasons != nil {
+		c.EnableRevertReasons = *dec.EnableRevertReasons
+	}
+	if dec.EnableBalanceChanges != nil {
+		c.EnableBalanceChanges = *dec.EnableBalanceChanges
+	}
+	if dec.EnableTokenTransfers != nil {
+		c.EnableTokenTransfers = *dec.EnableTokenTransfers
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
@@ -0,0 +1,67 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// BackfillInnerTxs re-executes the canonical blocks in the [from, to] range and
+// stores the inner transactions recorded while doing so, for nodes which enabled
+// recording only after syncing. Blocks are re-executed concurrently by the given
+// number of workers on top of the sequentially regenerated chain state. The
+// receipts root of every re-executed block is verified against its header.
+//
+// Progress is checkpointed into the database, so a backfill interrupted by closing
+// the stop channel or by a failure is resumed by the next invocation covering the
+// checkpoint. The state of the block preceding the range needs to be available,
+// or at most reexec blocks away from available state.
+func BackfillInnerTxs(chain *core.BlockChain, db ethdb.Database, from, to uint64, workers int, reexec uint64, stop <-chan struct{}) error {
+	return backfill(chain, db, from, to, workers, reexec, stop, &backfillSpec{
+		name:             "inner transactions",
+		process:          backfillBlockInnerTxs,
+		readCheckpoint:   rawdb.ReadInnerTxBackfillCheckpoint,
+		writeCheckpoint:  rawdb.WriteInnerTxBackfillCheckpoint,
+		deleteCheckpoint: rawdb.DeleteInnerTxBackfillCheckpoint,
+	})
+}
+
+// backfillBlockInnerTxs re-executes a block on top of its parent state, recording
+// and storing its inner transactions. Blocks which already have their inner
+// transactions recorded are skipped.
+func backfillBlockInnerTxs(chain *core.BlockChain, db ethdb.Database, block *types.Block, statedb *state.StateDB) error {
+	if rawdb.HasInnerTxs(db, block.Hash(), block.NumberU64()) {
+		return nil
+	}
+	receipts, _, innerTxs, _, err := chain.Processor().Process(block, statedb, vm.Config{RecordInnerTxs: true})
+	if err != nil {
+		return fmt.Errorf("processing block %d failed: %v", block.NumberU64(), err)
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != block.ReceiptHash() {
+		return fmt.Errorf("receipts root mismatch in block %d: have %x, want %x", block.NumberU64(), root, block.ReceiptHash())
+	}
+	rawdb.WriteInnerTxs(db, block.Hash(), block.NumberU64(), innerTxs)
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the inner transactions of blocks imported without recording can be
+// backfilled, resuming from a previously persisted checkpoint.
+func TestBackfillInnerTxs(t *testing.T) {
+	var (
+		key, _      = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender      = crypto.PubkeyToAddress(key.PublicKey)
+		forwarder   = common.HexToAddress("0xf0")
+		beneficiary = common.HexToAddress("0xbe")
+		db          = rawdb.NewMemoryDatabase()
+	)
+	// The forwarder passes any received value on to the beneficiary
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLVALUE), byte(vm.PUSH20),
+	}
+	code = append(code, beneficiary.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender:    {Balance: big.NewInt(params.Ether)},
+			forwarder: {Code: code, Balance: new(big.Int)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 8, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), forwarder, big.NewInt(1), 100000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	check := func(number uint64, recorded bool) {
+		block := blocks[number-1]
+		innerTxs := rawdb.ReadInnerTxs(db, block.Hash(), number)
+		if !recorded {
+			if innerTxs != nil {
+				t.Errorf("block %d: unexpected inner transactions %v", number, innerTxs)
+			}
+			return
+		}
+		if len(innerTxs) != 1 || len(innerTxs[0]) != 1 {
+			t.Fatalf("block %d: inner transaction count mismatch: have %v", number, innerTxs)
+		}
+		if tx := innerTxs[0][0]; tx.From != forwarder || tx.To != beneficiary || tx.Value.Int64() != 1 {
+			t.Errorf("block %d: inner transaction mismatch: have %x->%x %v", number, tx.From, tx.To, tx.Value)
+		}
+	}
+	// Pretend an earlier backfill was interrupted at block 4 and resume it
+	rawdb.WriteInnerTxBackfillCheckpoint(db, 4)
+	if err := BackfillInnerTxs(chain, db, 1, 8, 2, 0, nil); err != nil {
+		t.Fatalf("failed to resume backfill: %v", err)
+	}
+	for number := uint64(1); number <= 8; number++ {
+		check(number, number >= 4)
+	}
+	if checkpoint := rawdb.ReadInnerTxBackfillCheckpoint(db); checkpoint != nil {
+		t.Fatalf("checkpoint retained after finished backfill: %d", *checkpoint)
+	}
+	// Run a fresh backfill over the entire range
+	if err := BackfillInnerTxs(chain, db, 1, 8, 3, 0, nil); err != nil {
+		t.Fatalf("failed to backfill: %v", err)
+	}
+	for number := uint64(1); number <= 8; number++ {
+		check(number, true)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// innerTxIndexSectionSize is the number of blocks in a single section of the
+	// inner transaction address index. Blocks in the last unfinished section are
+	// searched without the index, so it's kept small.
+	innerTxIndexSectionSize = 256
+
+	// innerTxIndexConfirms is the number of confirmation blocks before an inner
+	// transaction index section is considered probably final and gets indexed.
+	innerTxIndexConfirms = 64
+
+	// innerTxIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	innerTxIndexThrottling = 100 * time.Millisecond
+)
+
+// errInnerTxsNotRecorded is returned by the indexers built from the inner
+// transactions if a block was imported without recording them. The section is
+// retried on the next chain head update, so it gets indexed once the inner
+// transactions are backfilled.
+var errInnerTxsNotRecorded = errors.New("inner transactions not recorded")
+
+// InnerTxIndexer implements a core.ChainIndexer, building up an index from the
+// accounts sending or receiving internal value transfers to their positions in
+// the canonical chain.
+//
+// Entries of reorged sections are not deleted, rather overwritten or left behind
+// when the section is reprocessed. They record the hash of the block they were
+// indexed from, so readers can filter out stale entries.
+type InnerTxIndexer struct {
+	db    ethdb.Database // database instance to write index data into
+	batch ethdb.Batch    // batch accumulating the index entries of the current section
+}
+
+// NewInnerTxIndexer returns a chain indexer that maintains the address index of
+// the inner transactions recorded for the canonical chain.
+func NewInnerTxIndexer(db ethdb.Database, size, confirms uint64) *core.ChainIndexer {
+	backend := &InnerTxIndexer{
+		db: db,
+	}
+	table := rawdb.NewTable(db, string(rawdb.InnerTxIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, size, confirms, innerTxIndexThrottling, "innertxs")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new inner transaction
+// index section.
+func (b *InnerTxIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.batch = b.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the value transferring
+// inner transactions of a block into the index. Blocks imported without recording
+// their inner transactions fail the section, leaving it unindexed until they are
+// backfilled.
+func (b *InnerTxIndexer) Process(ctx context.Context, header *types.Header) error {
+	number, hash := header.Number.Uint64(), header.Hash()
+	if number == 0 {
+		return nil // Genesis is never processed, so it has no inner transactions
+	}
+	innerTxs := rawdb.ReadInnerTxs(b.db, hash, number)
+	if innerTxs == nil {
+		return fmt.Errorf("block #%d [%x…]: %w", number, hash[:4], errInnerTxsNotRecorded)
+	}
+	for i, txs := range innerTxs {
+		for j, tx := range txs {
+			if !tx.IsValueTransfer() {
+				continue
+			}
+			pos := rawdb.InnerTxPosition{Number: number, TxIndex: uint32(i), CallIndex: uint32(j)}
+			rawdb.WriteInnerTxAddressEntry(b.batch, tx.From, pos, hash)
+			if tx.To != tx.From {
+				rawdb.WriteInnerTxAddressEntry(b.batch, tx.To, pos, hash)
+			}
+		}
+	}
+	if b.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := b.batch.Write(); err != nil {
+			return err
+		}
+		b.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the remaining index
+// entries of the section into the database.
+func (b *InnerTxIndexer) Commit() error {
+	return b.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *InnerTxIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that blocks imported without recording their inner transactions fail the
+// section of the address index, which gets indexed once they are backfilled.
+func TestInnerTxIndexerUnrecorded(t *testing.T) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		forwarder = common.HexToAddress("0xfa")
+		recipient = common.HexToAddress("0xaa")
+		db        = rawdb.NewMemoryDatabase()
+	)
+	// The forwarder passes the value it receives on to the recipient
+	forward := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLVALUE),
+		byte(vm.PUSH1), 0xaa, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP),
+	}
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender:    {Balance: big.NewInt(params.Ether)},
+			forwarder: {Code: forward, Balance: new(big.Int)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 3, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), forwarder, big.NewInt(1), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{RecordInnerTxs: true}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Pretend the middle block was imported before recording was enabled
+	recorded := rawdb.ReadInnerTxs(db, blocks[1].Hash(), blocks[1].NumberU64())
+	rawdb.DeleteInnerTxs(db, blocks[1].Hash(), blocks[1].NumberU64())
+
+	indexer := &InnerTxIndexer{db: db}
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	for _, block := range append([]*types.Block{genesis}, blocks...) {
+		err := indexer.Process(context.Background(), block.Header())
+		if block == blocks[1] {
+			if !errors.Is(err, errInnerTxsNotRecorded) {
+				t.Fatalf("unrecorded block error mismatch: have %v, want %v", err, errInnerTxsNotRecorded)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to index block %d: %v", block.NumberU64(), err)
+		}
+	}
+	// Backfill the missing inner transactions and reprocess the section
+	rawdb.WriteInnerTxs(db, blocks[1].Hash(), blocks[1].NumberU64(), recorded)
+
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	for _, block := range append([]*types.Block{genesis}, blocks...) {
+		if err := indexer.Process(context.Background(), block.Header()); err != nil {
+			t.Fatalf("failed to index block %d: %v", block.NumberU64(), err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("failed to commit index: %v", err)
+	}
+	entries := rawdb.ReadInnerTxAddressEntries(db, recipient, rawdb.InnerTxPosition{}, 4, 10)
+	if len(entries) != len(blocks) {
+		t.Fatalf("index entry count mismatch: have %d, want %d", len(entries), len(blocks))
+	}
+	for i, block := range blocks {
+		if entries[i].Number != block.NumberU64() || entries[i].BlockHash != block.Hash() {
+			t.Errorf("entry %d: block mismatch: have #%d [%x], want #%d [%x]", i, entries[i].Number, entries[i].BlockHash, block.NumberU64(), block.Hash())
+		}
+	}
+}
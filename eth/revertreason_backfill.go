@@ -0,0 +1,59 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// BackfillRevertReasons re-executes the canonical blocks in the [from, to] range
+// and stores the revert reasons of their failed transactions, for blocks imported
+// before revert reasons were recorded or synced without execution. The receipts
+// root of every re-executed block is verified against its header.
+//
+// Progress is checkpointed the same way as for BackfillInnerTxs.
+func BackfillRevertReasons(chain *core.BlockChain, db ethdb.Database, from, to uint64, workers int, reexec uint64, stop <-chan struct{}) error {
+	return backfill(chain, db, from, to, workers, reexec, stop, &backfillSpec{
+		name:             "revert reasons",
+		process:          backfillBlockRevertReasons,
+		readCheckpoint:   rawdb.ReadRevertReasonBackfillCheckpoint,
+		writeCheckpoint:  rawdb.WriteRevertReasonBackfillCheckpoint,
+		deleteCheckpoint: rawdb.DeleteRevertReasonBackfillCheckpoint,
+	})
+}
+
+// backfillBlockRevertReasons re-executes a block on top of its parent state,
+// storing the revert reasons of its failed transactions.
+func backfillBlockRevertReasons(chain *core.BlockChain, db ethdb.Database, block *types.Block, statedb *state.StateDB) error {
+	receipts, _, _, _, err := chain.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		return fmt.Errorf("processing block %d failed: %v", block.NumberU64(), err)
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != block.ReceiptHash() {
+		return fmt.Errorf("receipts root mismatch in block %d: have %x, want %x", block.NumberU64(), root, block.ReceiptHash())
+	}
+	rawdb.WriteRevertReasons(db, receipts)
+	return nil
+}
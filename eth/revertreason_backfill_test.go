@@ -0,0 +1,123 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the revert reasons of failed transactions are recorded on import,
+// attached to their receipts and can be backfilled by re-execution.
+func TestBackfillRevertReasons(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		reverter = common.HexToAddress("0xdead")
+		db       = rawdb.NewMemoryDatabase()
+	)
+	// The reverter reverts with the ABI encoded Error("boom") appended to its code
+	reason := hexutil.MustDecode("0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000004" +
+		"626f6f6d00000000000000000000000000000000000000000000000000000000")
+	code := []byte{
+		byte(vm.PUSH1), byte(len(reason)), byte(vm.PUSH1), 12, byte(vm.PUSH1), 0, byte(vm.CODECOPY),
+		byte(vm.PUSH1), byte(len(reason)), byte(vm.PUSH1), 0, byte(vm.REVERT),
+	}
+	code = append(code, reason...)
+
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender:   {Balance: big.NewInt(params.Ether)},
+			reverter: {Code: code, Balance: new(big.Int)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 4, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), reverter, nil, 100000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(sender), common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+	})
+	cacheConfig := &core.CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 256,
+		TrieTimeLimit:  5 * time.Minute,
+		RevertReasons:  true,
+	}
+	chain, _ := core.NewBlockChain(db, cacheConfig, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	check := func(recorded bool) {
+		for _, block := range blocks {
+			receipts := rawdb.ReadReceipts(db, block.Hash(), block.NumberU64(), gspec.Config)
+			if len(receipts) != 2 {
+				t.Fatalf("block %d: receipt count mismatch: have %d, want 2", block.NumberU64(), len(receipts))
+			}
+			if receipts[1].RevertReason != nil {
+				t.Errorf("block %d: revert reason on successful transaction: %x", block.NumberU64(), receipts[1].RevertReason)
+			}
+			if !recorded {
+				if receipts[0].RevertReason != nil {
+					t.Errorf("block %d: unexpected revert reason %x", block.NumberU64(), receipts[0].RevertReason)
+				}
+				continue
+			}
+			if !bytes.Equal(receipts[0].RevertReason, reason) {
+				t.Errorf("block %d: revert reason mismatch: have %x, want %x", block.NumberU64(), receipts[0].RevertReason, reason)
+			}
+			if msg, err := abi.UnpackRevert(receipts[0].RevertReason); err != nil || msg != "boom" {
+				t.Errorf("block %d: revert message mismatch: have %q (%v), want %q", block.NumberU64(), msg, err, "boom")
+			}
+		}
+	}
+	check(true)
+
+	// Drop the recorded reasons and backfill them by re-execution
+	for _, block := range blocks {
+		rawdb.DeleteRevertReason(db, block.Transactions()[0].Hash())
+	}
+	check(false)
+
+	if err := BackfillRevertReasons(chain, db, 1, 4, 2, 0, nil); err != nil {
+		t.Fatalf("failed to backfill: %v", err)
+	}
+	check(true)
+
+	if checkpoint := rawdb.ReadRevertReasonBackfillCheckpoint(db); checkpoint != nil {
+		t.Fatalf("checkpoint retained after finished backfill: %d", *checkpoint)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// tokenIndexSectionSize is the number of blocks in a single section of the
+	// token transfer index, the same as the bloombits sections.
+	tokenIndexSectionSize = params.BloomBitsBlocks
+
+	// tokenIndexConfirms is the number of confirmation blocks before a token
+	// transfer index section is considered probably final and gets indexed.
+	tokenIndexConfirms = params.BloomConfirms
+
+	// tokenIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	tokenIndexThrottling = 100 * time.Millisecond
+)
+
+// TokenTransferIndexer implements a core.ChainIndexer, building up an index from
+// the holders sending or receiving ERC-20, ERC-721 and ERC-1155 tokens to the
+// transfers decoded from the standard transfer events.
+//
+// The holders indexed in every section are recorded, so when a section is
+// reprocessed after a reorg, its previous entries are deleted first.
+type TokenTransferIndexer struct {
+	db     ethdb.Database      // database instance to write index data into
+	table  ethdb.Database      // table of the chain indexer to record the holders of the sections in
+	config *params.ChainConfig // chain configuration to derive the receipt fields with
+	size   uint64              // number of blocks in a single section
+
+	section uint64                      // section currently being indexed
+	holders map[common.Address]struct{} // holders indexed in the current section
+	batch   ethdb.Batch                 // batch accumulating the index entries of the current section
+}
+
+// NewTokenTransferIndexer returns a chain indexer that maintains the holder index
+// of the token transfers of the canonical chain.
+func NewTokenTransferIndexer(db ethdb.Database, config *params.ChainConfig, size, confirms uint64) *core.ChainIndexer {
+	table := rawdb.NewTable(db, string(rawdb.TokenTransferIndexPrefix))
+	backend := &TokenTransferIndexer{
+		db:     db,
+		table:  table,
+		config: config,
+		size:   size,
+	}
+	return core.NewChainIndexer(db, table, backend, size, confirms, tokenIndexThrottling, "tokens")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new token transfer index
+// section. Any entries indexed for the section before are rolled back. Their
+// holders stay recorded, so an interrupted rollback is redone on the next try.
+func (b *TokenTransferIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.section, b.holders, b.batch = section, make(map[common.Address]struct{}), b.db.NewBatch()
+
+	from, to := section*b.size, (section+1)*b.size
+	for _, holder := range rawdb.ReadTokenTransferHolders(b.table, section) {
+		rawdb.DeleteTokenTransfers(b.db, b.batch, holder, from, to)
+		b.holders[holder] = struct{}{}
+	}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the token transfers of a
+// block into the index of both their sender and recipient.
+func (b *TokenTransferIndexer) Process(ctx context.Context, header *types.Header) error {
+	if header.ReceiptHash == types.EmptyRootHash {
+		return nil
+	}
+	number, hash := header.Number.Uint64(), header.Hash()
+	receipts := rawdb.ReadReceipts(b.db, hash, number, b.config)
+	if receipts == nil {
+		return fmt.Errorf("receipts of block #%d [%x…] not found", number, hash[:4])
+	}
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			for _, transfer := range types.DecodeTokenTransfers(log) {
+				b.index(transfer.From, transfer)
+				if transfer.To != transfer.From {
+					b.index(transfer.To, transfer)
+				}
+			}
+		}
+	}
+	if b.batch.ValueSize() >= ethdb.IdealBatchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// index adds a token transfer to the index of a holder. Transfers of the zero
+// address, i.e. mints and burns, are only indexed for the other holder.
+func (b *TokenTransferIndexer) index(holder common.Address, transfer *types.TokenTransfer) {
+	if holder == (common.Address{}) {
+		return
+	}
+	b.holders[holder] = struct{}{}
+	rawdb.WriteTokenTransfer(b.batch, holder, transfer)
+}
+
+// flush writes out the index entries accumulated so far. The holders of the
+// section are recorded beforehand, so even partially indexed sections can be
+// rolled back.
+func (b *TokenTransferIndexer) flush() error {
+	holders := make([]common.Address, 0, len(b.holders))
+	for holder := range b.holders {
+		holders = append(holders, holder)
+	}
+	rawdb.WriteTokenTransferHolders(b.table, b.section, holders)
+	if err := b.batch.Write(); err != nil {
+		return err
+	}
+	b.batch.Reset()
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the remaining index
+// entries of the section into the database.
+func (b *TokenTransferIndexer) Commit() error {
+	return b.flush()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *TokenTransferIndexer) Prune(threshold uint64) error {
+	return nil
+}
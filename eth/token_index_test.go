@@ -0,0 +1,135 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// transferCode returns the code of a token emitting an ERC-20 Transfer event of
+// the given value from the caller to the recipient whenever called.
+func transferCode(to common.Address, value byte) []byte {
+	code := []byte{byte(vm.PUSH1), value, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH20)}
+	code = append(code, to.Bytes()...)
+	code = append(code, byte(vm.CALLER), byte(vm.PUSH32))
+	code = append(code, types.TransferEventTopic.Bytes()...)
+	return append(code, byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG3), byte(vm.STOP))
+}
+
+// Tests that token transfers are indexed for both their sender and recipient, and
+// that reprocessing a section after a reorg rolls back its stale entries.
+func TestTokenTransferIndexer(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		alice  = common.HexToAddress("0xa1")
+		bob    = common.HexToAddress("0xb0")
+		tokenA = common.HexToAddress("0xaa")
+		tokenB = common.HexToAddress("0xbb")
+		db     = rawdb.NewMemoryDatabase()
+	)
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			tokenA: {Code: transferCode(alice, 1), Balance: new(big.Int)},
+			tokenB: {Code: transferCode(bob, 2), Balance: new(big.Int)},
+		},
+	}
+	genesis := gspec.MustCommit(db)
+	signer := types.HomesteadSigner{}
+	generate := func(token common.Address, n int, seed byte) []*types.Block {
+		blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, n, func(i int, b *core.BlockGen) {
+			b.SetExtra([]byte{seed})
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), token, new(big.Int), 100000, big.NewInt(1), nil), signer, key)
+			b.AddTx(tx)
+		})
+		return blocks
+	}
+	chain, _ := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer chain.Stop()
+
+	table := rawdb.NewTable(db, string(rawdb.TokenTransferIndexPrefix))
+	indexer := &TokenTransferIndexer{db: db, table: table, config: gspec.Config, size: 4}
+	index := func() {
+		if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+			t.Fatalf("failed to reset indexer: %v", err)
+		}
+		for number := uint64(0); number < 4; number++ {
+			if err := indexer.Process(context.Background(), chain.GetHeaderByNumber(number)); err != nil {
+				t.Fatalf("failed to index block %d: %v", number, err)
+			}
+		}
+		if err := indexer.Commit(); err != nil {
+			t.Fatalf("failed to commit index: %v", err)
+		}
+	}
+	count := func(holder common.Address, token common.Address) int {
+		var n int
+		for _, transfer := range rawdb.ReadTokenTransfers(db, holder, rawdb.TokenTransferPosition{}, 4, math.MaxInt32) {
+			if transfer.Token != token {
+				t.Errorf("holder %x: transfer of unexpected token %x", holder, transfer.Token)
+			}
+			if chain.GetCanonicalHash(transfer.BlockNumber) != transfer.BlockHash {
+				t.Errorf("holder %x: transfer of non-canonical block %d", holder, transfer.BlockNumber)
+			}
+			n++
+		}
+		return n
+	}
+	// Index a chain transferring token A to alice in every block
+	if _, err := chain.InsertChain(generate(tokenA, 3, 0)); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	index()
+	if n := count(sender, tokenA); n != 3 {
+		t.Errorf("sender transfers mismatch: have %d, want 3", n)
+	}
+	if n := count(alice, tokenA); n != 3 {
+		t.Errorf("alice transfers mismatch: have %d, want 3", n)
+	}
+	transfers := rawdb.ReadTokenTransfers(db, alice, rawdb.TokenTransferPosition{Number: 2}, 4, 1)
+	if len(transfers) != 1 || transfers[0].BlockNumber != 2 || transfers[0].From != sender || transfers[0].Value.Uint64() != 1 || transfers[0].Standard != types.TokenERC20 {
+		t.Errorf("transfer mismatch: have %+v", transfers)
+	}
+	// Reorg to a chain transferring token B to bob instead and reindex
+	if _, err := chain.InsertChain(generate(tokenB, 4, 1)); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	index()
+	if n := count(sender, tokenB); n != 3 {
+		t.Errorf("sender transfers mismatch after reorg: have %d, want 3", n)
+	}
+	if n := count(bob, tokenB); n != 3 {
+		t.Errorf("bob transfers mismatch after reorg: have %d, want 3", n)
+	}
+	if n := count(alice, tokenA); n != 0 {
+		t.Errorf("alice transfers not rolled back: have %d", n)
+	}
+}
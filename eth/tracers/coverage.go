@@ -0,0 +1,238 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func init() {
+	RegisterNative("coverageTracer", func(txCtx vm.TxContext) TxTracer { return NewCoverageTracer() })
+}
+
+// CodeCoverage is the coverage of the instructions of a single contract code.
+type CodeCoverage struct {
+	Address          common.Address    `json:"address"`          // Address the code was first executed at
+	Instructions     int               `json:"instructions"`     // Number of instructions in the code
+	Covered          int               `json:"covered"`          // Number of instructions executed at least once
+	JumpDests        int               `json:"jumpDests"`        // Number of jump destinations in the code
+	JumpDestsCovered int               `json:"jumpDestsCovered"` // Number of jump destinations executed at least once
+	Hits             map[uint64]uint64 `json:"hits"`             // Number of executions of each program counter
+
+	code []byte
+}
+
+// instructions returns the program counters of all the instructions in the given
+// code, skipping over push data. Any data appended to the code (e.g. the compiler
+// metadata) is decoded as instructions too.
+func instructions(code []byte) []uint64 {
+	var pcs []uint64
+	for pc := 0; pc < len(code); pc++ {
+		pcs = append(pcs, uint64(pc))
+		if op := vm.OpCode(code[pc]); op.IsPush() {
+			pc += int(op - vm.PUSH1 + 1)
+		}
+	}
+	return pcs
+}
+
+// CoverageTracer is a tracer recording how often each instruction of each code
+// was executed. A single tracer may be attached to any number of executions,
+// concurrent ones included, to aggregate their coverage.
+type CoverageTracer struct {
+	codes map[common.Hash]*CodeCoverage
+	lock  sync.Mutex
+
+	stop   uint32 // Atomic flag to stop collecting coverage
+	reason error  // Textual reason for the interruption
+}
+
+// NewCoverageTracer creates a coverage tracer without any executions recorded.
+func NewCoverageTracer() *CoverageTracer {
+	return &CoverageTracer{codes: make(map[common.Hash]*CodeCoverage)}
+}
+
+// Stop terminates the coverage collection at the first opportune moment.
+func (t *CoverageTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.stop, 1)
+}
+
+// CaptureStart implements the Tracer interface.
+func (t *CoverageTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface, counting the executed instruction
+// towards the coverage of the executing code.
+func (t *CoverageTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	// Steps aborted before execution (e.g. out of gas) are not covered
+	if err != nil || atomic.LoadUint32(&t.stop) > 0 {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	cov, ok := t.codes[contract.CodeHash]
+	if !ok {
+		cov = &CodeCoverage{
+			Address: contract.Address(),
+			Hits:    make(map[uint64]uint64),
+			code:    common.CopyBytes(contract.Code),
+		}
+		if contract.CodeAddr != nil {
+			cov.Address = *contract.CodeAddr
+		}
+		t.codes[contract.CodeHash] = cov
+	}
+	cov.Hits[pc]++
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface.
+func (t *CoverageTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureExit implements the Tracer interface.
+func (t *CoverageTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// CaptureFault implements the Tracer interface.
+func (t *CoverageTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface.
+func (t *CoverageTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	return nil
+}
+
+// Coverage returns the coverage collected so far of every executed code, keyed
+// by code hash.
+func (t *CoverageTracer) Coverage() map[common.Hash]*CodeCoverage {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := make(map[common.Hash]*CodeCoverage, len(t.codes))
+	for hash, cov := range t.codes {
+		summary := &CodeCoverage{
+			Address: cov.Address,
+			Hits:    make(map[uint64]uint64, len(cov.Hits)),
+			code:    cov.code,
+		}
+		for pc, hits := range cov.Hits {
+			summary.Hits[pc] = hits
+		}
+		for _, pc := range instructions(cov.code) {
+			jumpdest := vm.OpCode(cov.code[pc]) == vm.JUMPDEST
+			if summary.Instructions++; jumpdest {
+				summary.JumpDests++
+			}
+			if summary.Hits[pc] > 0 {
+				if summary.Covered++; jumpdest {
+					summary.JumpDestsCovered++
+				}
+			}
+		}
+		result[hash] = summary
+	}
+	return result
+}
+
+// WriteLCOV writes the line coverage of all the executed code that can be mapped
+// to its sources by the given registry, in lcov tracefile format. A line counts
+// as many hits as the most executed instruction mapping to it.
+func (t *CoverageTracer) WriteLCOV(w io.Writer, registry *SourceRegistry) error {
+	coverage := t.Coverage()
+
+	hashes := make([]common.Hash, 0, len(coverage))
+	for hash := range coverage {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+
+	files := make(map[string]map[int]uint64)
+	for _, hash := range hashes {
+		cov := coverage[hash]
+		for _, pc := range instructions(cov.code) {
+			loc := registry.LocateCode(hash, &cov.Address, pc)
+			if loc == nil {
+				continue
+			}
+			lines, ok := files[loc.File]
+			if !ok {
+				lines = make(map[int]uint64)
+				files[loc.File] = lines
+			}
+			if hits, ok := lines[loc.Line]; !ok || cov.Hits[pc] > hits {
+				lines[loc.Line] = cov.Hits[pc]
+			}
+		}
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		lines := make([]int, 0, len(files[name]))
+		for line := range files[name] {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", name); err != nil {
+			return err
+		}
+		hit := 0
+		for _, line := range lines {
+			hits := files[name][line]
+			if hits > 0 {
+				hit++
+			}
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", line, hits); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetResult returns the coverage collected as raw per program counter hit counts.
+func (t *CoverageTracer) GetResult() (json.RawMessage, error) {
+	if atomic.LoadUint32(&t.stop) > 0 {
+		return nil, t.reason
+	}
+	return json.Marshal(t.Coverage())
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+// TxTracer is a vm.Tracer assembling a JSON result of a single traced transaction,
+// whose execution can be interrupted. It's implemented by both the JavaScript and
+// the native tracers.
+type TxTracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the tracing, or any error which occurred.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the tracing at the first opportune moment.
+	Stop(err error)
+}
+
+// NativeConstructor creates a fresh native tracer for tracing a transaction in
+// the given context.
+type NativeConstructor func(txCtx vm.TxContext) TxTracer
+
+// natives contains all the registered Go-native tracers by name.
+var natives = make(map[string]NativeConstructor)
+
+// RegisterNative makes a Go-native tracer available by name. Native tracers take
+// precedence over the built in JavaScript tracers of the same name, which they
+// are expected to be output compatible with. It panics if a native tracer with
+// the same name is already registered.
+func RegisterNative(name string, ctor NativeConstructor) {
+	if _, ok := natives[name]; ok {
+		panic(fmt.Sprintf("native tracer %q already registered", name))
+	}
+	natives[name] = ctor
+}
+
+// NewTxTracer instantiates the native tracer registered with the given name, or
+// if there's none, a JavaScript tracer from the given code or built in tracer name.
+func NewTxTracer(code string, txCtx vm.TxContext) (TxTracer, error) {
+	if ctor, ok := natives[code]; ok {
+		return ctor(txCtx), nil
+	}
+	tracer, err := New(code, txCtx)
+	if err != nil {
+		return nil, err
+	}
+	return tracer, nil
+}
+
+// stackPeek returns the nth-from-the-top element of the stack, or zero if the
+// stack is not deep enough, mirroring the stack access of JavaScript tracers.
+func stackPeek(stack *vm.Stack, n int) *uint256.Int {
+	if len(stack.Data()) <= n || n < 0 {
+		log.Warn("Tracer accessed out of bound stack", "size", len(stack.Data()), "index", n)
+		return new(uint256.Int)
+	}
+	return stack.Back(n)
+}
+
+// memorySlice returns a copy of the [offset, offset+size) range of the memory, or
+// an empty slice if out of bounds, mirroring the memory access of JavaScript tracers.
+func memorySlice(memory *vm.Memory, offset, size *uint256.Int) []byte {
+	if size.IsZero() {
+		return []byte{}
+	}
+	if !offset.IsUint64() || !size.IsUint64() || offset.Uint64()+size.Uint64() < offset.Uint64() || uint64(memory.Len()) < offset.Uint64()+size.Uint64() {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", offset, "size", size)
+		return []byte{}
+	}
+	return memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+}
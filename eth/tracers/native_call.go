@@ -0,0 +1,323 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+)
+
+func init() {
+	RegisterNative("callTracer", newCallTracer)
+}
+
+// callFrame is a single call reported by the call tracer. The field order and the
+// omitted fields match the output of the JavaScript callTracer.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes  `json:"input,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Time    string          `json:"time,omitempty"`
+	Calls   []*callFrame    `json:"calls,omitempty"`
+
+	gas     *uint64      // Gas available within the call, if known
+	gasIn   uint64       // Gas available to the caller before the call
+	gasCost uint64       // Cost of the opcode initiating the call
+	outOff  *uint256.Int // Memory offset of the call output in the caller
+	outLen  *uint256.Int // Memory size of the call output in the caller
+}
+
+// callTracer is a native implementation of the callTracer JavaScript tracer,
+// extracting and reporting all the internal calls made by a transaction.
+type callTracer struct {
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+
+	typ     string         // Type of the top level call, CALL or CREATE
+	from    common.Address // Sender of the transaction
+	to      common.Address // Recipient or created contract of the transaction
+	input   []byte         // Input data or init code of the transaction
+	gas     uint64         // Gas available to the top level call
+	value   *big.Int       // Value sent along with the transaction
+	output  []byte         // Return data of the top level call
+	gasUsed uint64         // Gas used by the top level call
+	elapsed time.Duration  // Duration of the top level call
+	err     error          // Error the top level call failed with
+	reason  error          // Textual reason for the interruption
+	stop    uint32         // Atomic flag to signal execution interruption
+	stopped bool           // Whether the interruption was already handled
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer(txCtx vm.TxContext) TxTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// Stop terminates the tracing at the first opportune moment.
+func (t *callTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.stop, 1)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.typ = "CALL"
+	if create {
+		t.typ = "CREATE"
+	}
+	t.from, t.to, t.input, t.gas, t.value = from, to, input, gas, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface, tracking the call frames entered
+// and left by the opcodes executed.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if t.stopped {
+		return nil
+	}
+	if atomic.LoadUint32(&t.stop) > 0 {
+		t.stopped = true
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// If a new contract is being created, add to the call stack
+		input := hexutil.Bytes(memorySlice(memory, stackPeek(stack, 1), stackPeek(stack, 2)))
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			Input:   &input,
+			Value:   (*hexutil.Big)(stackPeek(stack, 0).ToBig()),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// If a contract is being self destructed, gather that as a subcall too
+		to := common.Address(stackPeek(stack, 0).Bytes20())
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{
+			Type:  op.String(),
+			From:  contract.Address(),
+			To:    &to,
+			Value: (*hexutil.Big)(new(big.Int).Set(env.StateDB.GetBalance(contract.Address()))),
+		})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.Address(stackPeek(stack, 1).Bytes20())
+		if _, ok := vm.PrecompiledContractsIstanbul[to]; ok {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		input := hexutil.Bytes(memorySlice(memory, stackPeek(stack, 2+off), stackPeek(stack, 3+off)))
+		call := &callFrame{
+			Type:    op.String(),
+			From:    contract.Address(),
+			To:      &to,
+			Input:   &input,
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  new(uint256.Int).Set(stackPeek(stack, 4+off)),
+			outLen:  new(uint256.Int).Set(stackPeek(stack, 5+off)),
+		}
+		if off == 1 {
+			call.Value = (*hexutil.Big)(stackPeek(stack, 2).ToBig())
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	// Calls made to plain accounts don't execute any code, so their gas is unknown.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			allowance := gas
+			t.callstack[len(t.callstack)-1].gas = &allowance
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := stackPeek(stack, 0)
+		if call.Type == "CREATE" || call.Type == "CREATE2" {
+			// If the call was a CREATE, retrieve the contract address and output code
+			gasUsed := hexutil.Uint64(call.gasIn - call.gasCost - gas)
+			call.GasUsed = &gasUsed
+
+			if !ret.IsZero() {
+				to := common.Address(ret.Bytes20())
+				output := hexutil.Bytes(env.StateDB.GetCode(to))
+				call.To, call.Output = &to, &output
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else {
+			// If the call was a contract call, retrieve the gas usage and output
+			if call.gas != nil {
+				gasUsed := hexutil.Uint64(call.gasIn - call.gasCost + *call.gas - gas)
+				call.GasUsed = &gasUsed
+			}
+			if !ret.IsZero() {
+				output := hexutil.Bytes(memorySlice(memory, call.outOff, call.outLen))
+				call.Output = &output
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		if call.gas != nil {
+			allowance := hexutil.Uint64(*call.gas)
+			call.Gas = &allowance
+		}
+		// Inject the call into the previous one
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface. Call frames are tracked by their
+// opcodes in CaptureState, mirroring the JavaScript tracer.
+func (t *callTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureExit implements the Tracer interface.
+func (t *callTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	if !t.stopped {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault handles the failure of the currently executing call.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas
+	if call.gas != nil {
+		allowance := hexutil.Uint64(*call.gas)
+		call.Gas, call.GasUsed = &allowance, &allowance
+	}
+	// Flatten the failed call into its parent
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	t.output, t.gasUsed, t.elapsed, t.err = output, gasUsed, elapsed, err
+	return nil
+}
+
+// GetResult returns the call tree of the traced transaction, or the reason the
+// tracing was interrupted with.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.stopped {
+		return nil, t.reason
+	}
+	var (
+		to      = t.to
+		value   = new(big.Int)
+		gas     = hexutil.Uint64(t.gas)
+		gasUsed = hexutil.Uint64(t.gasUsed)
+		input   = hexutil.Bytes(t.input)
+		output  = hexutil.Bytes(t.output)
+	)
+	if t.value != nil {
+		value.Set(t.value)
+	}
+	if input == nil {
+		input = hexutil.Bytes{}
+	}
+	if output == nil {
+		output = hexutil.Bytes{}
+	}
+	result := &callFrame{
+		Type:    t.typ,
+		From:    t.from,
+		To:      &to,
+		Value:   (*hexutil.Big)(value),
+		Gas:     &gas,
+		GasUsed: &gasUsed,
+		Input:   &input,
+		Output:  &output,
+		Time:    t.elapsed.String(),
+		Calls:   t.callstack[0].Calls,
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.err != nil {
+		result.Error = t.err.Error()
+	}
+	if result.Error != "" && (result.Error != "execution reverted" || len(output) == 0) {
+		result.Output = nil
+	}
+	return json.Marshal(result)
+}
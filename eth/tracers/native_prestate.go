@@ -0,0 +1,290 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func init() {
+	RegisterNative("prestateTracer", newPrestateTracer)
+}
+
+// prestateAccount is the state of a single account prior to the traced transaction.
+type prestateAccount struct {
+	Balance *hexutil.Big
+	Nonce   uint64
+	Code    hexutil.Bytes
+	Storage map[common.Hash]common.Hash
+
+	slots []common.Hash // Storage slots in the order they were accessed
+}
+
+// MarshalJSON encodes the account with its storage slots in the order they were
+// accessed, same as the JavaScript tracer does.
+func (acc *prestateAccount) MarshalJSON() ([]byte, error) {
+	balance, err := json.Marshal(acc.Balance)
+	if err != nil {
+		return nil, err
+	}
+	code, err := json.Marshal(acc.Code)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(`{"balance":`)
+	buf.Write(balance)
+	buf.WriteString(`,"nonce":`)
+	buf.WriteString(strconv.FormatUint(acc.Nonce, 10))
+	buf.WriteString(`,"code":`)
+	buf.Write(code)
+	buf.WriteString(`,"storage":{`)
+	for i, slot := range acc.slots {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, `"%s":"%s"`, slot.Hex(), acc.Storage[slot].Hex())
+	}
+	buf.WriteString("}}")
+	return buf.Bytes(), nil
+}
+
+// prestateAlloc is the set of accounts accessed by the traced transaction.
+type prestateAlloc struct {
+	accounts map[common.Address]*prestateAccount
+	order    []common.Address // Accounts in the order they were accessed
+}
+
+// MarshalJSON encodes the allocations with the accounts in the order they were
+// accessed, same as the JavaScript tracer does.
+func (alloc *prestateAlloc) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, addr := range alloc.order {
+		account, err := json.Marshal(alloc.accounts[addr])
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, `"%s":`, hexutil.Encode(addr[:]))
+		buf.Write(account)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// prestateTracer is a native implementation of the prestateTracer JavaScript
+// tracer, collecting the state accessed by a transaction prior to its execution,
+// sufficient to create a local execution of it from a custom assembled genesis.
+type prestateTracer struct {
+	prestate *prestateAlloc // Genesis allocations being built
+	db       vm.StateDB     // State database the transaction executes on
+
+	create       bool           // Whether the transaction creates a contract
+	from         common.Address // Sender of the transaction
+	to           common.Address // Recipient or created contract of the transaction
+	input        []byte         // Input data or init code of the transaction
+	value        *big.Int       // Value sent along with the transaction
+	gasPrice     *big.Int       // Gas price of the transaction
+	gasUsed      uint64         // Gas used by the top level call
+	intrinsicGas uint64         // Intrinsic gas of the transaction
+
+	err     error  // Error, if one has occurred
+	reason  error  // Textual reason for the interruption
+	stop    uint32 // Atomic flag to signal execution interruption
+	stopped bool   // Whether the interruption was already handled
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer(txCtx vm.TxContext) TxTracer {
+	return &prestateTracer{gasPrice: txCtx.GasPrice}
+}
+
+// Stop terminates the tracing at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.stop, 1)
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate.accounts[addr]; ok {
+		return
+	}
+	t.prestate.order = append(t.prestate.order, addr)
+	t.prestate.accounts[addr] = &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.db.GetBalance(addr))),
+		Nonce:   t.db.GetNonce(addr),
+		Code:    common.CopyBytes(t.db.GetCode(addr)),
+		Storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+
+	account := t.prestate.accounts[addr]
+	if _, ok := account.Storage[key]; ok {
+		return
+	}
+	account.slots = append(account.slots, key)
+	account.Storage[key] = t.db.GetState(addr, key)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create, t.from, t.to, t.input, t.value = create, from, to, input, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface, adding any state accessed by the
+// executed opcodes to the prestate.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil || t.stopped {
+		return nil
+	}
+	if atomic.LoadUint32(&t.stop) > 0 {
+		t.stopped = true
+		return nil
+	}
+	// Add the current account if we just started tracing
+	if t.prestate == nil {
+		// Compute intrinsic gas
+		isHomestead := env.ChainConfig().IsHomestead(env.Context.BlockNumber)
+		isIstanbul := env.ChainConfig().IsIstanbul(env.Context.BlockNumber)
+		if t.intrinsicGas, t.err = core.IntrinsicGas(t.input, t.create, isHomestead, isIstanbul); t.err != nil {
+			return t.err
+		}
+		t.prestate = &prestateAlloc{accounts: make(map[common.Address]*prestateAccount)}
+		t.db = env.StateDB
+
+		// Balance will potentially be wrong here, since this will include the value
+		// sent along with the message. We fix that in GetResult.
+		t.lookupAccount(contract.Address())
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.Address(stackPeek(stack, 0).Bytes20()))
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+
+	case vm.CREATE2:
+		// stack: salt, size, offset, endowment
+		from := contract.Address()
+		code := memorySlice(memory, stackPeek(stack, 1), stackPeek(stack, 2))
+		salt := common.Hash(stackPeek(stack, 3).Bytes32())
+		t.lookupAccount(crypto.CreateAddress2(from, salt, crypto.Keccak256(code)))
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.Address(stackPeek(stack, 1).Bytes20()))
+
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.Hash(stackPeek(stack, 0).Bytes32()))
+	}
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface. Call frames are tracked by their
+// opcodes in CaptureState, mirroring the JavaScript tracer.
+func (t *prestateTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureExit implements the Tracer interface.
+func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	t.gasUsed = gasUsed
+	return nil
+}
+
+// GetResult returns the assembled prestate of the traced transaction, or any
+// error which occurred.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.stopped {
+		return nil, t.reason
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.prestate == nil {
+		return nil, errors.New("no state accessed by the traced execution")
+	}
+	// At this point, we need to deduct the 'value' from the outer transaction,
+	// and move it back to the origin
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+
+	value := new(big.Int)
+	if t.value != nil {
+		value.Set(t.value)
+	}
+	fee := new(big.Int).SetUint64(t.gasUsed + t.intrinsicGas)
+	if t.gasPrice != nil {
+		fee.Mul(fee, t.gasPrice)
+	} else {
+		fee.SetUint64(0)
+	}
+	to, from := t.prestate.accounts[t.to], t.prestate.accounts[t.from]
+	to.Balance = (*hexutil.Big)(new(big.Int).Sub(to.Balance.ToInt(), value))
+	from.Balance = (*hexutil.Big)(new(big.Int).Add(from.Balance.ToInt(), new(big.Int).Add(value, fee)))
+
+	// Decrement the caller's nonce, and remove empty create targets
+	from.Nonce--
+	if t.create {
+		// We can blindly delete the contract prestate, as any existing state would
+		// have caused the transaction to be rejected as invalid in the first place.
+		delete(t.prestate.accounts, t.to)
+		for i, addr := range t.prestate.order {
+			if addr == t.to {
+				t.prestate.order = append(t.prestate.order[:i], t.prestate.order[i+1:]...)
+				break
+			}
+		}
+	}
+	// Return the assembled allocations (prestate)
+	return json.Marshal(t.prestate)
+}
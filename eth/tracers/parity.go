@@ -0,0 +1,493 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+)
+
+// ParityTraceAction is the action of a single OpenEthereum style trace. Only the
+// fields relevant to the trace type are set:
+//   - call:    callType, from, gas, input, to, value
+//   - create:  from, gas, init, value
+//   - suicide: address, balance, refundAddress
+//   - reward:  author, rewardType, value
+type ParityTraceAction struct {
+	Address       *common.Address `json:"address,omitempty"`
+	Author        *common.Address `json:"author,omitempty"`
+	Balance       *hexutil.Big    `json:"balance,omitempty"`
+	CallType      string          `json:"callType,omitempty"`
+	From          *common.Address `json:"from,omitempty"`
+	Gas           *hexutil.Uint64 `json:"gas,omitempty"`
+	Init          *hexutil.Bytes  `json:"init,omitempty"`
+	Input         *hexutil.Bytes  `json:"input,omitempty"`
+	RefundAddress *common.Address `json:"refundAddress,omitempty"`
+	RewardType    string          `json:"rewardType,omitempty"`
+	To            *common.Address `json:"to,omitempty"`
+	Value         *hexutil.Big    `json:"value,omitempty"`
+}
+
+// ParityTraceResult is the outcome of a successful call or create trace.
+type ParityTraceResult struct {
+	Address *common.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes  `json:"code,omitempty"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+}
+
+// ParityTrace is a single call frame of a transaction in the flat trace format of
+// OpenEthereum. The position of the frame within the call tree is described by
+// its traceAddress: the path of child indices leading to it from the top level
+// call. Failed frames have their error set and no result.
+type ParityTrace struct {
+	Action       *ParityTraceAction `json:"action"`
+	Error        string             `json:"error,omitempty"`
+	Result       *ParityTraceResult `json:"result,omitempty"`
+	Subtraces    int                `json:"subtraces"`
+	TraceAddress []int              `json:"traceAddress"`
+	Type         string             `json:"type"`
+}
+
+// ParityMemoryDiff is a memory region written by an operation.
+type ParityMemoryDiff struct {
+	Off  uint64        `json:"off"`
+	Data hexutil.Bytes `json:"data"`
+}
+
+// ParityStorageDiff is a storage slot written by an operation.
+type ParityStorageDiff struct {
+	Key *hexutil.Big `json:"key"`
+	Val *hexutil.Big `json:"val"`
+}
+
+// ParityVMExecuted contains the effects of an executed operation.
+type ParityVMExecuted struct {
+	Used  uint64             `json:"used"`  // Gas remaining after the operation
+	Push  []*hexutil.Big     `json:"push"`  // Items pushed onto the stack
+	Mem   *ParityMemoryDiff  `json:"mem"`   // Memory written, if any
+	Store *ParityStorageDiff `json:"store"` // Storage written, if any
+}
+
+// ParityVMOperation is a single operation executed by the EVM.
+type ParityVMOperation struct {
+	Cost uint64            `json:"cost"`
+	Ex   *ParityVMExecuted `json:"ex"` // Effects of the operation, nil if it failed
+	Pc   uint64            `json:"pc"`
+	Sub  *ParityVMTrace    `json:"sub"` // Trace of the frame entered by calls and creates
+}
+
+// ParityVMTrace is the OpenEthereum style trace of the operations executed within
+// a single call frame.
+type ParityVMTrace struct {
+	Code hexutil.Bytes        `json:"code"`
+	Ops  []*ParityVMOperation `json:"ops"`
+}
+
+// ParityAccountDiff contains the changes of a single account within a state diff.
+// Each field is either "=" if unchanged, {"+": value} if the account was created,
+// {"-": value} if it was destroyed or {"*": {"from": old, "to": new}} otherwise.
+type ParityAccountDiff struct {
+	Balance interface{}                 `json:"balance"`
+	Code    interface{}                 `json:"code"`
+	Nonce   interface{}                 `json:"nonce"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// ParityStateDiff contains the changes of all the accounts modified by a transaction.
+type ParityStateDiff map[common.Address]*ParityAccountDiff
+
+// parityFrame is a call frame being traced.
+type parityFrame struct {
+	trace *ParityTrace
+	to    common.Address // Recipient or created contract of the frame
+}
+
+// parityVMFrame is a call frame whose operations are being traced.
+type parityVMFrame struct {
+	trace *ParityVMTrace
+	gas   uint64 // Gas available to the frame
+
+	pending *ParityVMOperation // Last operation, waiting for its effects
+	pushes  int                // Number of stack items pushed by the pending operation
+	memOff  uint64             // Memory offset written by the pending operation
+	memLen  uint64             // Memory length written by the pending operation
+	store   *ParityStorageDiff // Storage written by the pending operation
+}
+
+// ParityTracer is a native tracer producing OpenEthereum compatible call traces,
+// operation traces and state diffs of a transaction.
+type ParityTracer struct {
+	traces []*ParityTrace // Flattened call frames in the order of entering them
+	frames []*parityFrame // Stack of currently executing call frames
+
+	vmTrace  bool             // Whether to trace the executed operations
+	vmFrames []*parityVMFrame // Stack of currently executing operation traced frames
+	vmRoot   *ParityVMTrace   // Operation trace of the top level call
+
+	touched map[common.Address]map[common.Hash]struct{} // Accounts and slots possibly modified
+}
+
+// NewParityTracer creates a tracer producing OpenEthereum style traces. Tracing
+// the individual operations is expensive, so it's only done if vmTrace is set.
+func NewParityTracer(vmTrace bool) *ParityTracer {
+	return &ParityTracer{
+		vmTrace: vmTrace,
+		touched: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// touch marks an account as possibly modified by the transaction.
+func (t *ParityTracer) touch(addr common.Address) map[common.Hash]struct{} {
+	slots, ok := t.touched[addr]
+	if !ok {
+		slots = make(map[common.Hash]struct{})
+		t.touched[addr] = slots
+	}
+	return slots
+}
+
+// enter pushes a new call frame onto the stack, linking it to its parent.
+func (t *ParityTracer) enter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.touch(from)
+	t.touch(to)
+
+	trace := &ParityTrace{Action: new(ParityTraceAction), TraceAddress: []int{}}
+	if len(t.frames) > 0 {
+		parent := t.frames[len(t.frames)-1].trace
+		trace.TraceAddress = append(append([]int{}, parent.TraceAddress...), parent.Subtraces)
+		parent.Subtraces++
+	}
+	var (
+		action   = trace.Action
+		gasLimit = hexutil.Uint64(gas)
+		data     = hexutil.Bytes(common.CopyBytes(input))
+	)
+	if data == nil {
+		data = hexutil.Bytes{}
+	}
+	switch typ {
+	case vm.CREATE, vm.CREATE2:
+		trace.Type = "create"
+		action.From, action.Gas, action.Init, action.Value = &from, &gasLimit, &data, (*hexutil.Big)(new(big.Int).Set(value))
+
+	case vm.SELFDESTRUCT:
+		trace.Type = "suicide"
+		action.Address, action.RefundAddress, action.Balance = &from, &to, (*hexutil.Big)(new(big.Int).Set(value))
+
+	default:
+		trace.Type = "call"
+		action.CallType = strings.ToLower(typ.String())
+		action.From, action.To, action.Gas, action.Input = &from, &to, &gasLimit, &data
+
+		// Delegate calls carry over the value of their parent frame
+		switch {
+		case value != nil:
+			action.Value = (*hexutil.Big)(new(big.Int).Set(value))
+		case typ == vm.DELEGATECALL && len(t.frames) > 0:
+			action.Value = t.frames[len(t.frames)-1].trace.Action.Value
+		default:
+			action.Value = new(hexutil.Big)
+		}
+	}
+	t.traces = append(t.traces, trace)
+	t.frames = append(t.frames, &parityFrame{trace: trace, to: to})
+}
+
+// exit pops the topmost call frame off the stack, filling in its results.
+func (t *ParityTracer) exit(output []byte, gasUsed uint64, err error) *parityFrame {
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	trace := frame.trace
+	switch {
+	case trace.Type == "suicide":
+		// Self-destructs have no results
+
+	case err != nil:
+		trace.Error = parityError(err)
+
+	case trace.Type == "create":
+		code := hexutil.Bytes(common.CopyBytes(output))
+		if code == nil {
+			code = hexutil.Bytes{}
+		}
+		trace.Result = &ParityTraceResult{Address: &frame.to, Code: &code, GasUsed: hexutil.Uint64(gasUsed)}
+
+	default:
+		out := hexutil.Bytes(common.CopyBytes(output))
+		if out == nil {
+			out = hexutil.Bytes{}
+		}
+		trace.Result = &ParityTraceResult{GasUsed: hexutil.Uint64(gasUsed), Output: &out}
+	}
+	return frame
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *ParityTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	if create {
+		t.enter(vm.CREATE, from, to, input, gas, value)
+	} else {
+		t.enter(vm.CALL, from, to, input, gas, value)
+	}
+	if t.vmTrace {
+		t.vmRoot = &ParityVMTrace{Code: hexutil.Bytes{}, Ops: []*ParityVMOperation{}}
+		t.vmFrames = append(t.vmFrames, &parityVMFrame{trace: t.vmRoot, gas: gas})
+	}
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface to track a nested call frame.
+func (t *ParityTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	t.enter(typ, from, to, input, gas, value)
+
+	// Self-destructs don't execute any code, there's nothing to trace within them
+	if t.vmTrace && typ != vm.SELFDESTRUCT {
+		sub := &ParityVMTrace{Code: hexutil.Bytes{}, Ops: []*ParityVMOperation{}}
+		if parent := t.vmFrames[len(t.vmFrames)-1]; parent.pending != nil {
+			parent.pending.Sub = sub
+		}
+		t.vmFrames = append(t.vmFrames, &parityVMFrame{trace: sub, gas: gas})
+	}
+	return nil
+}
+
+// CaptureExit implements the Tracer interface to finalize a nested call frame.
+func (t *ParityTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	frame := t.exit(output, gasUsed, err)
+	if t.vmTrace && frame.trace.Type != "suicide" {
+		t.exitVM(gasUsed, err)
+	}
+	return nil
+}
+
+// exitVM pops the topmost operation traced frame, finalizing its last operation.
+func (t *ParityTracer) exitVM(gasUsed uint64, err error) {
+	frame := t.vmFrames[len(t.vmFrames)-1]
+	t.vmFrames = t.vmFrames[:len(t.vmFrames)-1]
+
+	// The last operation of the frame either halted or failed
+	if frame.pending != nil && (err == nil || err == vm.ErrExecutionReverted) {
+		frame.finalize(frame.gas-gasUsed, nil, nil)
+	}
+	frame.pending = nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *ParityTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if op == vm.SSTORE && err == nil {
+		t.touch(contract.Address())[common.Hash(stackPeek(stack, 0).Bytes32())] = struct{}{}
+	}
+	if !t.vmTrace {
+		return nil
+	}
+	frame := t.vmFrames[len(t.vmFrames)-1]
+	if len(frame.trace.Code) == 0 {
+		frame.trace.Code = common.CopyBytes(contract.Code)
+	}
+	// Fill in the effects of the previous operation and start the next one
+	if frame.pending != nil {
+		frame.finalize(gas, stack, memory)
+	}
+	operation := &ParityVMOperation{Cost: cost, Pc: pc}
+	frame.trace.Ops = append(frame.trace.Ops, operation)
+
+	// Operations failing before being executed have no effects
+	if err != nil {
+		return nil
+	}
+	frame.pending, frame.pushes, frame.memOff, frame.memLen, frame.store = operation, parityStackPushes(op), 0, 0, nil
+
+	switch op {
+	case vm.MSTORE:
+		frame.memOff, frame.memLen = stackPeek(stack, 0).Uint64(), 32
+	case vm.MSTORE8:
+		frame.memOff, frame.memLen = stackPeek(stack, 0).Uint64(), 1
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		frame.memOff, frame.memLen = stackPeek(stack, 0).Uint64(), stackPeek(stack, 2).Uint64()
+	case vm.EXTCODECOPY:
+		frame.memOff, frame.memLen = stackPeek(stack, 1).Uint64(), stackPeek(stack, 3).Uint64()
+	case vm.CALL, vm.CALLCODE:
+		frame.memOff, frame.memLen = stackPeek(stack, 5).Uint64(), stackPeek(stack, 6).Uint64()
+	case vm.DELEGATECALL, vm.STATICCALL:
+		frame.memOff, frame.memLen = stackPeek(stack, 4).Uint64(), stackPeek(stack, 5).Uint64()
+	case vm.SSTORE:
+		frame.store = &ParityStorageDiff{
+			Key: (*hexutil.Big)(stackPeek(stack, 0).ToBig()),
+			Val: (*hexutil.Big)(stackPeek(stack, 1).ToBig()),
+		}
+	}
+	return nil
+}
+
+// finalize fills in the effects of the pending operation of the frame, given the
+// gas remaining and the stack and memory state after its execution.
+func (f *parityVMFrame) finalize(gas uint64, stack *vm.Stack, memory *vm.Memory) {
+	ex := &ParityVMExecuted{Used: gas, Push: []*hexutil.Big{}, Store: f.store}
+	if stack != nil {
+		for i := f.pushes - 1; i >= 0; i-- {
+			ex.Push = append(ex.Push, (*hexutil.Big)(stackPeek(stack, i).ToBig()))
+		}
+	}
+	if memory != nil && f.memLen > 0 {
+		data := memorySlice(memory, uint256.NewInt().SetUint64(f.memOff), uint256.NewInt().SetUint64(f.memLen))
+		ex.Mem = &ParityMemoryDiff{Off: f.memOff, Data: data}
+	}
+	f.pending.Ex = ex
+	f.pending = nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *ParityTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *ParityTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) error {
+	t.exit(output, gasUsed, err)
+	if t.vmTrace {
+		t.exitVM(gasUsed, err)
+	}
+	return nil
+}
+
+// Traces returns the flattened call frames of the traced transaction.
+func (t *ParityTracer) Traces() []*ParityTrace {
+	return t.traces
+}
+
+// VMTrace returns the operation trace of the traced transaction, or nil if the
+// operations weren't traced.
+func (t *ParityTracer) VMTrace() *ParityVMTrace {
+	return t.vmRoot
+}
+
+// StateDiff compares the state before and after executing the traced transaction,
+// returning the changes of all the accounts touched by it. The post state needs
+// to be finalised, so destructed accounts are already deleted. Besides accounts
+// touched by the execution, the block's coinbase is also checked for fees.
+func (t *ParityTracer) StateDiff(pre, post vm.StateDB, coinbase common.Address) ParityStateDiff {
+	t.touch(coinbase)
+
+	diff := make(ParityStateDiff)
+	for addr, slots := range t.touched {
+		existed, exists := pre.Exist(addr), post.Exist(addr)
+		if !existed && !exists {
+			continue
+		}
+		var (
+			account = &ParityAccountDiff{Storage: make(map[common.Hash]interface{})}
+			changed = existed != exists
+		)
+		account.Balance, changed = parityDiff((*hexutil.Big)(pre.GetBalance(addr)), (*hexutil.Big)(post.GetBalance(addr)), existed, exists, pre.GetBalance(addr).Cmp(post.GetBalance(addr)) == 0, changed)
+		account.Nonce, changed = parityDiff(hexutil.Uint64(pre.GetNonce(addr)), hexutil.Uint64(post.GetNonce(addr)), existed, exists, pre.GetNonce(addr) == post.GetNonce(addr), changed)
+		account.Code, changed = parityDiff(hexutil.Bytes(pre.GetCode(addr)), hexutil.Bytes(post.GetCode(addr)), existed, exists, pre.GetCodeHash(addr) == post.GetCodeHash(addr), changed)
+
+		for slot := range slots {
+			var from, to common.Hash
+			if existed {
+				from = pre.GetState(addr, slot)
+			}
+			if exists {
+				to = post.GetState(addr, slot)
+			}
+			// Only report slots with values, and only changed ones of surviving accounts
+			if (existed && exists && from == to) || (!existed && to == common.Hash{}) || (!exists && from == common.Hash{}) {
+				continue
+			}
+			account.Storage[slot], _ = parityDiff(from, to, existed, exists, false, true)
+			changed = true
+		}
+		if changed {
+			diff[addr] = account
+		}
+	}
+	return diff
+}
+
+// parityDiff assembles the state diff representation of a single value, also
+// returning whether the value or any previous one in the account changed.
+func parityDiff(from, to interface{}, existed, exists, equal, changed bool) (interface{}, bool) {
+	switch {
+	case !existed:
+		return map[string]interface{}{"+": to}, true
+	case !exists:
+		return map[string]interface{}{"-": from}, true
+	case equal:
+		return "=", changed
+	default:
+		return map[string]interface{}{"*": map[string]interface{}{"from": from, "to": to}}, true
+	}
+}
+
+// parityError converts an EVM execution error into its OpenEthereum equivalent.
+func parityError(err error) string {
+	var (
+		invalidOp *vm.ErrInvalidOpCode
+		underflow *vm.ErrStackUnderflow
+		overflow  *vm.ErrStackOverflow
+	)
+	switch {
+	case errors.Is(err, vm.ErrExecutionReverted):
+		return "Reverted"
+	case errors.Is(err, vm.ErrOutOfGas), errors.Is(err, vm.ErrCodeStoreOutOfGas):
+		return "Out of gas"
+	case errors.Is(err, vm.ErrInvalidJump):
+		return "Bad jump destination"
+	case errors.Is(err, vm.ErrWriteProtection):
+		return "Mutable Call In Static Context"
+	case errors.As(err, &invalidOp):
+		return "Bad instruction"
+	case errors.As(err, &underflow):
+		return "Stack underflow"
+	case errors.As(err, &overflow):
+		return "Out of stack"
+	default:
+		return err.Error()
+	}
+}
+
+// parityStackPushes returns the number of stack items reported as pushed by an
+// operation. Duplications and swaps report all the items they touched.
+func parityStackPushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.PUSH1 && op <= vm.PUSH32:
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.CALLDATACOPY, vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY, vm.POP,
+		vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.JUMP, vm.JUMPI, vm.JUMPDEST, vm.BEGINSUB, vm.JUMPSUB,
+		vm.RETURNSUB, vm.RETURN, vm.REVERT, vm.SELFDESTRUCT:
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,345 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+func init() {
+	RegisterNative("gasProfiler", func(txCtx vm.TxContext) TxTracer { return NewGasProfiler() })
+}
+
+// precompileOp is the pseudo opcode the gas of precompiled contracts is
+// attributed to, as they don't execute any real opcodes.
+const precompileOp = "PRECOMPILE"
+
+// ProfileFrame is the aggregated gas and time usage of all the executions of a
+// unique call stack.
+type ProfileFrame struct {
+	Stack   string `json:"stack"`   // Call stack in collapsed format, outermost first
+	Calls   uint64 `json:"calls"`   // Number of times the stack was entered
+	Gas     uint64 `json:"gas"`     // Gas used including nested calls
+	SelfGas uint64 `json:"selfGas"` // Gas used excluding nested calls
+	Time    int64  `json:"timeNs"`  // Wall time in nanoseconds including nested calls
+}
+
+// ProfileBucket is the aggregated gas and time usage of a single opcode within
+// a single function of a single contract.
+type ProfileBucket struct {
+	Contract common.Address `json:"contract"`
+	Selector string         `json:"selector"`
+	Op       string         `json:"op"`
+	Count    uint64         `json:"count"`  // Number of times the opcode was executed
+	Gas      uint64         `json:"gas"`    // Gas used by the opcode, excluding nested calls
+	Time     int64          `json:"timeNs"` // Wall time in nanoseconds, excluding nested calls
+}
+
+// GasProfile is the summary of a profiled execution.
+type GasProfile struct {
+	Gas     uint64           `json:"gas"`    // Execution gas used, excluding the intrinsic gas
+	Time    int64            `json:"timeNs"` // Wall time of the execution in nanoseconds
+	Frames  []*ProfileFrame  `json:"frames"`
+	Buckets []*ProfileBucket `json:"buckets"`
+	Folded  string           `json:"folded"` // Gas usage in collapsed stack format
+}
+
+// profileKey identifies an opcode within a function of a contract.
+type profileKey struct {
+	contract common.Address
+	selector string
+	op       string
+}
+
+// profileFrame is a call frame currently being executed.
+type profileFrame struct {
+	stack    string         // Collapsed call stack leading to and including this frame
+	contract common.Address // Contract whose code is executing
+	selector string         // Function selector the frame was entered with
+	start    time.Time      // Time the frame was entered
+
+	ownGas     uint64        // Gas attributed to the opcodes of this frame so far
+	childGas   uint64        // Gas used by nested calls of the pending opcode
+	childTotal uint64        // Gas used by all nested calls of this frame
+	childTime  time.Duration // Time spent in nested calls of the pending opcode
+
+	pending    bool      // Whether there's an opcode awaiting its cost
+	pendingOp  vm.OpCode // Opcode awaiting its cost
+	pendingGas uint64    // Gas available before the pending opcode
+	pendingAt  time.Time // Time the pending opcode started executing
+}
+
+// GasProfiler is a tracer attributing the gas and wall time used by a transaction
+// to the call frames and the (contract, function selector, opcode) buckets they
+// were spent in.
+//
+// The cost of an opcode is measured as the gas and time elapsed until the next
+// opcode of the same frame, less anything used by the calls it made. This way,
+// the dynamic cost of calls and creations is accounted for correctly and all the
+// opcodes and precompiles add up to the total execution gas.
+type GasProfiler struct {
+	callstack  []*profileFrame
+	frames     map[string]*ProfileFrame
+	buckets    map[profileKey]*ProfileBucket
+	foldedGas  map[string]uint64
+	foldedTime map[string]int64
+
+	gasUsed uint64        // Execution gas used by the top level call
+	elapsed time.Duration // Duration of the top level call
+	reason  error         // Textual reason for the interruption
+	stop    uint32        // Atomic flag to signal execution interruption
+}
+
+// NewGasProfiler creates a gas profiling tracer.
+func NewGasProfiler() *GasProfiler {
+	return &GasProfiler{
+		frames:     make(map[string]*ProfileFrame),
+		buckets:    make(map[profileKey]*ProfileBucket),
+		foldedGas:  make(map[string]uint64),
+		foldedTime: make(map[string]int64),
+	}
+}
+
+// Stop terminates the profiling at the first opportune moment.
+func (p *GasProfiler) Stop(err error) {
+	p.reason = err
+	atomic.StoreUint32(&p.stop, 1)
+}
+
+// CaptureStart implements the Tracer interface to open the top level frame.
+func (p *GasProfiler) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	p.enter(to, create, input)
+	return nil
+}
+
+// CaptureState implements the Tracer interface, settling the cost of the previous
+// opcode of the current frame and starting the measurement of the next one.
+func (p *GasProfiler) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	if atomic.LoadUint32(&p.stop) > 0 || len(p.callstack) == 0 {
+		return nil
+	}
+	now := time.Now()
+
+	frame := p.callstack[len(p.callstack)-1]
+	if frame.pending {
+		var used uint64
+		if frame.pendingGas > gas+frame.childGas {
+			used = frame.pendingGas - gas - frame.childGas
+		}
+		p.attribute(frame, frame.pendingOp.String(), used, now.Sub(frame.pendingAt)-frame.childTime)
+	}
+	frame.pending, frame.pendingOp, frame.pendingGas, frame.pendingAt = true, op, gas, now
+	frame.childGas, frame.childTime = 0, 0
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface to open a nested frame.
+func (p *GasProfiler) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	if atomic.LoadUint32(&p.stop) > 0 {
+		return nil
+	}
+	p.enter(to, typ == vm.CREATE || typ == vm.CREATE2, input)
+	return nil
+}
+
+// CaptureExit implements the Tracer interface to close a nested frame.
+func (p *GasProfiler) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	if atomic.LoadUint32(&p.stop) > 0 {
+		return nil
+	}
+	p.exit(gasUsed, time.Now())
+	return nil
+}
+
+// CaptureFault implements the Tracer interface. The faulting opcode is charged
+// with the remaining gas of its frame when the frame is exited.
+func (p *GasProfiler) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements the Tracer interface to close the top level frame.
+func (p *GasProfiler) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	p.gasUsed, p.elapsed = gasUsed, t
+	if atomic.LoadUint32(&p.stop) > 0 || len(p.callstack) == 0 {
+		return nil
+	}
+	p.exit(gasUsed, time.Now())
+	return nil
+}
+
+// enter pushes a new frame executing the code of the given contract.
+func (p *GasProfiler) enter(contract common.Address, create bool, input []byte) {
+	selector := "fallback"
+	switch {
+	case create:
+		selector = "constructor"
+	case len(input) >= 4:
+		selector = fmt.Sprintf("%#x", input[:4])
+	}
+	stack := fmt.Sprintf("%s:%s", contract.Hex(), selector)
+	if len(p.callstack) > 0 {
+		stack = p.callstack[len(p.callstack)-1].stack + ";" + stack
+	}
+	p.callstack = append(p.callstack, &profileFrame{
+		stack:    stack,
+		contract: contract,
+		selector: selector,
+		start:    time.Now(),
+	})
+}
+
+// exit pops the current frame, charging its last opcode with whatever gas of the
+// frame is not accounted for yet.
+func (p *GasProfiler) exit(gasUsed uint64, now time.Time) {
+	frame := p.callstack[len(p.callstack)-1]
+	p.callstack = p.callstack[:len(p.callstack)-1]
+
+	var rest uint64
+	if gasUsed > frame.ownGas+frame.childTotal {
+		rest = gasUsed - frame.ownGas - frame.childTotal
+	}
+	switch {
+	case frame.pending:
+		p.attribute(frame, frame.pendingOp.String(), rest, now.Sub(frame.pendingAt)-frame.childTime)
+	case rest > 0:
+		// No opcodes executed but gas was used, must have been a precompile
+		p.attribute(frame, precompileOp, rest, now.Sub(frame.start))
+	}
+	elapsed := now.Sub(frame.start)
+
+	summary, ok := p.frames[frame.stack]
+	if !ok {
+		summary = &ProfileFrame{Stack: frame.stack}
+		p.frames[frame.stack] = summary
+	}
+	summary.Calls++
+	summary.Gas += gasUsed
+	summary.SelfGas += gasUsed - frame.childTotal
+	summary.Time += int64(elapsed)
+
+	if len(p.callstack) > 0 {
+		parent := p.callstack[len(p.callstack)-1]
+		parent.childGas += gasUsed
+		parent.childTotal += gasUsed
+		parent.childTime += elapsed
+	}
+}
+
+// attribute charges the given gas and time to an opcode executed in a frame.
+func (p *GasProfiler) attribute(frame *profileFrame, op string, gas uint64, elapsed time.Duration) {
+	frame.ownGas += gas
+
+	key := profileKey{contract: frame.contract, selector: frame.selector, op: op}
+	bucket, ok := p.buckets[key]
+	if !ok {
+		bucket = &ProfileBucket{Contract: frame.contract, Selector: frame.selector, Op: op}
+		p.buckets[key] = bucket
+	}
+	bucket.Count++
+	bucket.Gas += gas
+	bucket.Time += int64(elapsed)
+
+	stack := frame.stack + ";" + op
+	p.foldedGas[stack] += gas
+	p.foldedTime[stack] += int64(elapsed)
+}
+
+// Profile assembles the summary of the profiled execution. Frames are ordered by
+// their call stack, buckets by decreasing gas usage.
+func (p *GasProfiler) Profile() *GasProfile {
+	profile := &GasProfile{
+		Gas:     p.gasUsed,
+		Time:    int64(p.elapsed),
+		Frames:  make([]*ProfileFrame, 0, len(p.frames)),
+		Buckets: make([]*ProfileBucket, 0, len(p.buckets)),
+	}
+	for _, frame := range p.frames {
+		profile.Frames = append(profile.Frames, frame)
+	}
+	sort.Slice(profile.Frames, func(i, j int) bool {
+		return profile.Frames[i].Stack < profile.Frames[j].Stack
+	})
+	for _, bucket := range p.buckets {
+		profile.Buckets = append(profile.Buckets, bucket)
+	}
+	sort.Slice(profile.Buckets, func(i, j int) bool {
+		a, b := profile.Buckets[i], profile.Buckets[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		if a.Contract != b.Contract {
+			return a.Contract.Hex() < b.Contract.Hex()
+		}
+		if a.Selector != b.Selector {
+			return a.Selector < b.Selector
+		}
+		return a.Op < b.Op
+	})
+	var folded strings.Builder
+	p.WriteFolded(&folded, false)
+	profile.Folded = folded.String()
+
+	return profile
+}
+
+// WriteFolded writes the gas usage, or the wall time usage in nanoseconds of the
+// profiled execution in collapsed stack format, as consumed by flamegraph tools.
+func (p *GasProfiler) WriteFolded(w io.Writer, wallTime bool) error {
+	var stacks []string
+	if wallTime {
+		for stack, elapsed := range p.foldedTime {
+			if elapsed > 0 {
+				stacks = append(stacks, fmt.Sprintf("%s %d", stack, elapsed))
+			}
+		}
+	} else {
+		for stack, gas := range p.foldedGas {
+			if gas > 0 {
+				stacks = append(stacks, fmt.Sprintf("%s %d", stack, gas))
+			}
+		}
+	}
+	sort.Strings(stacks)
+
+	out := bufio.NewWriter(w)
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintln(out, stack); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// GetResult returns the profile of the traced transaction, or the reason the
+// profiling was interrupted with.
+func (p *GasProfiler) GetResult() (json.RawMessage, error) {
+	if atomic.LoadUint32(&p.stop) > 0 {
+		return nil, p.reason
+	}
+	return json.Marshal(p.Profile())
+}
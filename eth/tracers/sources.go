@@ -0,0 +1,224 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SourceArtifact is the compiler output of a contract needed to map its executed
+// instructions back to its sources.
+type SourceArtifact struct {
+	Name      string            `json:"name"`
+	Code      hexutil.Bytes     `json:"code"`      // Runtime bytecode as emitted by the compiler
+	SourceMap string            `json:"sourceMap"` // Runtime source map as emitted by the compiler
+	Sources   []compiler.Source `json:"sources"`   // Sources in the order of the compiler's source list
+	Addresses []common.Address  `json:"addresses"` // Deployments whose code differs from the compiler's (immutables, linking)
+}
+
+// SourceRegistry is a collection of compiler artifacts that program counters of
+// executing contracts can be resolved against. Contracts are matched by the hash
+// of their code, or failing that, by the address of their code.
+type SourceRegistry struct {
+	byHash map[common.Hash]*compiler.SourceMapper
+	byAddr map[common.Address]*compiler.SourceMapper
+	lock   sync.RWMutex
+}
+
+// NewSourceRegistry creates an empty source registry.
+func NewSourceRegistry() *SourceRegistry {
+	return &SourceRegistry{
+		byHash: make(map[common.Hash]*compiler.SourceMapper),
+		byAddr: make(map[common.Address]*compiler.SourceMapper),
+	}
+}
+
+// Register adds a compiler artifact to the registry, replacing any previous one
+// with the same code or deployment addresses.
+func (r *SourceRegistry) Register(artifact *SourceArtifact) error {
+	if len(artifact.Code) == 0 {
+		return errors.New("artifact missing code")
+	}
+	if artifact.SourceMap == "" {
+		return errors.New("artifact missing source map")
+	}
+	mapper, err := compiler.NewSourceMapper(artifact.Code, artifact.SourceMap, artifact.Sources)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.byHash[crypto.Keccak256Hash(artifact.Code)] = mapper
+	for _, addr := range artifact.Addresses {
+		r.byAddr[addr] = mapper
+	}
+	return nil
+}
+
+// RegisterCombinedJSON adds all the contracts of a solc --combined-json output to
+// the registry. The sources are read from the paths in the compiler's source
+// list, relative to the given base directory.
+func (r *SourceRegistry) RegisterCombinedJSON(combinedJSON []byte, basedir string) error {
+	contracts, err := compiler.ParseCombinedJSON(combinedJSON, "", "", "", "")
+	if err != nil {
+		return err
+	}
+	sources := make(map[string]compiler.Source)
+	for name, contract := range contracts {
+		if contract.Info.SrcMapRuntime == "" || len(contract.RuntimeCode) <= 2 {
+			continue // Abstract contracts and interfaces
+		}
+		artifact := &SourceArtifact{
+			Name:      name,
+			Code:      common.FromHex(contract.RuntimeCode),
+			SourceMap: contract.Info.SrcMapRuntime,
+		}
+		for _, path := range contract.Info.SourceList {
+			src, ok := sources[path]
+			if !ok {
+				full := path
+				if !filepath.IsAbs(full) {
+					full = filepath.Join(basedir, path)
+				}
+				content, err := ioutil.ReadFile(full)
+				if err != nil {
+					return err
+				}
+				src = compiler.Source{Name: path, Content: string(content)}
+				sources[path] = src
+			}
+			artifact.Sources = append(artifact.Sources, src)
+		}
+		if err := r.Register(artifact); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Locate resolves a program counter of the given contract into a source location,
+// or nil if there is no artifact registered for the contract's code.
+func (r *SourceRegistry) Locate(contract *vm.Contract, pc uint64) *compiler.SourceLocation {
+	return r.LocateCode(contract.CodeHash, contract.CodeAddr, pc)
+}
+
+// LocateCode resolves a program counter of the code with the given hash, deployed
+// at the given address if known, into a source location.
+func (r *SourceRegistry) LocateCode(codeHash common.Hash, codeAddr *common.Address, pc uint64) *compiler.SourceLocation {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	mapper, ok := r.byHash[codeHash]
+	if !ok && codeAddr != nil {
+		mapper, ok = r.byAddr[*codeAddr]
+	}
+	if !ok {
+		return nil
+	}
+	return mapper.Locate(pc)
+}
+
+// SourceTracer is a tracer wrapping another one, resolving the source location
+// of every step the wrapped tracer captured and of every revert or fault.
+type SourceTracer struct {
+	tracer   vm.Tracer // Wrapped tracer to forward all events to, may be nil
+	registry *SourceRegistry
+
+	Steps   []*compiler.SourceLocation // Location of each step captured by the wrapped tracer
+	Reverts []*compiler.SourceLocation // Location of each revert and fault, innermost first
+}
+
+// NewSourceTracer wraps a tracer to resolve the executed steps against the
+// given source registry.
+func NewSourceTracer(tracer vm.Tracer, registry *SourceRegistry) *SourceTracer {
+	return &SourceTracer{tracer: tracer, registry: registry}
+}
+
+// CaptureStart implements the Tracer interface, forwarding to the wrapped tracer.
+func (t *SourceTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	if t.tracer == nil {
+		return nil
+	}
+	return t.tracer.CaptureStart(from, to, create, input, gas, value)
+}
+
+// CaptureState implements the Tracer interface, resolving the location of each
+// step the wrapped tracer accepts.
+func (t *SourceTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, rData []byte, contract *vm.Contract, depth int, err error) error {
+	// Reverts and faults after the step are reported by CaptureFault, only the
+	// ones aborting before it (e.g. stack underflows) are reported here.
+	loc := t.registry.Locate(contract, pc)
+	if err != nil && loc != nil {
+		t.Reverts = append(t.Reverts, loc)
+	}
+	if t.tracer == nil {
+		return nil
+	}
+	if err := t.tracer.CaptureState(env, pc, op, gas, cost, memory, stack, rStack, rData, contract, depth, err); err != nil {
+		return err
+	}
+	t.Steps = append(t.Steps, loc)
+	return nil
+}
+
+// CaptureEnter implements the Tracer interface, forwarding to the wrapped tracer.
+func (t *SourceTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	if t.tracer == nil {
+		return nil
+	}
+	return t.tracer.CaptureEnter(typ, from, to, input, gas, value)
+}
+
+// CaptureExit implements the Tracer interface, forwarding to the wrapped tracer.
+func (t *SourceTracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	if t.tracer == nil {
+		return nil
+	}
+	return t.tracer.CaptureExit(output, gasUsed, err)
+}
+
+// CaptureFault implements the Tracer interface, resolving the location of the
+// faulting step.
+func (t *SourceTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
+	if loc := t.registry.Locate(contract, pc); loc != nil {
+		t.Reverts = append(t.Reverts, loc)
+	}
+	if t.tracer == nil {
+		return nil
+	}
+	return t.tracer.CaptureFault(env, pc, op, gas, cost, memory, stack, rStack, contract, depth, err)
+}
+
+// CaptureEnd implements the Tracer interface, forwarding to the wrapped tracer.
+func (t *SourceTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	if t.tracer == nil {
+		return nil
+	}
+	return t.tracer.CaptureEnd(output, gasUsed, elapsed, err)
+}
@@ -284,6 +284,95 @@ func (cw *contractWrapper) pushObject(vm *duktape.Context) {
 	vm.PutPropString(obj, "getInput")
 }
 
+// frame represents a nested call frame entered by the EVM, exposed to the
+// JavaScript enter function.
+type frame struct {
+	typ   string
+	from  common.Address
+	to    common.Address
+	input []byte
+	gas   uint
+	value *big.Int
+}
+
+// pushObject assembles a JSVM object wrapping a swappable call frame and pushes
+// it onto the VM stack.
+func (f *frame) pushObject(vm *duktape.Context) {
+	obj := vm.PushObject()
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int { ctx.PushString(f.typ); return 1 })
+	vm.PutPropString(obj, "getType")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(20)
+		copy(makeSlice(ptr, 20), f.from[:])
+		return 1
+	})
+	vm.PutPropString(obj, "getFrom")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(20)
+		copy(makeSlice(ptr, 20), f.to[:])
+		return 1
+	})
+	vm.PutPropString(obj, "getTo")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(len(f.input))
+		copy(makeSlice(ptr, uint(len(f.input))), f.input)
+		return 1
+	})
+	vm.PutPropString(obj, "getInput")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int { ctx.PushUint(f.gas); return 1 })
+	vm.PutPropString(obj, "getGas")
+
+	// Value is undefined for frames not transferring any (DELEGATECALL, STATICCALL)
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		if f.value != nil {
+			pushBigInt(f.value, ctx)
+		} else {
+			ctx.PushUndefined()
+		}
+		return 1
+	})
+	vm.PutPropString(obj, "getValue")
+}
+
+// frameResult represents the outcome of a nested call frame left by the EVM,
+// exposed to the JavaScript exit function.
+type frameResult struct {
+	gasUsed    uint
+	output     []byte
+	errorValue *string
+}
+
+// pushObject assembles a JSVM object wrapping a swappable call frame result and
+// pushes it onto the VM stack.
+func (r *frameResult) pushObject(vm *duktape.Context) {
+	obj := vm.PushObject()
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int { ctx.PushUint(r.gasUsed); return 1 })
+	vm.PutPropString(obj, "getGasUsed")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		ptr := ctx.PushFixedBuffer(len(r.output))
+		copy(makeSlice(ptr, uint(len(r.output))), r.output)
+		return 1
+	})
+	vm.PutPropString(obj, "getOutput")
+
+	vm.PushGoFunction(func(ctx *duktape.Context) int {
+		if r.errorValue != nil {
+			ctx.PushString(*r.errorValue)
+		} else {
+			ctx.PushUndefined()
+		}
+		return 1
+	})
+	vm.PutPropString(obj, "getError")
+}
+
 // Tracer provides an implementation of Tracer that evaluates a Javascript
 // function for each VM execution step.
 type Tracer struct {
@@ -299,6 +388,10 @@ type Tracer struct {
 	memoryWrapper   *memoryWrapper   // Wrapper around the VM memory
 	contractWrapper *contractWrapper // Wrapper around the contract object
 	dbWrapper       *dbWrapper       // Wrapper around the VM environment
+	frame           *frame           // Wrapper around the entered call frame
+	frameResult     *frameResult     // Wrapper around the results of the left call frame
+
+	traceCallFrames bool // Whether the tracer exposes the optional enter/exit functions
 
 	pcValue     *uint   // Swappable pc value wrapped by a log accessor
 	gasValue    *uint   // Swappable gas value wrapped by a log accessor
@@ -316,7 +409,8 @@ type Tracer struct {
 
 // New instantiates a new tracer instance. code specifies a Javascript snippet,
 // which must evaluate to an expression returning an object with 'step', 'fault'
-// and 'result' functions.
+// and 'result' functions, and optionally 'enter' and 'exit' functions to be
+// notified of nested call frames.
 func New(code string, txCtx vm.TxContext) (*Tracer, error) {
 	// Resolve any tracers by name and assemble the tracer object
 	if tracer, ok := tracer(code); ok {
@@ -330,6 +424,8 @@ func New(code string, txCtx vm.TxContext) (*Tracer, error) {
 		memoryWrapper:   new(memoryWrapper),
 		contractWrapper: new(contractWrapper),
 		dbWrapper:       new(dbWrapper),
+		frame:           new(frame),
+		frameResult:     new(frameResult),
 		pcValue:         new(uint),
 		gasValue:        new(uint),
 		costValue:       new(uint),
@@ -445,6 +541,17 @@ func New(code string, txCtx vm.TxContext) (*Tracer, error) {
 	}
 	tracer.vm.Pop()
 
+	// The call frame hooks are optional, but only make sense together
+	hasEnter := tracer.vm.GetPropString(tracer.tracerObject, "enter")
+	tracer.vm.Pop()
+	hasExit := tracer.vm.GetPropString(tracer.tracerObject, "exit")
+	tracer.vm.Pop()
+
+	if hasEnter != hasExit {
+		return nil, fmt.Errorf("trace object must expose either both or none of enter() and exit()")
+	}
+	tracer.traceCallFrames = hasEnter
+
 	// Tracer is valid, inject the big int library to access large numbers
 	tracer.vm.EvalString(bigIntegerJS)
 	tracer.vm.PutGlobalString("bigInt")
@@ -496,6 +603,12 @@ func New(code string, txCtx vm.TxContext) (*Tracer, error) {
 	tracer.dbWrapper.pushObject(tracer.vm)
 	tracer.vm.PutPropString(tracer.stateObject, "db")
 
+	tracer.frame.pushObject(tracer.vm)
+	tracer.vm.PutPropString(tracer.stateObject, "frame")
+
+	tracer.frameResult.pushObject(tracer.vm)
+	tracer.vm.PutPropString(tracer.stateObject, "frameResult")
+
 	return tracer, nil
 }
 
@@ -593,6 +706,50 @@ func (jst *Tracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost
 	return nil
 }
 
+// CaptureEnter is called when the EVM enters a nested call frame, invoking the
+// JavaScript enter function if the tracer has one.
+func (jst *Tracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) error {
+	if !jst.traceCallFrames || jst.err != nil {
+		return nil
+	}
+	// If tracing was interrupted, set the error and stop
+	if atomic.LoadUint32(&jst.interrupt) > 0 {
+		jst.err = jst.reason
+		return nil
+	}
+	*jst.frame = frame{
+		typ:   typ.String(),
+		from:  from,
+		to:    to,
+		input: input,
+		gas:   uint(gas),
+		value: value,
+	}
+	if _, err := jst.call("enter", "frame"); err != nil {
+		jst.err = wrapError("enter", err)
+	}
+	return nil
+}
+
+// CaptureExit is called when the EVM leaves a nested call frame, invoking the
+// JavaScript exit function if the tracer has one.
+func (jst *Tracer) CaptureExit(output []byte, gasUsed uint64, err error) error {
+	if !jst.traceCallFrames || jst.err != nil {
+		return nil
+	}
+	jst.frameResult.gasUsed = uint(gasUsed)
+	jst.frameResult.output = output
+	jst.frameResult.errorValue = nil
+	if err != nil {
+		jst.frameResult.errorValue = new(string)
+		*jst.frameResult.errorValue = err.Error()
+	}
+	if _, err := jst.call("exit", "frameResult"); err != nil {
+		jst.err = wrapError("exit", err)
+	}
+	return nil
+}
+
 // CaptureFault implements the Tracer interface to trace an execution fault
 // while running an opcode.
 func (jst *Tracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, rStack *vm.ReturnStack, contract *vm.Contract, depth int, err error) error {
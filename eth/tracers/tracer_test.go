@@ -19,13 +19,17 @@ package tracers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -161,3 +165,47 @@ func TestHaltBetweenSteps(t *testing.T) {
 		t.Errorf("Expected timeout error, got %v", err)
 	}
 }
+
+func TestEnterExit(t *testing.T) {
+	// Tracers need to expose either both or none of the call frame hooks
+	if _, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }, enter: function() {}}", vm.TxContext{}); err == nil {
+		t.Fatal("tracer creation should've failed without exit() method")
+	}
+	if _, err := New("{step: function() {}, fault: function() {}, result: function() { return null; }, enter: function() {}, exit: function() {}}", vm.TxContext{}); err != nil {
+		t.Fatal(err)
+	}
+	// Trace a contract calling into a reverting one and check the reported frames
+	tracer, err := New(`{frames: [], step: function() {}, fault: function() {}, result: function() { return this.frames; },
+		enter: function(frame) { this.frames.push(frame.getType() + " " + toHex(frame.getFrom()) + " " + toHex(frame.getTo()) + " " + frame.getValue() + " " + toHex(frame.getInput())); },
+		exit: function(res) { this.frames.push(res.getGasUsed() + " " + toHex(res.getOutput()) + " " + res.getError()); }}`, vm.TxContext{GasPrice: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		caller = common.BytesToAddress([]byte("contract"))
+		callee = common.HexToAddress("0xbb")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.AddBalance(common.Address{}, big.NewInt(7))
+	statedb.SetCode(callee, []byte{byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.REVERT)})
+
+	code := []byte{
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, // out size, out offset, in size, in offset
+		byte(vm.PUSH1), 0x07, byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP),
+	}
+	if _, _, err := runtime.Execute(code, nil, &runtime.Config{
+		State:     statedb,
+		Value:     big.NewInt(7),
+		EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf(`["CALL %s %s 7 0x","6 0x execution reverted"]`, strings.ToLower(caller.Hex()), strings.ToLower(callee.Hex()))
+	if string(res) != want {
+		t.Errorf("frames mismatch: have %s, want %s", res, want)
+	}
+}
@@ -17,9 +17,11 @@
 package tracers
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"math/big"
 	"path/filepath"
@@ -28,12 +30,15 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -204,6 +209,66 @@ func TestPrestateTracerCreate2(t *testing.T) {
 // Iterates over all the input-output datasets in the tracer test harness and
 // runs the JavaScript tracers against them.
 func TestCallTracer(t *testing.T) {
+	testCallTracer(t, func(txCtx vm.TxContext) (TxTracer, error) {
+		return New("callTracer", txCtx)
+	})
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// runs the native call tracer against them.
+func TestNativeCallTracer(t *testing.T) {
+	testCallTracer(t, func(txCtx vm.TxContext) (TxTracer, error) {
+		return natives["callTracer"](txCtx), nil
+	})
+}
+
+func testCallTracer(t *testing.T, newTracer func(txCtx vm.TxContext) (TxTracer, error)) {
+	forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+		res, err := runCallTracerTest(test, newTracer)
+		if err != nil {
+			t.Fatalf("failed to trace transaction: %v", err)
+		}
+		ret := new(callTrace)
+		if err := json.Unmarshal(res, ret); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+
+		if !jsonEqual(ret, test.Result) {
+			// uncomment this for easier debugging
+			//have, _ := json.MarshalIndent(ret, "", " ")
+			//want, _ := json.MarshalIndent(test.Result, "", " ")
+			//t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", string(have), string(want))
+			t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
+		}
+	})
+}
+
+// Iterates over all the datasets in the tracer test harness and checks that the
+// native prestate tracer produces byte for byte the same output as the JavaScript
+// one.
+func TestNativePrestateTracer(t *testing.T) {
+	forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+		want, err := runCallTracerTest(test, func(txCtx vm.TxContext) (TxTracer, error) {
+			return New("prestateTracer", txCtx)
+		})
+		if err != nil {
+			t.Fatalf("failed to trace transaction with JavaScript tracer: %v", err)
+		}
+		have, err := runCallTracerTest(test, func(txCtx vm.TxContext) (TxTracer, error) {
+			return natives["prestateTracer"](txCtx), nil
+		})
+		if err != nil {
+			t.Fatalf("failed to trace transaction with native tracer: %v", err)
+		}
+		if !bytes.Equal(have, want) {
+			t.Fatalf("prestate mismatch: \nhave %s\nwant %s", have, want)
+		}
+	})
+}
+
+// forEachCallTracerTest loads all the call tracer datasets in the tracer test
+// harness and runs the given test function against each of them.
+func forEachCallTracerTest(t *testing.T, fn func(t *testing.T, test *callTracerTest)) {
 	files, err := ioutil.ReadDir("testdata")
 	if err != nil {
 		t.Fatalf("failed to retrieve tracer test suite: %v", err)
@@ -225,62 +290,53 @@ func TestCallTracer(t *testing.T) {
 			if err := json.Unmarshal(blob, test); err != nil {
 				t.Fatalf("failed to parse testcase: %v", err)
 			}
-			// Configure a blockchain with the given prestate
-			tx := new(types.Transaction)
-			if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
-				t.Fatalf("failed to parse testcase input: %v", err)
-			}
-			signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
-			origin, _ := signer.Sender(tx)
-			txContext := vm.TxContext{
-				Origin:   origin,
-				GasPrice: tx.GasPrice(),
-			}
-			context := vm.BlockContext{
-				CanTransfer: core.CanTransfer,
-				Transfer:    core.Transfer,
-				Coinbase:    test.Context.Miner,
-				BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
-				Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
-				Difficulty:  (*big.Int)(test.Context.Difficulty),
-				GasLimit:    uint64(test.Context.GasLimit),
-			}
-			_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
+			fn(t, test)
+		})
+	}
+}
 
-			// Create the tracer, the EVM environment and run it
-			tracer, err := New("callTracer", txContext)
-			if err != nil {
-				t.Fatalf("failed to create call tracer: %v", err)
-			}
-			evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+// runCallTracerTest executes the transaction of a tracer test dataset on top of
+// its prestate with the given tracer, returning the trace result.
+func runCallTracerTest(test *callTracerTest, newTracer func(txCtx vm.TxContext) (TxTracer, error)) (json.RawMessage, error) {
+	// Configure a blockchain with the given prestate
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		return nil, fmt.Errorf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+	txContext := vm.TxContext{
+		Origin:   origin,
+		GasPrice: tx.GasPrice(),
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc, false)
 
-			msg, err := tx.AsMessage(signer)
-			if err != nil {
-				t.Fatalf("failed to prepare transaction for tracing: %v", err)
-			}
-			st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-			if _, err = st.TransitionDb(); err != nil {
-				t.Fatalf("failed to execute transaction: %v", err)
-			}
-			// Retrieve the trace result and compare against the etalon
-			res, err := tracer.GetResult()
-			if err != nil {
-				t.Fatalf("failed to retrieve trace result: %v", err)
-			}
-			ret := new(callTrace)
-			if err := json.Unmarshal(res, ret); err != nil {
-				t.Fatalf("failed to unmarshal trace result: %v", err)
-			}
+	// Create the tracer, the EVM environment and run it
+	tracer, err := newTracer(txContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracer: %v", err)
+	}
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
 
-			if !jsonEqual(ret, test.Result) {
-				// uncomment this for easier debugging
-				//have, _ := json.MarshalIndent(ret, "", " ")
-				//want, _ := json.MarshalIndent(test.Result, "", " ")
-				//t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", string(have), string(want))
-				t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
-			}
-		})
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare transaction for tracing: %v", err)
 	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, err = st.TransitionDb(); err != nil {
+		return nil, fmt.Errorf("failed to execute transaction: %v", err)
+	}
+	// Retrieve the trace result
+	return tracer.GetResult()
 }
 
 // jsonEqual is similar to reflect.DeepEqual, but does a 'bounce' via json prior to
@@ -300,3 +356,327 @@ func jsonEqual(x, y interface{}) bool {
 	}
 	return reflect.DeepEqual(xTrace, yTrace)
 }
+
+// parityTxTracer wraps a Parity tracer to return its flat call traces as the
+// result of the trace.
+type parityTxTracer struct {
+	*ParityTracer
+}
+
+func (t *parityTxTracer) GetResult() (json.RawMessage, error) { return json.Marshal(t.Traces()) }
+func (t *parityTxTracer) Stop(err error)                      {}
+
+// Iterates over all the datasets in the tracer test harness and checks that the
+// flat Parity traces describe the same call trees as the callTracer results.
+func TestParityTracer(t *testing.T) {
+	forEachCallTracerTest(t, func(t *testing.T, test *callTracerTest) {
+		res, err := runCallTracerTest(test, func(txCtx vm.TxContext) (TxTracer, error) {
+			return &parityTxTracer{NewParityTracer(false)}, nil
+		})
+		if err != nil {
+			t.Fatalf("failed to trace transaction: %v", err)
+		}
+		var traces []*ParityTrace
+		if err := json.Unmarshal(res, &traces); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		// Failed frames should omit the result field altogether
+		var fields []map[string]json.RawMessage
+		if err := json.Unmarshal(res, &fields); err != nil {
+			t.Fatalf("failed to unmarshal trace fields: %v", err)
+		}
+		for i, trace := range traces {
+			if _, ok := fields[i]["result"]; ok == (trace.Error != "") {
+				t.Errorf("trace %v: result presence mismatch: have %v, error %q", trace.TraceAddress, ok, trace.Error)
+			}
+		}
+		var have, want []string
+		for _, trace := range traces {
+			have = append(have, summarizeParityTrace(trace))
+		}
+		summarizeCallTrace(test.Result, []int{}, &want)
+
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("trace mismatch: \nhave %s\nwant %s", strings.Join(have, "\n     "), strings.Join(want, "\n     "))
+		}
+	})
+}
+
+// summarizeParityTrace formats the fields of a Parity trace which are comparable
+// with the callTracer output.
+func summarizeParityTrace(trace *ParityTrace) string {
+	var (
+		action = trace.Action
+		typ    = trace.Type
+		from   common.Address
+		to     common.Address
+		input  []byte
+		value  *hexutil.Big
+	)
+	switch trace.Type {
+	case "call":
+		typ, from, to, input = action.CallType, *action.From, *action.To, *action.Input
+		if typ == "call" || typ == "callcode" {
+			value = action.Value
+		}
+	case "create":
+		from, input, value = *action.From, *action.Init, action.Value
+		if trace.Result != nil {
+			to = *trace.Result.Address
+		}
+	case "suicide":
+		from, to, value = *action.Address, *action.RefundAddress, action.Balance
+	}
+	summary := fmt.Sprintf("%v %s %x->%x subtraces=%d input=%x value=%v failed=%v", trace.TraceAddress, typ, from, to, trace.Subtraces, input, value, trace.Error != "")
+	// The callTracer doesn't report the gas used by calls not executing any code
+	if len(trace.TraceAddress) > 0 && trace.Result != nil && trace.Result.GasUsed > 0 {
+		summary += fmt.Sprintf(" gasUsed=%d", trace.Result.GasUsed)
+	}
+	return summary
+}
+
+// summarizeCallTrace flattens a callTracer call tree, formatting each call the
+// same way as summarizeParityTrace does with Parity traces.
+func summarizeCallTrace(call *callTrace, address []int, summaries *[]string) {
+	var (
+		typ   = strings.ToLower(call.Type)
+		to    = call.To
+		value = call.Value
+	)
+	switch typ {
+	case "create2":
+		typ = "create"
+	case "selfdestruct":
+		typ = "suicide"
+	case "delegatecall", "staticcall":
+		value = nil
+	}
+	// Failed creations don't have a deployed contract
+	failed := call.Error != ""
+	if typ == "create" && failed {
+		to = common.Address{}
+	}
+	summary := fmt.Sprintf("%v %s %x->%x subtraces=%d input=%x value=%v failed=%v", address, typ, call.From, to, len(call.Calls), []byte(call.Input), value, failed)
+	if len(address) > 0 && typ != "suicide" && !failed && call.GasUsed != nil {
+		summary += fmt.Sprintf(" gasUsed=%d", *call.GasUsed)
+	}
+	*summaries = append(*summaries, summary)
+
+	for i := range call.Calls {
+		summarizeCallTrace(&call.Calls[i], append(append([]int{}, address...), i), summaries)
+	}
+}
+
+// Tests that the operation traces and the state diffs produced by the Parity
+// tracer contain the effects of the executed operations.
+func TestParityVMTraceAndStateDiff(t *testing.T) {
+	var (
+		contract = common.BytesToAddress([]byte("contract"))
+		callee   = common.HexToAddress("0xbb")
+		tracer   = NewParityTracer(true)
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.AddBalance(common.Address{}, big.NewInt(100))
+	statedb.SetCode(callee, []byte{byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.REVERT)})
+
+	// Call into the reverting callee with some value, then write into storage
+	code := []byte{
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0x07, byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.STOP),
+	}
+	statedb.SetCode(contract, code)
+	pre := statedb.Copy()
+
+	if _, _, err := runtime.Execute(code, nil, &runtime.Config{
+		State:     statedb,
+		GasLimit:  100000,
+		Value:     big.NewInt(10),
+		EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+	}); err != nil {
+		t.Fatalf("failed to execute code: %v", err)
+	}
+	statedb.Finalise(true)
+
+	// Check the operations and the effects of the interesting ones
+	trace := tracer.VMTrace()
+	if !bytes.Equal(trace.Code, code) || len(trace.Ops) != 13 {
+		t.Fatalf("operation trace mismatch: code %x, %d ops", trace.Code, len(trace.Ops))
+	}
+	for i, op := range trace.Ops {
+		if op.Ex == nil {
+			t.Fatalf("op %d: missing effects", i)
+		}
+	}
+	if call := trace.Ops[7]; call.Sub == nil || len(call.Sub.Ops) != 3 || len(call.Ex.Push) != 1 || call.Ex.Push[0].ToInt().Sign() != 0 {
+		t.Errorf("failed call mismatch: sub %+v, push %v", call.Sub, call.Ex.Push)
+	}
+	if store := trace.Ops[11].Ex.Store; store == nil || store.Key.ToInt().Sign() != 0 || store.Val.ToInt().Int64() != 1 {
+		t.Errorf("storage write mismatch: have %+v", store)
+	}
+	if last := trace.Ops[12]; last.Ex.Used+20000+3+3 > 100000 || len(last.Ex.Push) != 0 {
+		t.Errorf("final operation mismatch: used %d, push %v", last.Ex.Used, last.Ex.Push)
+	}
+	// Check the state diff, the callee reverted so it should be unchanged
+	diff, err := json.Marshal(tracer.StateDiff(pre, statedb, common.Address{}))
+	if err != nil {
+		t.Fatalf("failed to marshal state diff: %v", err)
+	}
+	want := `{"0x0000000000000000000000000000000000000000":{"balance":{"*":{"from":"0x64","to":"0x5a"}},"code":"=","nonce":"=","storage":{}},` +
+		`"0x000000000000000000000000636f6e7472616374":{"balance":{"*":{"from":"0x0","to":"0xa"}},"code":"=","nonce":"=","storage":{"0x0000000000000000000000000000000000000000000000000000000000000000":{"*":{"from":"0x0000000000000000000000000000000000000000000000000000000000000000","to":"0x0000000000000000000000000000000000000000000000000000000000000001"}}}}}`
+	if string(diff) != want {
+		t.Errorf("state diff mismatch:\nhave %s\nwant %s", diff, want)
+	}
+}
+
+func TestGasProfiler(t *testing.T) {
+	var (
+		contract = common.BytesToAddress([]byte("contract"))
+		callee   = common.HexToAddress("0xbb")
+		sha256   = common.BytesToAddress([]byte{0x02})
+		profiler = NewGasProfiler()
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(callee, []byte{byte(vm.PUSH1), 0x01, byte(vm.PUSH1), 0x00, byte(vm.SSTORE), byte(vm.STOP)})
+
+	// Call into the storing callee, then into the sha256 precompile
+	code := []byte{
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0x02, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	if _, _, err := runtime.Execute(code, []byte{0x12, 0x34, 0x56, 0x78}, &runtime.Config{
+		State:     statedb,
+		GasLimit:  100000,
+		EVMConfig: vm.Config{Debug: true, Tracer: profiler},
+	}); err != nil {
+		t.Fatalf("failed to execute code: %v", err)
+	}
+	profile := profiler.Profile()
+
+	// All the gas should be attributed to opcodes and frames exactly once
+	var opGas, selfGas uint64
+	for _, bucket := range profile.Buckets {
+		opGas += bucket.Gas
+	}
+	for _, frame := range profile.Frames {
+		selfGas += frame.SelfGas
+	}
+	if profile.Gas == 0 || opGas != profile.Gas || selfGas != profile.Gas {
+		t.Fatalf("gas attribution mismatch: total %d, opcodes %d, frames %d", profile.Gas, opGas, selfGas)
+	}
+	// Check the interesting buckets and the folded stacks
+	want := map[profileKey]uint64{
+		{contract: callee, selector: "fallback", op: "SSTORE"}:     20000,
+		{contract: sha256, selector: "fallback", op: precompileOp}: 60,
+	}
+	for _, bucket := range profile.Buckets {
+		key := profileKey{contract: bucket.Contract, selector: bucket.Selector, op: bucket.Op}
+		if gas, ok := want[key]; ok {
+			if bucket.Gas != gas || bucket.Count != 1 {
+				t.Errorf("bucket %v mismatch: have %d gas in %d runs, want %d in 1", key, bucket.Gas, bucket.Count, gas)
+			}
+			delete(want, key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing buckets: %v", want)
+	}
+	root := contract.Hex() + ":0x12345678"
+	for _, line := range []string{
+		root + ";" + callee.Hex() + ":fallback;SSTORE 20000\n",
+		root + ";" + sha256.Hex() + ":fallback;" + precompileOp + " 60\n",
+		root + ";CALL 1400\n",
+	} {
+		if !strings.Contains(profile.Folded, line) {
+			t.Errorf("folded stacks missing %q:\n%s", line, profile.Folded)
+		}
+	}
+}
+
+func TestSourceTracer(t *testing.T) {
+	var (
+		source   = "contract Vault {\n    function withdraw(uint amount) public {\n        require(amount > 0);\n    }\n}\n"
+		require  = strings.Index(source, "require(amount > 0)")
+		code     = []byte{byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.REVERT)}
+		registry = NewSourceRegistry()
+	)
+	err := registry.Register(&SourceArtifact{
+		Name:      "Vault",
+		Code:      code,
+		SourceMap: fmt.Sprintf("0:%d:0:-;%d:19:0;", len(source), require),
+		Sources:   []compiler.Source{{Name: "Vault.sol", Content: source}},
+	})
+	if err != nil {
+		t.Fatalf("failed to register artifact: %v", err)
+	}
+	var (
+		logger = vm.NewStructLogger(nil)
+		tracer = NewSourceTracer(logger, registry)
+	)
+	runtime.Execute(code, nil, &runtime.Config{
+		EVMConfig: vm.Config{Debug: true, Tracer: tracer},
+	})
+	if len(tracer.Steps) != len(logger.StructLogs()) || len(tracer.Steps) != 3 {
+		t.Fatalf("step count mismatch: have %d locations for %d logs", len(tracer.Steps), len(logger.StructLogs()))
+	}
+	if loc := tracer.Steps[0]; loc == nil || loc.Line != 1 || loc.Function != "" {
+		t.Errorf("first step location mismatch: have %+v", loc)
+	}
+	if len(tracer.Reverts) != 1 {
+		t.Fatalf("revert count mismatch: have %d, want 1", len(tracer.Reverts))
+	}
+	if loc := tracer.Reverts[0]; loc.String() != "Vault.sol:3 require(amount > 0)" || loc.Function != "Vault.withdraw" {
+		t.Errorf("revert location mismatch: have %v in %s", loc, loc.Function)
+	}
+}
+
+func TestCoverageTracer(t *testing.T) {
+	var (
+		source = "contract C {\n  a;\n  b;\n}\n"
+		code   = []byte{
+			byte(vm.PUSH1), 0x05, byte(vm.JUMP), // line 1
+			byte(vm.PUSH1), 0x00, // line 3, skipped
+			byte(vm.JUMPDEST), byte(vm.STOP), // line 2
+		}
+		coverage = NewCoverageTracer()
+		registry = NewSourceRegistry()
+	)
+	for i := 0; i < 2; i++ {
+		runtime.Execute(code, nil, &runtime.Config{
+			EVMConfig: vm.Config{Debug: true, Tracer: coverage},
+		})
+	}
+	result := coverage.Coverage()
+	if len(result) != 1 {
+		t.Fatalf("code count mismatch: have %d, want 1", len(result))
+	}
+	cov := result[crypto.Keccak256Hash(code)]
+	if cov == nil {
+		t.Fatalf("coverage of executed code missing")
+	}
+	if cov.Instructions != 5 || cov.Covered != 4 || cov.JumpDests != 1 || cov.JumpDestsCovered != 1 {
+		t.Errorf("coverage summary mismatch: have %+v", cov)
+	}
+	if want := map[uint64]uint64{0: 2, 2: 2, 5: 2, 6: 2}; !reflect.DeepEqual(cov.Hits, want) {
+		t.Errorf("hits mismatch: have %v, want %v", cov.Hits, want)
+	}
+	err := registry.Register(&SourceArtifact{
+		Name:      "C",
+		Code:      code,
+		SourceMap: fmt.Sprintf("0:%d:0:-;;20:1:0;15:1:0;", len(source)),
+		Sources:   []compiler.Source{{Name: "C.sol", Content: source}},
+	})
+	if err != nil {
+		t.Fatalf("failed to register artifact: %v", err)
+	}
+	lcov := new(bytes.Buffer)
+	if err := coverage.WriteLCOV(lcov, registry); err != nil {
+		t.Fatalf("failed to write lcov: %v", err)
+	}
+	if want := "TN:\nSF:C.sol\nDA:1,2\nDA:2,2\nDA:3,0\nLF:3\nLH:2\nend_of_record\n"; lcov.String() != want {
+		t.Errorf("lcov mismatch:\nhave %q\nwant %q", lcov.String(), want)
+	}
+}
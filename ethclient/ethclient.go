@@ -285,6 +285,57 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// BlockReceipts returns the receipts of all the transactions in the given block.
+func (ec *Client) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "eth_getBlockReceipts", toBlockNumberOrHashArg(blockNrOrHash))
+	if err == nil && r == nil {
+		return nil, ethereum.NotFound
+	}
+	return r, err
+}
+
+// InternalTransactionsByHash returns the value transfers and contract creations
+// made by the nested calls of the given transaction.
+func (ec *Client) InternalTransactionsByHash(ctx context.Context, txHash common.Hash) ([]*types.InnerTx, error) {
+	var r []*types.InnerTx
+	err := ec.c.CallContext(ctx, &r, "eth_getInternalTransactionsByHash", txHash)
+	if err == nil {
+		if r == nil {
+			return nil, ethereum.NotFound
+		}
+	}
+	return r, err
+}
+
+// InternalTransactionsByBlockHash returns the value transfers and contract
+// creations made by the nested calls of all the transactions in the given block.
+func (ec *Client) InternalTransactionsByBlockHash(ctx context.Context, hash common.Hash) ([]*types.InnerTx, error) {
+	var r []*types.InnerTx
+	err := ec.c.CallContext(ctx, &r, "eth_getInternalTransactionsByBlockHash", hash)
+	if err == nil {
+		if r == nil {
+			return nil, ethereum.NotFound
+		}
+	}
+	return r, err
+}
+
+// InternalTransactionsByBlockNumber returns the value transfers and contract
+// creations made by the nested calls of all the transactions in the given block.
+//
+// If number is nil, the latest known block is used.
+func (ec *Client) InternalTransactionsByBlockNumber(ctx context.Context, number *big.Int) ([]*types.InnerTx, error) {
+	var r []*types.InnerTx
+	err := ec.c.CallContext(ctx, &r, "eth_getInternalTransactionsByBlockNumber", toBlockNumArg(number))
+	if err == nil {
+		if r == nil {
+			return nil, ethereum.NotFound
+		}
+	}
+	return r, err
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -296,6 +347,23 @@ func toBlockNumArg(number *big.Int) string {
 	return hexutil.EncodeBig(number)
 }
 
+func toBlockNumberOrHashArg(blockNrOrHash rpc.BlockNumberOrHash) interface{} {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		if blockNrOrHash.RequireCanonical {
+			return map[string]interface{}{"blockHash": hash, "requireCanonical": true}
+		}
+		return hash
+	}
+	number, _ := blockNrOrHash.Number()
+	switch number {
+	case rpc.LatestBlockNumber:
+		return "latest"
+	case rpc.PendingBlockNumber:
+		return "pending"
+	}
+	return hexutil.Uint64(number)
+}
+
 type rpcProgress struct {
 	StartingBlock hexutil.Uint64
 	CurrentBlock  hexutil.Uint64
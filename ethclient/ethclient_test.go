@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // Verify that Client implements the ethereum interfaces.
@@ -193,7 +194,7 @@ func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 		t.Fatalf("can't create new node: %v", err)
 	}
 	// Create Ethereum Service
-	config := &eth.Config{Genesis: genesis}
+	config := &eth.Config{Genesis: genesis, EnableInnerTxs: true}
 	config.Ethash.PowMode = ethash.ModeFake
 	ethservice, err := eth.New(n, config)
 	if err != nil {
@@ -319,6 +320,63 @@ func TestBalanceAt(t *testing.T) {
 	}
 }
 
+func TestInternalTransactions(t *testing.T) {
+	backend, chain := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	ec := NewClient(client)
+
+	// The genesis block is never processed, so its inner txs are not indexed
+	if _, err := ec.InternalTransactionsByBlockNumber(context.Background(), big.NewInt(0)); err == nil {
+		t.Fatalf("expected not indexed error for genesis block")
+	}
+	// Imported blocks without any inner txs must return an empty list
+	txs, err := ec.InternalTransactionsByBlockHash(context.Background(), chain[1].Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve inner txs: %v", err)
+	}
+	if len(txs) != 0 {
+		t.Fatalf("inner tx count mismatch: have %d, want 0", len(txs))
+	}
+	// Unknown blocks must not be found
+	if _, err := ec.InternalTransactionsByBlockNumber(context.Background(), big.NewInt(1000000000)); err != ethereum.NotFound {
+		t.Fatalf("error mismatch: have %v, want %v", err, ethereum.NotFound)
+	}
+}
+
+func TestBlockReceipts(t *testing.T) {
+	backend, chain := newTestBackend(t)
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	ec := NewClient(client)
+
+	// Blocks without transactions must return an empty list, by number or hash
+	for _, arg := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(1),
+		rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber),
+		rpc.BlockNumberOrHashWithHash(chain[1].Hash(), true),
+	} {
+		receipts, err := ec.BlockReceipts(context.Background(), arg)
+		if err != nil {
+			t.Fatalf("failed to retrieve receipts of %v: %v", arg, err)
+		}
+		if receipts == nil || len(receipts) != 0 {
+			t.Fatalf("receipts of %v mismatch: have %v, want empty list", arg, receipts)
+		}
+	}
+	// Unknown blocks must not be found
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(1000000000)); err != ethereum.NotFound {
+		t.Fatalf("error mismatch: have %v, want %v", err, ethereum.NotFound)
+	}
+	if _, err := ec.BlockReceipts(context.Background(), rpc.BlockNumberOrHashWithHash(common.Hash{1}, false)); err == nil {
+		t.Fatalf("expected error for unknown block hash")
+	}
+}
+
 func TestTransactionInBlockInterrupted(t *testing.T) {
 	backend, _ := newTestBackend(t)
 	client, _ := backend.Attach()
@@ -20,6 +20,7 @@ package graphql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -88,6 +89,36 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+func (a *Account) Creator(ctx context.Context) (*ContractCreation, error) {
+	creation, err := ethapi.FindContractCreation(ctx, a.backend, a.address)
+	if creation == nil || err != nil {
+		return nil, err
+	}
+	return &ContractCreation{backend: a.backend, creation: creation}, nil
+}
+
+// ContractCreation represents the deployment of a contract account.
+type ContractCreation struct {
+	backend  ethapi.Backend
+	creation *types.ContractCreation
+}
+
+func (c *ContractCreation) Creator(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		backend:       c.backend,
+		address:       c.creation.Creator,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (c *ContractCreation) Type(ctx context.Context) string {
+	return c.creation.Type
+}
+
+func (c *ContractCreation) Transaction(ctx context.Context) *Transaction {
+	return &Transaction{backend: c.backend, hash: c.creation.TxHash}
+}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     ethapi.Backend
@@ -341,6 +372,68 @@ func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*v), nil
 }
 
+// Receipt represents the receipt of a transaction mined in a block.
+type Receipt struct {
+	backend     ethapi.Backend
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) *hexutil.Uint64 {
+	if len(r.receipt.PostState) > 0 {
+		return nil
+	}
+	ret := hexutil.Uint64(r.receipt.Status)
+	return &ret
+}
+
+func (r *Receipt) Root(ctx context.Context) *common.Hash {
+	if len(r.receipt.PostState) == 0 {
+		return nil
+	}
+	ret := common.BytesToHash(r.receipt.PostState)
+	return &ret
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		backend:       r.backend,
+		address:       r.receipt.ContractAddress,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     r.backend,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return r.receipt.Bloom.Bytes()
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -650,6 +743,36 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	return &ret, nil
 }
 
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil || receipts == nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts length mismatch: %d vs %d", len(receipts), len(txs))
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		ret = append(ret, &Receipt{
+			backend: b.backend,
+			transaction: &Transaction{
+				backend: b.backend,
+				hash:    txs[i].Hash(),
+				tx:      txs[i],
+				block:   b,
+				index:   uint64(i),
+			},
+			receipt: receipt,
+		})
+	}
+	return &ret, nil
+}
+
 func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
@@ -803,7 +926,7 @@ func (b *Block) Call(ctx context.Context, args struct {
 			return nil, err
 		}
 	}
-	result, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.numberOrHash, nil, vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
+	result, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.numberOrHash, nil, nil, vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
@@ -873,7 +996,7 @@ func (p *Pending) Call(ctx context.Context, args struct {
 	Data ethapi.CallArgs
 }) (*CallResult, error) {
 	pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
-	result, err := ethapi.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
+	result, err := ethapi.DoCall(ctx, p.backend, args.Data, pendingBlockNr, nil, nil, vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
 	if err != nil {
 		return nil, err
 	}
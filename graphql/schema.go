@@ -52,6 +52,23 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # Creator is the creation of the contract at this address, or null if
+        # no contract was ever created there. Only available on nodes indexing
+        # contract creations.
+        creator: ContractCreation
+    }
+
+    # ContractCreation is the deployment of a contract account.
+    type ContractCreation {
+        # Creator is the account that created the contract: the sender of the
+        # transaction for contracts deployed by a transaction, the creating
+        # contract for the ones created by another contract.
+        creator(block: Long): Account!
+        # Type is TRANSACTION for contracts deployed by a transaction, CREATE or
+        # CREATE2 for the ones created by another contract.
+        type: String!
+        # Transaction is the transaction the contract was created in.
+        transaction: Transaction!
     }
 
     # Log is an Ethereum event log.
@@ -120,6 +137,31 @@ const schema string = `
         v: BigInt!
     }
 
+    # Receipt is the outcome of a transaction mined in a block.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction: 1 if it succeeded,
+        # or 0 if it failed. Transactions mined before the Byzantium hard fork
+        # report their post-transaction state root instead.
+        status: Long
+        # Root is the post-transaction state root of transactions mined before
+        # the Byzantium hard fork, null afterwards.
+        root: Bytes32
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # CreatedContract is the account that was created by a contract creation
+        # transaction, null for any other transaction.
+        createdContract(block: Long): Account
+        # Logs is a list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is the bloom filter of the logs emitted by the transaction.
+        logsBloom: Bytes!
+    }
+
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
     # to a single block.
     input BlockFilterCriteria {
@@ -200,6 +242,10 @@ const schema string = `
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Int!): Transaction
+        # Receipts is a list of the receipts of the transactions in this block,
+        # in the same order. If receipts are unavailable for this block, this
+        # field will be null.
+        receipts: [Receipt!]
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
         # Account fetches an Ethereum account at the current block's state.
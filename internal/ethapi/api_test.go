@@ -0,0 +1,394 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend is a Backend serving the API from a local chain, without any
+// networking, transaction pool or indexers running.
+type testBackend struct {
+	db    ethdb.Database
+	chain *core.BlockChain
+
+	creationSections uint64 // Number of sections reported by the contract creation index
+}
+
+// newTestBackend creates a backend on top of a chain of n blocks generated from
+// the given genesis, processed with the given vm config.
+func newTestBackend(t *testing.T, n int, gspec *core.Genesis, config vm.Config, generator func(i int, b *core.BlockGen)) *testBackend {
+	var (
+		engine = ethash.NewFaker()
+		db     = rawdb.NewMemoryDatabase()
+	)
+	// Generate the chain on a separate database, so only the imported blocks
+	// are processed with the requested vm config
+	gendb := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(gspec.Config, gspec.MustCommit(gendb), engine, gendb, n, generator)
+
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec.Config, engine, config, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	return &testBackend{db: db, chain: chain}
+}
+
+func (b *testBackend) Downloader() *downloader.Downloader                 { return nil }
+func (b *testBackend) SuggestPrice(ctx context.Context) (*big.Int, error) { return big.NewInt(1), nil }
+func (b *testBackend) FeeStatistics(ctx context.Context, first, last uint64) ([]*gasprice.BlockFees, error) {
+	return nil, errors.New("not supported")
+}
+func (b *testBackend) SuggestPriceTiers(ctx context.Context) (*gasprice.PriceTiers, error) {
+	return nil, errors.New("not supported")
+}
+func (b *testBackend) ChainDb() ethdb.Database           { return b.db }
+func (b *testBackend) AccountManager() *accounts.Manager { return nil }
+func (b *testBackend) ExtRPCEnabled() bool               { return false }
+func (b *testBackend) RPCGasCap() uint64                 { return 25000000 }
+func (b *testBackend) RPCTxFeeCap() float64              { return 1 }
+
+func (b *testBackend) SetHead(number uint64)        { b.chain.SetHead(number) }
+func (b *testBackend) CurrentHeader() *types.Header { return b.chain.CurrentHeader() }
+func (b *testBackend) CurrentBlock() *types.Block   { return b.chain.CurrentBlock() }
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number < 0 {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *testBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if number, ok := blockNrOrHash.Number(); ok {
+		return b.HeaderByNumber(ctx, number)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	return b.HeaderByHash(ctx, hash)
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number < 0 {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if number, ok := blockNrOrHash.Number(); ok {
+		return b.BlockByNumber(ctx, number)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	return b.BlockByHash(ctx, hash)
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.StateAndHeaderByNumberOrHash(ctx, rpc.BlockNumberOrHashWithNumber(number))
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, nil, errors.New("header not found")
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
+func (b *testBackend) GetInnerTxs(ctx context.Context, hash common.Hash) ([]types.InnerTxs, error) {
+	return b.chain.GetInnerTxsByHash(hash), nil
+}
+
+func (b *testBackend) InnerTxIndexStatus() (uint64, uint64) { return 256, 0 }
+func (b *testBackend) ContractCreationIndexStatus() (uint64, uint64) {
+	return 256, b.creationSections
+}
+func (b *testBackend) TokenTransferIndexStatus() (uint64, uint64) { return 0, 0 }
+
+func (b *testBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
+	return b.chain.GetTdByHash(hash)
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+	txContext := core.NewEVMTxContext(msg)
+	context := core.NewEVMBlockContext(header, b.chain, nil)
+	return vm.NewEVM(context, txContext, state, b.chain.Config(), *b.chain.GetVMConfig()), func() error { return nil }, nil
+}
+
+func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.chain.SubscribeChainEvent(ch)
+}
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.chain.SubscribeChainHeadEvent(ch)
+}
+func (b *testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	return b.chain.SubscribeChainSideEvent(ch)
+}
+
+func (b *testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	return errors.New("not supported")
+}
+func (b *testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.db, txHash)
+	return tx, blockHash, blockNumber, index, nil
+}
+func (b *testBackend) GetPoolTransactions() (types.Transactions, error)         { return nil, nil }
+func (b *testBackend) GetPoolTransaction(txHash common.Hash) *types.Transaction { return nil }
+func (b *testBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	return 0, nil
+}
+func (b *testBackend) Stats() (pending int, queued int) { return 0, 0 }
+func (b *testBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return nil, nil
+}
+func (b *testBackend) SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription {
+	return nil
+}
+
+func (b *testBackend) BloomStatus() (uint64, uint64) { return params.BloomBitsBlocks, 0 }
+func (b *testBackend) GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error) {
+	return nil, errors.New("not supported")
+}
+func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {}
+func (b *testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return b.chain.SubscribeLogsEvent(ch)
+}
+func (b *testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
+	return nil
+}
+func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
+	return b.chain.SubscribeRemovedLogsEvent(ch)
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
+func (b *testBackend) Engine() consensus.Engine         { return b.chain.Engine() }
+
+func TestStateOverrideApply(t *testing.T) {
+	var (
+		addr  = common.HexToAddress("0x01")
+		slot1 = common.Hash{0x01}
+		slot2 = common.Hash{0x02}
+		value = common.Hash{0xff}
+	)
+	uint64p := func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+	bigp := func(n int64) **hexutil.Big { b := (*hexutil.Big)(big.NewInt(n)); return &b }
+	bytesp := func(b []byte) *hexutil.Bytes { return (*hexutil.Bytes)(&b) }
+	storagep := func(s map[common.Hash]common.Hash) *map[common.Hash]common.Hash { return &s }
+
+	tests := []struct {
+		override OverrideAccount
+		balance  int64
+		nonce    uint64
+		code     []byte
+		storage  map[common.Hash]common.Hash
+		err      bool
+	}{
+		// Untouched account
+		{
+			override: OverrideAccount{},
+			balance:  1, nonce: 1, code: []byte{0x01},
+			storage: map[common.Hash]common.Hash{slot1: {0x01}, slot2: {0x02}},
+		},
+		{
+			override: OverrideAccount{Balance: bigp(100)},
+			balance:  100, nonce: 1, code: []byte{0x01},
+			storage: map[common.Hash]common.Hash{slot1: {0x01}, slot2: {0x02}},
+		},
+		{
+			override: OverrideAccount{Nonce: uint64p(5)},
+			balance:  1, nonce: 5, code: []byte{0x01},
+			storage: map[common.Hash]common.Hash{slot1: {0x01}, slot2: {0x02}},
+		},
+		{
+			override: OverrideAccount{Code: bytesp([]byte{0x02, 0x03})},
+			balance:  1, nonce: 1, code: []byte{0x02, 0x03},
+			storage: map[common.Hash]common.Hash{slot1: {0x01}, slot2: {0x02}},
+		},
+		// State replaces the whole storage, slots not given are cleared
+		{
+			override: OverrideAccount{State: storagep(map[common.Hash]common.Hash{slot1: value})},
+			balance:  1, nonce: 1, code: []byte{0x01},
+			storage: map[common.Hash]common.Hash{slot1: value, slot2: {}},
+		},
+		// StateDiff only changes the given slots
+		{
+			override: OverrideAccount{StateDiff: storagep(map[common.Hash]common.Hash{slot1: value})},
+			balance:  1, nonce: 1, code: []byte{0x01},
+			storage: map[common.Hash]common.Hash{slot1: value, slot2: {0x02}},
+		},
+		{
+			override: OverrideAccount{
+				State:     storagep(map[common.Hash]common.Hash{slot1: value}),
+				StateDiff: storagep(map[common.Hash]common.Hash{slot2: value}),
+			},
+			err: true,
+		},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetBalance(addr, big.NewInt(1))
+		statedb.SetNonce(addr, 1)
+		statedb.SetCode(addr, []byte{0x01})
+		statedb.SetState(addr, slot1, common.Hash{0x01})
+		statedb.SetState(addr, slot2, common.Hash{0x02})
+
+		err := (&StateOverride{addr: tt.override}).Apply(statedb)
+		if tt.err {
+			if err == nil {
+				t.Errorf("test %d: conflicting override applied", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to apply override: %v", i, err)
+		}
+		if have := statedb.GetBalance(addr); have.Cmp(big.NewInt(tt.balance)) != 0 {
+			t.Errorf("test %d: balance mismatch: have %v, want %v", i, have, tt.balance)
+		}
+		if have := statedb.GetNonce(addr); have != tt.nonce {
+			t.Errorf("test %d: nonce mismatch: have %v, want %v", i, have, tt.nonce)
+		}
+		if have := statedb.GetCode(addr); !bytes.Equal(have, tt.code) {
+			t.Errorf("test %d: code mismatch: have %x, want %x", i, have, tt.code)
+		}
+		for slot, want := range tt.storage {
+			if have := statedb.GetState(addr, slot); have != want {
+				t.Errorf("test %d: slot %x mismatch: have %x, want %x", i, slot, have, want)
+			}
+		}
+	}
+	// A nil override leaves the state alone
+	if err := (*StateOverride)(nil).Apply(nil); err != nil {
+		t.Errorf("nil override failed: %v", err)
+	}
+}
+
+// blockContextCode returns the block number, timestamp and coinbase of the
+// context it is executed in.
+var blockContextCode = []byte{
+	byte(vm.NUMBER), byte(vm.PUSH1), 0, byte(vm.MSTORE),
+	byte(vm.TIMESTAMP), byte(vm.PUSH1), 32, byte(vm.MSTORE),
+	byte(vm.COINBASE), byte(vm.PUSH1), 64, byte(vm.MSTORE),
+	byte(vm.PUSH1), 96, byte(vm.PUSH1), 0, byte(vm.RETURN),
+}
+
+// Tests that eth_call executes within the overridden block context and state.
+func TestCallOverrides(t *testing.T) {
+	var (
+		reporter = common.HexToAddress("0xc0")
+		coinbase = common.HexToAddress("0xc01b")
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{reporter: {Balance: new(big.Int), Code: blockContextCode}},
+		}
+	)
+	backend := newTestBackend(t, 2, gspec, vm.Config{}, func(i int, b *core.BlockGen) {})
+	defer backend.chain.Stop()
+
+	var (
+		head    = backend.chain.CurrentBlock()
+		number  = (*hexutil.Big)(big.NewInt(1000))
+		time    = (*hexutil.Big)(big.NewInt(12345))
+		code    = hexutil.Bytes{byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN)}
+		replace = &StateOverride{reporter: OverrideAccount{Code: &code}}
+	)
+	tests := []struct {
+		state  *StateOverride
+		block  *BlockOverrides
+		want   []common.Hash
+		failed bool
+	}{
+		{
+			want: []common.Hash{common.BigToHash(head.Number()), common.BigToHash(new(big.Int).SetUint64(head.Time())), common.BytesToHash(head.Coinbase().Bytes())},
+		},
+		{
+			block: &BlockOverrides{Number: number},
+			want:  []common.Hash{common.BigToHash(number.ToInt()), common.BigToHash(new(big.Int).SetUint64(head.Time())), common.BytesToHash(head.Coinbase().Bytes())},
+		},
+		{
+			block: &BlockOverrides{Time: time, Coinbase: &coinbase},
+			want:  []common.Hash{common.BigToHash(head.Number()), common.BigToHash(time.ToInt()), common.BytesToHash(coinbase.Bytes())},
+		},
+		{
+			state: replace,
+			block: &BlockOverrides{Number: number},
+			want:  []common.Hash{{31: 0x2a}},
+		},
+		{
+			state:  &StateOverride{reporter: OverrideAccount{State: &map[common.Hash]common.Hash{}, StateDiff: &map[common.Hash]common.Hash{}}},
+			failed: true,
+		},
+	}
+	api := NewPublicBlockChainAPI(backend)
+	for i, tt := range tests {
+		res, err := api.Call(context.Background(), CallArgs{To: &reporter}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), tt.state, tt.block)
+		if tt.failed {
+			if err == nil {
+				t.Errorf("test %d: call with invalid overrides succeeded", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: call failed: %v", i, err)
+		}
+		want := make([]byte, 0, 32*len(tt.want))
+		for _, word := range tt.want {
+			want = append(want, word.Bytes()...)
+		}
+		if !bytes.Equal(res, want) {
+			t.Errorf("test %d: result mismatch: have %x, want %x", i, res, want)
+		}
+	}
+}
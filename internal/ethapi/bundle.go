@@ -0,0 +1,197 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// bundleTimeout is the maximum time a whole bundle is allowed to execute for.
+const bundleTimeout = 5 * time.Second
+
+// BundleTransaction is a single entry of a simulated bundle. It is either a
+// signed raw transaction, or an unsigned call message with the same fields as
+// accepted by eth_call.
+type BundleTransaction struct {
+	CallArgs
+	Raw *hexutil.Bytes `json:"raw"`
+}
+
+// BundleTxResult is the outcome of a single message executed within a bundle.
+type BundleTxResult struct {
+	TxHash          *common.Hash   `json:"txHash,omitempty"`
+	GasUsed         hexutil.Uint64 `json:"gasUsed"`
+	ReturnData      hexutil.Bytes  `json:"returnData"`
+	Revert          string         `json:"revert,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	Logs            []*types.Log   `json:"logs"`
+	CoinbasePayment *hexutil.Big   `json:"coinbasePayment"`
+}
+
+// BundleResult is the aggregated outcome of executing a bundle.
+type BundleResult struct {
+	Results         []*BundleTxResult `json:"results"`
+	GasUsed         hexutil.Uint64    `json:"gasUsed"`
+	CoinbasePayment *hexutil.Big      `json:"coinbasePayment"`
+	StateBlockHash  common.Hash       `json:"stateBlockHash"`
+	StateRoot       common.Hash       `json:"stateRoot"`
+}
+
+// CallBundle executes the given list of transactions and call messages one after
+// the other on top of the state of the requested block, carrying the state
+// changes of each over to the next one. Optionally the block context of the
+// execution can be altered with the given overrides. The whole bundle is limited
+// to the gas limit of the block, call messages without gas allowance are given
+// the gas left in the block.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to simulate the outcome of an ordered set of transactions.
+func (s *PublicBlockChainAPI) CallBundle(ctx context.Context, txs []BundleTransaction, blockNrOrHash rpc.BlockNumberOrHash, blockOverrides *BlockOverrides) (*BundleResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM bundle finished", "runtime", time.Since(start)) }(time.Now())
+
+	if len(txs) == 0 {
+		return nil, errors.New("bundle missing transactions")
+	}
+	statedb, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	// Decode all the signed transactions up front to fail fast on junk
+	var (
+		config = s.b.ChainConfig()
+		signer = types.MakeSigner(config, header.Number)
+		msgs   = make([]core.Message, len(txs))
+		hashes = make([]*common.Hash, len(txs))
+	)
+	for i, tx := range txs {
+		if tx.Raw == nil {
+			continue // Call messages are assembled on execution, once the gas left is known
+		}
+		signed := new(types.Transaction)
+		if err := rlp.DecodeBytes(*tx.Raw, signed); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		if msgs[i], err = signed.AsMessage(signer); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		hash := signed.Hash()
+		hashes[i] = &hash
+	}
+	// Setup context so it may be cancelled if the bundle runs for too long
+	ctx, cancel := context.WithTimeout(ctx, bundleTimeout)
+	defer cancel()
+
+	first := msgs[0]
+	if first == nil {
+		first = txs[0].ToMessage(s.b.RPCGasCap())
+	}
+	evm, vmError, err := s.b.GetEVM(ctx, first, statedb, header)
+	if err != nil {
+		return nil, err
+	}
+	blockOverrides.Apply(&evm.Context)
+
+	// Wait for the context to be done and cancel the evm. Even if the
+	// EVM has finished, cancelling may be done (repeatedly)
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	var (
+		coinbase    = evm.Context.Coinbase
+		deleteEmpty = config.IsEIP158(evm.Context.BlockNumber)
+		gp          = new(core.GasPool).AddGas(evm.Context.GasLimit)
+		result      = &BundleResult{
+			Results:        make([]*BundleTxResult, 0, len(msgs)),
+			StateBlockHash: header.Hash(),
+		}
+		totalPayment = new(big.Int)
+	)
+	for i, msg := range msgs {
+		if msg == nil {
+			gasCap := s.b.RPCGasCap()
+			if txs[i].Gas == nil && (gasCap == 0 || gp.Gas() < gasCap) {
+				gasCap = gp.Gas()
+			}
+			msg = txs[i].ToMessage(gasCap)
+		}
+		// Logs are keyed by transaction hash in the state, unsigned messages
+		// are keyed by their position instead.
+		logKey := common.BigToHash(big.NewInt(int64(i)))
+		if hashes[i] != nil {
+			logKey = *hashes[i]
+		}
+		statedb.Prepare(logKey, header.Hash(), i)
+
+		before := statedb.GetBalance(coinbase)
+		evm.Reset(core.NewEVMTxContext(msg), statedb)
+
+		res, err := core.ApplyMessage(evm, msg, gp)
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if evm.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", bundleTimeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w (supplied gas %d)", i, err, msg.Gas())
+		}
+		statedb.Finalise(deleteEmpty)
+
+		payment := new(big.Int).Sub(statedb.GetBalance(coinbase), before)
+		totalPayment.Add(totalPayment, payment)
+
+		txResult := &BundleTxResult{
+			TxHash:          hashes[i],
+			GasUsed:         hexutil.Uint64(res.UsedGas),
+			ReturnData:      res.ReturnData,
+			Logs:            statedb.GetLogs(logKey),
+			CoinbasePayment: (*hexutil.Big)(payment),
+		}
+		if txResult.Logs == nil {
+			txResult.Logs = []*types.Log{}
+		}
+		if res.Err != nil {
+			txResult.Error = res.Err.Error()
+		}
+		if revert := res.Revert(); len(revert) > 0 {
+			if reason, err := abi.UnpackRevert(revert); err == nil {
+				txResult.Revert = reason
+			} else {
+				txResult.Revert = hexutil.Encode(revert)
+			}
+		}
+		result.Results = append(result.Results, txResult)
+		result.GasUsed += txResult.GasUsed
+	}
+	result.CoinbasePayment = (*hexutil.Big)(totalPayment)
+	result.StateRoot = statedb.IntermediateRoot(deleteEmpty)
+	return result, nil
+}
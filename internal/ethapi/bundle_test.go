@@ -0,0 +1,173 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// bundleCounterCode increments the counter in slot 0, logs and returns it
+	bundleCounterCode = []byte{
+		byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.PUSH1), 1, byte(vm.ADD),
+		byte(vm.DUP1), byte(vm.PUSH1), 0, byte(vm.SSTORE),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG0),
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN),
+	}
+	// bundleReverterCode reverts with the single byte 0x2a as its reason
+	bundleReverterCode = []byte{
+		byte(vm.PUSH1), 0x2a, byte(vm.PUSH1), 0, byte(vm.MSTORE8),
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.REVERT),
+	}
+)
+
+func TestCallBundle(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		counter  = common.HexToAddress("0xc0")
+		reverter = common.HexToAddress("0xdead")
+		gspec    = &core.Genesis{
+			Config:   params.TestChainConfig,
+			GasLimit: 1000000,
+			Alloc: core.GenesisAlloc{
+				sender:   {Balance: big.NewInt(params.Ether)},
+				counter:  {Balance: new(big.Int), Code: bundleCounterCode},
+				reverter: {Balance: new(big.Int), Code: bundleReverterCode},
+			},
+		}
+	)
+	backend := newTestBackend(t, 1, gspec, vm.Config{}, func(i int, b *core.BlockGen) {})
+	defer backend.chain.Stop()
+
+	signed := func(gas uint64) BundleTransaction {
+		tx, _ := types.SignTx(types.NewTransaction(0, counter, nil, gas, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		raw, _ := rlp.EncodeToBytes(tx)
+		return BundleTransaction{Raw: (*hexutil.Bytes)(&raw)}
+	}
+	call := func(to common.Address) BundleTransaction {
+		return BundleTransaction{CallArgs: CallArgs{To: &to}}
+	}
+	gasLimit := func(limit uint64) *BlockOverrides {
+		return &BlockOverrides{GasLimit: (*hexutil.Uint64)(&limit)}
+	}
+	type want struct {
+		counter uint64 // Value returned by the counter, 0 for other contracts
+		revert  string
+		payment bool // Whether the coinbase is paid for the gas
+	}
+	tests := []struct {
+		txs       []BundleTransaction
+		overrides *BlockOverrides
+		want      []want
+		err       error
+	}{
+		// State changes are carried over in order, between signed and unsigned messages
+		{
+			txs:  []BundleTransaction{signed(100000), call(counter), call(counter)},
+			want: []want{{counter: 1, payment: true}, {counter: 2}, {counter: 3}},
+		},
+		// Reverting messages don't change the state nor abort the bundle
+		{
+			txs:  []BundleTransaction{call(counter), call(reverter), call(counter)},
+			want: []want{{counter: 1}, {revert: "0x2a"}, {counter: 2}},
+		},
+		// Calls without gas allowance are limited to the gas left in the block
+		{
+			txs:       []BundleTransaction{call(counter), call(counter)},
+			overrides: gasLimit(100000),
+			want:      []want{{counter: 1}, {counter: 2}},
+		},
+		// Messages exceeding the gas left in the block are rejected
+		{
+			txs: []BundleTransaction{signed(2000000)},
+			err: core.ErrGasLimitReached,
+		},
+		{
+			txs:       []BundleTransaction{call(counter), signed(100000)},
+			overrides: gasLimit(100000),
+			err:       core.ErrGasLimitReached,
+		},
+	}
+	api := NewPublicBlockChainAPI(backend)
+	for i, tt := range tests {
+		result, err := api.CallBundle(context.Background(), tt.txs, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), tt.overrides)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: failed to call bundle: %v", i, err)
+		}
+		if len(result.Results) != len(tt.want) {
+			t.Fatalf("test %d: result count mismatch: have %d, want %d", i, len(result.Results), len(tt.want))
+		}
+		var gasUsed uint64
+		for j, res := range result.Results {
+			gasUsed += uint64(res.GasUsed)
+
+			want := tt.want[j]
+			if (res.TxHash != nil) != (tt.txs[j].Raw != nil) {
+				t.Errorf("test %d, tx %d: transaction hash mismatch: have %v", i, j, res.TxHash)
+			}
+			if res.Revert != want.revert {
+				t.Errorf("test %d, tx %d: revert mismatch: have %q, want %q", i, j, res.Revert, want.revert)
+			}
+			if want.revert != "" {
+				if res.Error != vm.ErrExecutionReverted.Error() {
+					t.Errorf("test %d, tx %d: error mismatch: have %q, want %q", i, j, res.Error, vm.ErrExecutionReverted)
+				}
+				continue
+			}
+			if res.Error != "" {
+				t.Errorf("test %d, tx %d: unexpected error: %v", i, j, res.Error)
+			}
+			if have := new(big.Int).SetBytes(res.ReturnData).Uint64(); have != want.counter {
+				t.Errorf("test %d, tx %d: counter mismatch: have %d, want %d", i, j, have, want.counter)
+			}
+			if len(res.Logs) != 1 || res.Logs[0].Address != counter {
+				t.Errorf("test %d, tx %d: logs mismatch: have %v", i, j, res.Logs)
+			}
+			payment := new(big.Int)
+			if want.payment {
+				payment.SetUint64(uint64(res.GasUsed))
+			}
+			if res.CoinbasePayment.ToInt().Cmp(payment) != 0 {
+				t.Errorf("test %d, tx %d: coinbase payment mismatch: have %v, want %v", i, j, res.CoinbasePayment, payment)
+			}
+		}
+		if uint64(result.GasUsed) != gasUsed {
+			t.Errorf("test %d: total gas used mismatch: have %d, want %d", i, result.GasUsed, gasUsed)
+		}
+	}
+}
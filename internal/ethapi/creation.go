@@ -0,0 +1,136 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxCreationScanBlocks is the maximum number of blocks not yet covered by the
+// contract creation index that a single lookup scans one by one.
+const maxCreationScanBlocks = 1024
+
+var (
+	// errCreationIndexDisabled is returned if contract creators are requested
+	// from a node not indexing them.
+	errCreationIndexDisabled = errors.New("contract creation index not enabled")
+
+	// errCreationIndexBehind is returned if too many blocks are missing from the
+	// contract creation index to scan them directly, e.g. while it's being built.
+	errCreationIndexBehind = errors.New("contract creation index too far behind the chain head")
+)
+
+// RPCContractCreation is the creation of a contract as returned over RPC.
+type RPCContractCreation struct {
+	Address     common.Address `json:"address"`
+	Creator     common.Address `json:"creator"`
+	Type        string         `json:"type"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+// GetContractCreator returns the account which created the contract at the given
+// address along with the transaction and block it was created in, or nil if no
+// contract was ever created there. The creator of a contract deployed by another
+// contract is the deploying contract, not the sender of the transaction.
+func (s *PublicBlockChainAPI) GetContractCreator(ctx context.Context, address common.Address) (*RPCContractCreation, error) {
+	creation, err := FindContractCreation(ctx, s.b, address)
+	if creation == nil || err != nil {
+		return nil, err
+	}
+	return &RPCContractCreation{
+		Address:     creation.Address,
+		Creator:     creation.Creator,
+		Type:        creation.Type,
+		TxHash:      creation.TxHash,
+		TxIndex:     hexutil.Uint(creation.TxIndex),
+		BlockHash:   creation.BlockHash,
+		BlockNumber: hexutil.Uint64(creation.BlockNumber),
+	}, nil
+}
+
+// FindContractCreation looks up the creation of the contract at the given address,
+// scanning the blocks not yet indexed one by one, most recent first, and using
+// the contract creation index for the rest of the chain. The most recent creation
+// is returned if the address was redeployed to, or nil if no contract was ever
+// created there. Nested creations are missed in blocks imported without recording
+// their inner transactions.
+func FindContractCreation(ctx context.Context, b Backend, address common.Address) (*types.ContractCreation, error) {
+	size, sections := b.ContractCreationIndexStatus()
+	if size == 0 {
+		return nil, errCreationIndexDisabled
+	}
+	var (
+		head    = b.CurrentHeader().Number.Uint64()
+		indexed = size * sections
+	)
+	for number := head; number >= indexed && number > 0; number-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if head-number == maxCreationScanBlocks {
+			return nil, errCreationIndexBehind
+		}
+		block, err := b.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		receipts, err := b.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		innerTxs, err := blockInnerTxs(ctx, b, block)
+		if err != nil {
+			if _, ok := err.(*innerTxsNotIndexedError); !ok {
+				return nil, err
+			}
+			innerTxs = make([]types.InnerTxs, len(block.Transactions()))
+		}
+		creations, err := types.ContractCreations(types.MakeSigner(b.ChainConfig(), block.Number()), block, receipts, innerTxs)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(creations) - 1; i >= 0; i-- {
+			if creations[i].Address == address {
+				return creations[i], nil
+			}
+		}
+	}
+	// Entries left behind by blocks reorged out of the chain are ignored
+	if creation := rawdb.ReadContractCreation(b.ChainDb(), address); creation != nil {
+		if rawdb.ReadCanonicalHash(b.ChainDb(), creation.BlockNumber) == creation.BlockHash {
+			return creation, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that contract creations are looked up in the unindexed blocks before
+// the index, and that blocks without recorded inner transactions are tolerated.
+func TestFindContractCreation(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+	)
+	// Deploy a contract in every block, none of them recording inner transactions
+	backend := newTestBackend(t, 3, gspec, vm.Config{}, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewContractCreation(b.TxNonce(sender), new(big.Int), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		b.AddTx(tx)
+	})
+	defer backend.chain.Stop()
+
+	var (
+		first    = backend.chain.GetBlockByNumber(1)
+		last     = backend.chain.GetBlockByNumber(3)
+		deployed = crypto.CreateAddress(sender, 2)
+		indexed  = common.HexToAddress("0xaa")
+		stale    = common.HexToAddress("0xbb")
+	)
+	// An older creation at the address of the recent deployment, as left behind
+	// by a selfdestructed and redeployed CREATE2 contract
+	rawdb.WriteContractCreation(backend.db, &types.ContractCreation{
+		Address: deployed, Creator: stale, Type: types.CreationCreate2, BlockHash: first.Hash(), BlockNumber: 1,
+	})
+	rawdb.WriteContractCreation(backend.db, &types.ContractCreation{
+		Address: indexed, Creator: sender, Type: types.CreationCreate, BlockHash: first.Hash(), BlockNumber: 1,
+	})
+	rawdb.WriteContractCreation(backend.db, &types.ContractCreation{
+		Address: stale, Creator: sender, Type: types.CreationCreate, BlockHash: common.Hash{0x01}, BlockNumber: 1,
+	})
+	tests := []struct {
+		address common.Address
+		creator common.Address
+		typ     string
+		block   *types.Block
+	}{
+		{deployed, sender, types.CreationTransaction, last},
+		{indexed, sender, types.CreationCreate, first},
+		{stale, common.Address{}, "", nil},
+	}
+	for i, tt := range tests {
+		creation, err := FindContractCreation(context.Background(), backend, tt.address)
+		if err != nil {
+			t.Errorf("test %d: failed to find creation: %v", i, err)
+			continue
+		}
+		if tt.block == nil {
+			if creation != nil {
+				t.Errorf("test %d: unexpected creation %+v", i, creation)
+			}
+			continue
+		}
+		if creation == nil {
+			t.Errorf("test %d: creation of %x not found", i, tt.address)
+			continue
+		}
+		if creation.Creator != tt.creator || creation.Type != tt.typ || creation.BlockHash != tt.block.Hash() {
+			t.Errorf("test %d: creation mismatch: have %+v", i, creation)
+		}
+	}
+}
+
+// Tests that contract creation lookups refuse to scan too many unindexed blocks.
+func TestFindContractCreationScanLimit(t *testing.T) {
+	gspec := &core.Genesis{Config: params.TestChainConfig}
+	backend := newTestBackend(t, maxCreationScanBlocks+16, gspec, vm.Config{}, func(i int, b *core.BlockGen) {})
+	defer backend.chain.Stop()
+
+	if _, err := FindContractCreation(context.Background(), backend, common.Address{}); err != errCreationIndexBehind {
+		t.Fatalf("unindexed chain: error mismatch: have %v, want %v", err, errCreationIndexBehind)
+	}
+	backend.creationSections = 1
+	if _, err := FindContractCreation(context.Background(), backend, common.Address{}); err != nil {
+		t.Fatalf("partially indexed chain: failed to scan: %v", err)
+	}
+}
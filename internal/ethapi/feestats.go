@@ -0,0 +1,177 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultFeeStatsBlocks is the number of most recent blocks whose fee
+	// statistics are returned if no range is requested.
+	defaultFeeStatsBlocks = 20
+
+	// maxFeeStatsBlocks is the maximum number of blocks whose fee statistics a
+	// single query may return.
+	maxFeeStatsBlocks = 1024
+
+	// maxFeeStatsPercentiles is the maximum number of percentiles a single fee
+	// statistics query may request.
+	maxFeeStatsPercentiles = 100
+)
+
+// FeeStatisticsArgs selects the blocks and percentiles of a fee statistics query.
+// Either the number of blocks ending at ToBlock or an explicit range starting at
+// FromBlock may be requested, ToBlock defaulting to the latest block.
+type FeeStatisticsArgs struct {
+	Blocks      *hexutil.Uint64  `json:"blocks"`
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	Percentiles []float64        `json:"percentiles"`
+}
+
+// RPCBlockFees is the fee statistics of a block as returned over RPC. The prices
+// are nil if the block has no transactions besides the ones sent by its miner.
+type RPCBlockFees struct {
+	Number       hexutil.Uint64 `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	GasLimit     hexutil.Uint64 `json:"gasLimit"`
+	GasUsedRatio float64        `json:"gasUsedRatio"`
+	Samples      hexutil.Uint   `json:"samples"`
+	MinPrice     *hexutil.Big   `json:"minPrice"`
+	MedianPrice  *hexutil.Big   `json:"medianPrice"`
+	MaxPrice     *hexutil.Big   `json:"maxPrice"`
+	Percentiles  []*hexutil.Big `json:"percentiles"`
+}
+
+// FeeStatistics returns the gas price distribution and gas usage of a range of
+// blocks: the minimum, median and maximum price along with the requested price
+// percentiles, sampled from the transactions not sent by the blocks' miners.
+func (s *PublicEthereumAPI) FeeStatistics(ctx context.Context, args FeeStatisticsArgs) ([]*RPCBlockFees, error) {
+	for _, percent := range args.Percentiles {
+		if percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid percentile %v, must be between 0 and 100", percent)
+		}
+	}
+	if len(args.Percentiles) > maxFeeStatsPercentiles {
+		return nil, fmt.Errorf("too many percentiles, at most %d allowed", maxFeeStatsPercentiles)
+	}
+	head := s.b.CurrentHeader().Number.Uint64()
+	last := head
+	if args.ToBlock != nil {
+		last = resolveBlockNumber(*args.ToBlock, head)
+	}
+	if last > head {
+		return nil, fmt.Errorf("block #%d not found", last)
+	}
+	var first uint64
+	switch {
+	case args.FromBlock != nil && args.Blocks != nil:
+		return nil, errors.New("both block count and fromBlock specified")
+	case args.FromBlock != nil:
+		first = resolveBlockNumber(*args.FromBlock, head)
+		if first > last {
+			return nil, errors.New("invalid block range")
+		}
+	default:
+		count := uint64(defaultFeeStatsBlocks)
+		if args.Blocks != nil {
+			count = uint64(*args.Blocks)
+		}
+		if count == 0 {
+			return nil, errors.New("invalid block count 0")
+		}
+		if count > last+1 {
+			count = last + 1
+		}
+		first = last + 1 - count
+	}
+	if last-first+1 > maxFeeStatsBlocks {
+		return nil, fmt.Errorf("block range too large, at most %d blocks allowed", maxFeeStatsBlocks)
+	}
+	stats, err := s.b.FeeStatistics(ctx, first, last)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*RPCBlockFees, len(stats))
+	for i, fees := range stats {
+		results[i] = &RPCBlockFees{
+			Number:       hexutil.Uint64(fees.Number),
+			Hash:         fees.Hash,
+			GasUsed:      hexutil.Uint64(fees.GasUsed),
+			GasLimit:     hexutil.Uint64(fees.GasLimit),
+			GasUsedRatio: fees.GasUsedRatio(),
+			Samples:      hexutil.Uint(len(fees.Prices)),
+			MinPrice:     (*hexutil.Big)(fees.Min()),
+			MedianPrice:  (*hexutil.Big)(fees.Median()),
+			MaxPrice:     (*hexutil.Big)(fees.Max()),
+			Percentiles:  make([]*hexutil.Big, len(args.Percentiles)),
+		}
+		for j, percent := range args.Percentiles {
+			results[i].Percentiles[j] = (*hexutil.Big)(fees.Percentile(percent))
+		}
+	}
+	return results, nil
+}
+
+// RPCPriceTier is a gas price recommendation as returned over RPC, along with
+// the expected number of blocks and seconds until inclusion.
+type RPCPriceTier struct {
+	Price  *hexutil.Big   `json:"price"`
+	Blocks hexutil.Uint64 `json:"expectedBlocks"`
+	Wait   hexutil.Uint64 `json:"expectedWait"`
+}
+
+// RPCPriceTiers are gas price recommendations for increasing inclusion speeds.
+type RPCPriceTiers struct {
+	Slow     *RPCPriceTier `json:"slow"`
+	Standard *RPCPriceTier `json:"standard"`
+	Fast     *RPCPriceTier `json:"fast"`
+	Instant  *RPCPriceTier `json:"instant"`
+}
+
+// GasPriceTiers returns slow, standard, fast and instant gas price suggestions,
+// each with its expected inclusion delay. Unlike GasPrice, the suggestions take
+// the backlog of the executable transactions in the pool into account.
+func (s *PublicEthereumAPI) GasPriceTiers(ctx context.Context) (*RPCPriceTiers, error) {
+	tiers, err := s.b.SuggestPriceTiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	convert := func(tier *gasprice.PriceTier) *RPCPriceTier {
+		return &RPCPriceTier{
+			Price:  (*hexutil.Big)(tier.Price),
+			Blocks: hexutil.Uint64(tier.Blocks),
+			Wait:   hexutil.Uint64(tier.Wait / time.Second),
+		}
+	}
+	return &RPCPriceTiers{
+		Slow:     convert(tiers.Slow),
+		Standard: convert(tiers.Standard),
+		Fast:     convert(tiers.Fast),
+		Instant:  convert(tiers.Instant),
+	}, nil
+}
@@ -0,0 +1,338 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// defaultInnerTxPageSize is the number of inner transactions returned by a
+	// single address history query if no limit is requested.
+	defaultInnerTxPageSize = 100
+
+	// maxInnerTxPageSize is the maximum number of inner transactions a single
+	// address history query may return.
+	maxInnerTxPageSize = 1000
+
+	// maxInnerTxScanBlocks is the maximum number of blocks not yet covered by the
+	// address index that a single address history query scans one by one.
+	maxInnerTxScanBlocks = 1024
+)
+
+// innerTxsNotIndexedError is returned if the inner transactions of a block were
+// not recorded when it was imported, e.g. because recording was not enabled yet.
+type innerTxsNotIndexedError struct {
+	hash   common.Hash
+	number uint64
+}
+
+func (e *innerTxsNotIndexedError) Error() string {
+	return fmt.Sprintf("inner transactions of block #%d [%x..] not indexed", e.number, e.hash[:4])
+}
+
+// blockInnerTxs retrieves the inner transactions of all the transactions in the
+// given block, failing if the block was imported without recording them.
+func blockInnerTxs(ctx context.Context, b Backend, block *types.Block) ([]types.InnerTxs, error) {
+	innerTxs, err := b.GetInnerTxs(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if innerTxs == nil {
+		return nil, &innerTxsNotIndexedError{hash: block.Hash(), number: block.NumberU64()}
+	}
+	if len(innerTxs) != len(block.Transactions()) {
+		return nil, fmt.Errorf("inner transaction lists mismatch: have %d, want %d", len(innerTxs), len(block.Transactions()))
+	}
+	for i, tx := range block.Transactions() {
+		innerTxs[i].DeriveFields(block.Hash(), block.NumberU64(), tx, uint(i))
+	}
+	return innerTxs, nil
+}
+
+// GetInternalTransactionsByHash returns the value transfers and contract creations
+// made by the nested calls of the transaction with the given hash.
+func (s *PublicTransactionPoolAPI) GetInternalTransactionsByHash(ctx context.Context, hash common.Hash) ([]*types.InnerTx, error) {
+	tx, blockHash, _, index, err := s.b.GetTransaction(ctx, hash)
+	if tx == nil || err != nil {
+		return nil, err
+	}
+	block, err := s.b.BlockByHash(ctx, blockHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	innerTxs, err := blockInnerTxs(ctx, s.b, block)
+	if err != nil {
+		return nil, err
+	}
+	return innerTxs[index].ValueTransfers(), nil
+}
+
+// GetInternalTransactionsByBlockNumber returns the value transfers and contract
+// creations made by the nested calls of all the transactions in the given block.
+func (s *PublicTransactionPoolAPI) GetInternalTransactionsByBlockNumber(ctx context.Context, blockNr rpc.BlockNumber) ([]*types.InnerTx, error) {
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return s.blockInternalTransactions(ctx, block)
+}
+
+// GetInternalTransactionsByBlockHash returns the value transfers and contract
+// creations made by the nested calls of all the transactions in the given block.
+func (s *PublicTransactionPoolAPI) GetInternalTransactionsByBlockHash(ctx context.Context, blockHash common.Hash) ([]*types.InnerTx, error) {
+	block, err := s.b.BlockByHash(ctx, blockHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return s.blockInternalTransactions(ctx, block)
+}
+
+// blockInternalTransactions flattens the value transferring inner transactions
+// of a block into a single list, ordered by transaction index.
+func (s *PublicTransactionPoolAPI) blockInternalTransactions(ctx context.Context, block *types.Block) ([]*types.InnerTx, error) {
+	innerTxs, err := blockInnerTxs(ctx, s.b, block)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*types.InnerTx, 0)
+	for _, txs := range innerTxs {
+		result = append(result, txs.ValueTransfers()...)
+	}
+	return result, nil
+}
+
+// InternalTransactionsPage is a page of the inner transaction history of an
+// account. The cursor is nil on the last page, otherwise it can be passed back
+// to retrieve the next page.
+type InternalTransactionsPage struct {
+	Transactions []*types.InnerTx `json:"transactions"`
+	Cursor       *hexutil.Bytes   `json:"cursor"`
+}
+
+// GetInternalTransactionsByAddress returns the value transfers and contract
+// creations made by nested calls in the given block range, which were sent or
+// received by the given account. Results are paginated, the cursor of the last
+// page resuming the query where it left off. A page may hold fewer results than
+// the limit if the scan of the blocks not yet indexed was cut short, in which case
+// it still carries a cursor.
+func (s *PublicTransactionPoolAPI) GetInternalTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, cursor *hexutil.Bytes, limit *hexutil.Uint64) (*InternalTransactionsPage, error) {
+	head := s.b.CurrentHeader().Number.Uint64()
+	begin, end := resolveBlockNumber(fromBlock, head), resolveBlockNumber(toBlock, head)
+	if end > head {
+		end = head
+	}
+	if begin > end {
+		return nil, errors.New("invalid block range")
+	}
+	count := defaultInnerTxPageSize
+	if limit != nil {
+		if *limit == 0 || *limit > maxInnerTxPageSize {
+			return nil, fmt.Errorf("invalid limit %d, must be between 1 and %d", *limit, maxInnerTxPageSize)
+		}
+		count = int(*limit)
+	}
+	start := rawdb.InnerTxPosition{Number: begin}
+	if cursor != nil {
+		pos, err := decodeInnerTxCursor(*cursor)
+		if err != nil {
+			return nil, err
+		}
+		if pos.Number < begin || pos.Number > end {
+			return nil, errors.New("cursor outside of block range")
+		}
+		start = pos
+	}
+	// Collect one result more than requested to find the start of the next page
+	c := &innerTxCollector{b: s.b, address: address, limit: count + 1}
+	if err := c.collect(ctx, start, end); err != nil {
+		return nil, err
+	}
+	page := &InternalTransactionsPage{Transactions: c.txs}
+	switch {
+	case len(c.txs) > count:
+		next := encodeInnerTxCursor(c.positions[count])
+		page.Transactions, page.Cursor = c.txs[:count], &next
+	case c.resume != nil:
+		next := encodeInnerTxCursor(*c.resume)
+		page.Cursor = &next
+	}
+	return page, nil
+}
+
+// resolveBlockNumber converts an rpc block number into an absolute one, mapping
+// the latest and pending tags to the current head.
+func resolveBlockNumber(number rpc.BlockNumber, head uint64) uint64 {
+	if number < 0 {
+		return head
+	}
+	return uint64(number)
+}
+
+// encodeInnerTxCursor encodes the position of an inner transaction into an
+// opaque pagination cursor.
+func encodeInnerTxCursor(pos rawdb.InnerTxPosition) hexutil.Bytes {
+	cursor := make([]byte, 16)
+	binary.BigEndian.PutUint64(cursor[0:], pos.Number)
+	binary.BigEndian.PutUint32(cursor[8:], pos.TxIndex)
+	binary.BigEndian.PutUint32(cursor[12:], pos.CallIndex)
+	return cursor
+}
+
+// decodeInnerTxCursor decodes a pagination cursor into the position of the inner
+// transaction to resume the query at.
+func decodeInnerTxCursor(cursor []byte) (rawdb.InnerTxPosition, error) {
+	if len(cursor) != 16 {
+		return rawdb.InnerTxPosition{}, errors.New("invalid cursor")
+	}
+	return rawdb.InnerTxPosition{
+		Number:    binary.BigEndian.Uint64(cursor[0:]),
+		TxIndex:   binary.BigEndian.Uint32(cursor[8:]),
+		CallIndex: binary.BigEndian.Uint32(cursor[12:]),
+	}, nil
+}
+
+// innerTxCollector gathers the inner transactions involving an account, using
+// the address index for the indexed sections of the chain and scanning the
+// remaining blocks one by one.
+type innerTxCollector struct {
+	b       Backend
+	address common.Address
+	limit   int
+
+	txs       []*types.InnerTx
+	positions []rawdb.InnerTxPosition
+	resume    *rawdb.InnerTxPosition // Position to resume at if the block scan was cut short
+
+	block    *types.Block     // Last block whose inner transactions were loaded
+	innerTxs []types.InnerTxs // Inner transactions of the last loaded block
+}
+
+// collect gathers inner transactions from the start position until the end block
+// (inclusive) or until the limit is reached. Blocks imported without recording
+// their inner transactions are skipped, same as by the address index.
+func (c *innerTxCollector) collect(ctx context.Context, start rawdb.InnerTxPosition, end uint64) error {
+	size, sections := c.b.InnerTxIndexStatus()
+	indexed := size * sections
+
+	// Retrieve the indexed part of the range from the address index
+	for start.Number < indexed && start.Number <= end && len(c.txs) < c.limit {
+		stop := indexed
+		if end+1 < stop {
+			stop = end + 1
+		}
+		want := c.limit - len(c.txs)
+		entries := rawdb.ReadInnerTxAddressEntries(c.b.ChainDb(), c.address, start, stop, want)
+		for _, entry := range entries {
+			// Skip entries left behind by blocks reorged out of the chain
+			if rawdb.ReadCanonicalHash(c.b.ChainDb(), entry.Number) != entry.BlockHash {
+				continue
+			}
+			if err := c.load(ctx, entry.BlockHash); err != nil {
+				return err
+			}
+			if int(entry.TxIndex) >= len(c.innerTxs) || int(entry.CallIndex) >= len(c.innerTxs[entry.TxIndex]) {
+				return fmt.Errorf("inner transaction index of block #%d [%x..] corrupted", entry.Number, entry.BlockHash[:4])
+			}
+			c.add(entry.InnerTxPosition, c.innerTxs[entry.TxIndex][entry.CallIndex])
+		}
+		if len(entries) < want {
+			start = rawdb.InnerTxPosition{Number: stop}
+			break
+		}
+		last := entries[len(entries)-1].InnerTxPosition
+		start = rawdb.InnerTxPosition{Number: last.Number, TxIndex: last.TxIndex, CallIndex: last.CallIndex + 1}
+	}
+	// Scan the unindexed blocks directly, up to a limit
+	for number := start.Number; number <= end && len(c.txs) < c.limit; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if number-start.Number == maxInnerTxScanBlocks {
+			c.resume = &rawdb.InnerTxPosition{Number: number}
+			return nil
+		}
+		block, err := c.b.BlockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			return fmt.Errorf("block #%d not found", number)
+		}
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		if err := c.load(ctx, block.Hash()); err != nil {
+			if _, ok := err.(*innerTxsNotIndexedError); ok {
+				continue
+			}
+			return err
+		}
+		for i, txs := range c.innerTxs {
+			for j, tx := range txs {
+				pos := rawdb.InnerTxPosition{Number: number, TxIndex: uint32(i), CallIndex: uint32(j)}
+				if number == start.Number && (pos.TxIndex < start.TxIndex || (pos.TxIndex == start.TxIndex && pos.CallIndex < start.CallIndex)) {
+					continue
+				}
+				if !tx.IsValueTransfer() || (tx.From != c.address && tx.To != c.address) {
+					continue
+				}
+				if len(c.txs) == c.limit {
+					return nil
+				}
+				c.add(pos, tx)
+			}
+		}
+	}
+	return nil
+}
+
+// load retrieves the inner transactions of the block with the given hash, unless
+// they are already loaded.
+func (c *innerTxCollector) load(ctx context.Context, hash common.Hash) error {
+	if c.block != nil && c.block.Hash() == hash {
+		return nil
+	}
+	block, err := c.b.BlockByHash(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if block == nil {
+		return fmt.Errorf("block %x not found", hash)
+	}
+	innerTxs, err := blockInnerTxs(ctx, c.b, block)
+	if err != nil {
+		return err
+	}
+	c.block, c.innerTxs = block, innerTxs
+	return nil
+}
+
+// add appends an inner transaction to the collected results.
+func (c *innerTxCollector) add(pos rawdb.InnerTxPosition, tx *types.InnerTx) {
+	c.txs = append(c.txs, tx)
+	c.positions = append(c.positions, pos)
+}
@@ -0,0 +1,244 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// tokenTransferPageSize is the number of token transfers returned by a single
+// token transfer history query.
+const tokenTransferPageSize = 100
+
+// errTokenIndexDisabled is returned if token transfers are requested from a node
+// not indexing them.
+var errTokenIndexDisabled = errors.New("token transfer index not enabled")
+
+// RPCTokenTransfer is a token transfer as returned over RPC. The token identifier
+// is omitted for ERC-20 transfers.
+type RPCTokenTransfer struct {
+	Standard    string         `json:"standard"`
+	Token       common.Address `json:"token"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	TokenID     *hexutil.Big   `json:"tokenId,omitempty"`
+	Value       *hexutil.Big   `json:"value"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+	BatchIndex  hexutil.Uint   `json:"batchIndex"`
+}
+
+// newRPCTokenTransfer converts a token transfer into its RPC representation.
+func newRPCTokenTransfer(transfer *types.TokenTransfer) *RPCTokenTransfer {
+	result := &RPCTokenTransfer{
+		Standard:    transfer.Standard,
+		Token:       transfer.Token,
+		From:        transfer.From,
+		To:          transfer.To,
+		Value:       (*hexutil.Big)(transfer.Value),
+		TxHash:      transfer.TxHash,
+		TxIndex:     hexutil.Uint(transfer.TxIndex),
+		BlockHash:   transfer.BlockHash,
+		BlockNumber: hexutil.Uint64(transfer.BlockNumber),
+		LogIndex:    hexutil.Uint(transfer.LogIndex),
+		BatchIndex:  hexutil.Uint(transfer.BatchIndex),
+	}
+	if transfer.Standard != types.TokenERC20 {
+		result.TokenID = (*hexutil.Big)(transfer.TokenID)
+	}
+	return result
+}
+
+// TokenTransfersPage is a page of the token transfer history of a holder. The
+// cursor is nil on the last page, otherwise it can be passed back to retrieve
+// the next page.
+type TokenTransfersPage struct {
+	Transfers []*RPCTokenTransfer `json:"transfers"`
+	Cursor    *hexutil.Bytes      `json:"cursor"`
+}
+
+// GetTokenTransfers returns the ERC-20, ERC-721 and ERC-1155 token transfers in
+// the given block range which were sent or received by the given holder,
+// optionally restricted to a single token contract. Results are paginated, the
+// cursor of the last page resuming the query where it left off.
+func (s *PublicBlockChainAPI) GetTokenTransfers(ctx context.Context, holder common.Address, token *common.Address, fromBlock, toBlock rpc.BlockNumber, cursor *hexutil.Bytes) (*TokenTransfersPage, error) {
+	size, sections := s.b.TokenTransferIndexStatus()
+	if size == 0 {
+		return nil, errTokenIndexDisabled
+	}
+	head := s.b.CurrentHeader().Number.Uint64()
+	begin, end := resolveBlockNumber(fromBlock, head), resolveBlockNumber(toBlock, head)
+	if end > head {
+		end = head
+	}
+	if begin > end {
+		return nil, errors.New("invalid block range")
+	}
+	start := rawdb.TokenTransferPosition{Number: begin}
+	if cursor != nil {
+		pos, err := decodeTokenTransferCursor(*cursor)
+		if err != nil {
+			return nil, err
+		}
+		if pos.Number < begin || pos.Number > end {
+			return nil, errors.New("cursor outside of block range")
+		}
+		start = pos
+	}
+	// Collect one result more than requested to find the start of the next page
+	c := &tokenTransferCollector{b: s.b, holder: holder, token: token, limit: tokenTransferPageSize + 1}
+	if err := c.collect(ctx, start, end, size*sections); err != nil {
+		return nil, err
+	}
+	page := &TokenTransfersPage{Transfers: make([]*RPCTokenTransfer, 0, len(c.transfers))}
+	for _, transfer := range c.transfers {
+		page.Transfers = append(page.Transfers, newRPCTokenTransfer(transfer))
+	}
+	if len(c.transfers) > tokenTransferPageSize {
+		next := encodeTokenTransferCursor(tokenTransferPosition(c.transfers[tokenTransferPageSize]))
+		page.Transfers, page.Cursor = page.Transfers[:tokenTransferPageSize], &next
+	}
+	return page, nil
+}
+
+// tokenTransferPosition returns the position of a token transfer in the chain.
+func tokenTransferPosition(transfer *types.TokenTransfer) rawdb.TokenTransferPosition {
+	return rawdb.TokenTransferPosition{
+		Number:     transfer.BlockNumber,
+		LogIndex:   uint32(transfer.LogIndex),
+		BatchIndex: uint32(transfer.BatchIndex),
+	}
+}
+
+// encodeTokenTransferCursor encodes the position of a token transfer into an
+// opaque pagination cursor.
+func encodeTokenTransferCursor(pos rawdb.TokenTransferPosition) hexutil.Bytes {
+	cursor := make([]byte, 16)
+	binary.BigEndian.PutUint64(cursor[0:], pos.Number)
+	binary.BigEndian.PutUint32(cursor[8:], pos.LogIndex)
+	binary.BigEndian.PutUint32(cursor[12:], pos.BatchIndex)
+	return cursor
+}
+
+// decodeTokenTransferCursor decodes a pagination cursor into the position of the
+// token transfer to resume the query at.
+func decodeTokenTransferCursor(cursor []byte) (rawdb.TokenTransferPosition, error) {
+	if len(cursor) != 16 {
+		return rawdb.TokenTransferPosition{}, errors.New("invalid cursor")
+	}
+	return rawdb.TokenTransferPosition{
+		Number:     binary.BigEndian.Uint64(cursor[0:]),
+		LogIndex:   binary.BigEndian.Uint32(cursor[8:]),
+		BatchIndex: binary.BigEndian.Uint32(cursor[12:]),
+	}, nil
+}
+
+// tokenTransferCollector gathers the token transfers of a holder, using the token
+// transfer index for the indexed sections of the chain and scanning the logs of
+// the remaining blocks one by one.
+type tokenTransferCollector struct {
+	b      Backend
+	holder common.Address
+	token  *common.Address
+	limit  int
+
+	transfers []*types.TokenTransfer
+}
+
+// collect gathers token transfers from the start position until the end block
+// (inclusive) or until the limit is reached. Blocks below the indexed number are
+// retrieved from the index, which is rolled back on reorgs and thus needs no
+// verification against the canonical chain.
+func (c *tokenTransferCollector) collect(ctx context.Context, start rawdb.TokenTransferPosition, end uint64, indexed uint64) error {
+	for start.Number < indexed && start.Number <= end && len(c.transfers) < c.limit {
+		stop := indexed
+		if end+1 < stop {
+			stop = end + 1
+		}
+		want := c.limit - len(c.transfers)
+		transfers := rawdb.ReadTokenTransfers(c.b.ChainDb(), c.holder, start, stop, want)
+		for _, transfer := range transfers {
+			c.add(transfer)
+		}
+		if len(transfers) < want {
+			start = rawdb.TokenTransferPosition{Number: stop}
+			break
+		}
+		start = tokenTransferPosition(transfers[len(transfers)-1])
+		start.BatchIndex++
+	}
+	// Scan the unindexed blocks directly, skipping the ones whose bloom filter
+	// rules out any event of the holder
+	topic := common.BytesToHash(c.holder.Bytes())
+	for number := start.Number; number <= end && len(c.transfers) < c.limit; number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		header, err := c.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			return fmt.Errorf("block #%d not found", number)
+		}
+		if !types.BloomLookup(header.Bloom, topic) || (c.token != nil && !types.BloomLookup(header.Bloom, *c.token)) {
+			continue
+		}
+		receipts, err := c.b.GetReceipts(ctx, header.Hash())
+		if err != nil {
+			return err
+		}
+		for _, receipt := range receipts {
+			for _, log := range receipt.Logs {
+				for _, transfer := range types.DecodeTokenTransfers(log) {
+					if number == start.Number && (uint32(transfer.LogIndex) < start.LogIndex || (uint32(transfer.LogIndex) == start.LogIndex && uint32(transfer.BatchIndex) < start.BatchIndex)) {
+						continue
+					}
+					if transfer.From != c.holder && transfer.To != c.holder {
+						continue
+					}
+					if len(c.transfers) == c.limit {
+						return nil
+					}
+					c.add(transfer)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// add appends a token transfer to the collected results, unless it's filtered
+// out by the requested token.
+func (c *tokenTransferCollector) add(transfer *types.TokenTransfer) {
+	if c.token != nil && transfer.Token != *c.token {
+		return
+	}
+	c.transfers = append(c.transfers, transfer)
+}
@@ -31,6 +31,7 @@ var Modules = map[string]string{
 	"rpc":        RpcJs,
 	"shh":        ShhJs,
 	"swarmfs":    SwarmfsJs,
+	"trace":      TraceJs,
 	"txpool":     TxpoolJs,
 	"les":        LESJs,
 	"lespay":     LESPayJs,
@@ -206,6 +207,10 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'reloadAccessControl',
+			call: 'admin_reloadAccessControl'
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -437,6 +442,55 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, null, null]
 		}),
+		new web3._extend.Method({
+			name: 'startTraceJob',
+			call: 'debug_startTraceJob',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'traceJobStatus',
+			call: 'debug_traceJobStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'traceJobs',
+			call: 'debug_traceJobs',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'cancelTraceJob',
+			call: 'debug_cancelTraceJob',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'resumeTraceJob',
+			call: 'debug_resumeTraceJob',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'coverage',
+			call: 'debug_coverage',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'balanceChanges',
+			call: 'debug_balanceChanges',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'registerSourceArtifact',
+			call: 'debug_registerSourceArtifact',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'replayModifiedTransaction',
+			call: 'debug_replayModifiedTransaction',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -563,6 +617,59 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'eth_getBlockReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactions',
+			call: 'eth_getInternalTransactionsByHash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getBlockInternalTransactions',
+			call: function(args) {
+				return (web3._extend.utils.isString(args[0]) && args[0].indexOf('0x') === 0 && args[0].length === 66) ? 'eth_getInternalTransactionsByBlockHash' : 'eth_getInternalTransactionsByBlockNumber';
+			},
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactionsByAddress',
+			call: 'eth_getInternalTransactionsByAddress',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getContractCreator',
+			call: 'eth_getContractCreator',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'gasPriceTiers',
+			call: 'eth_gasPriceTiers',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'feeStatistics',
+			call: 'eth_feeStatistics',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTokenTransfers',
+			call: 'eth_getTokenTransfers',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'callBundle',
+			call: 'eth_callBundle',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -752,6 +859,42 @@ web3._extend({
 });
 `
 
+const TraceJs = `
+web3._extend({
+	property: 'trace',
+	methods: [
+		new web3._extend.Method({
+			name: 'block',
+			call: 'trace_block',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'transaction',
+			call: 'trace_transaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'replayTransaction',
+			call: 'trace_replayTransaction',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'filter',
+			call: 'trace_filter',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'call',
+			call: 'trace_call',
+			params: 3,
+			inputFormatter: [null, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+	],
+	properties: []
+});
+`
+
 const TxpoolJs = `
 web3._extend({
 	property: 'txpool',
@@ -156,6 +156,22 @@ func (b *LesApiBackend) GetReceipts(ctx context.Context, hash common.Hash) (type
 	return nil, nil
 }
 
+func (b *LesApiBackend) GetInnerTxs(ctx context.Context, hash common.Hash) ([]types.InnerTxs, error) {
+	return nil, errors.New("inner transactions are not available in light mode")
+}
+
+func (b *LesApiBackend) InnerTxIndexStatus() (uint64, uint64) {
+	return 0, 0
+}
+
+func (b *LesApiBackend) ContractCreationIndexStatus() (uint64, uint64) {
+	return 0, 0
+}
+
+func (b *LesApiBackend) TokenTransferIndexStatus() (uint64, uint64) {
+	return 0, 0
+}
+
 func (b *LesApiBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
 	if number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash); number != nil {
 		return light.GetBlockLogs(ctx, b.eth.odr, hash, *number)
@@ -251,6 +267,18 @@ func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+func (b *LesApiBackend) FeeStatistics(ctx context.Context, first, last uint64) ([]*gasprice.BlockFees, error) {
+	return b.gpo.FeeStatistics(ctx, first, last)
+}
+
+func (b *LesApiBackend) SuggestPriceTiers(ctx context.Context) (*gasprice.PriceTiers, error) {
+	pending, err := b.GetPoolTransactions()
+	if err != nil {
+		return nil, err
+	}
+	return b.gpo.SuggestTiers(ctx, pending)
+}
+
 func (b *LesApiBackend) ChainDb() ethdb.Database {
 	return b.eth.chainDb
 }
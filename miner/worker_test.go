@@ -117,7 +117,7 @@ func newTestWorkerBackend(t *testing.T, chainConfig *params.ChainConfig, engine
 	}
 	genesis := gspec.MustCommit(db)
 
-	chain, _ := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec.Config, engine, vm.Config{}, nil, nil)
+	chain, _ := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, gspec.Config, engine, vm.Config{RecordInnerTxs: true}, nil, nil)
 	txpool := core.NewTxPool(testTxPoolConfig, chainConfig, chain)
 
 	// Generate a small n-block chain and an uncle block for it
@@ -236,6 +236,9 @@ func testGenerateBlockAndImport(t *testing.T, isClique bool) {
 		select {
 		case ev := <-sub.Chan():
 			block := ev.Data.(core.NewMinedBlockEvent).Block
+			if innerTxs := rawdb.ReadInnerTxs(db, block.Hash(), block.NumberU64()); len(innerTxs) != len(block.Transactions()) {
+				t.Errorf("mined block %d: inner txs mismatch: have %d, want %d", block.NumberU64(), len(innerTxs), len(block.Transactions()))
+			}
 			if _, err := chain.InsertChain([]*types.Block{block}); err != nil {
 				t.Fatalf("failed to insert new mined block %d: %v", block.NumberU64(), err)
 			}
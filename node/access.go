@@ -0,0 +1,288 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"golang.org/x/time/rate"
+)
+
+// accessKeyHeader is the HTTP header carrying the API key of a request. The key
+// may also be given as the request path instead, e.g. http://host:8545/<key>.
+const accessKeyHeader = "X-API-Key"
+
+var (
+	errInvalidAPIKey    = &accessError{-32004, "invalid API key"}
+	errRateLimited      = &accessError{-32005, "request rate limit exceeded"}
+	errBudgetExhausted  = &accessError{-32005, "daily compute budget exhausted"}
+	errAccessNotEnabled = errors.New("API key access control is not enabled")
+)
+
+// accessError is the error returned to the caller of a rejected method call.
+type accessError struct {
+	code    int
+	message string
+}
+
+func (e *accessError) ErrorCode() int { return e.code }
+
+func (e *accessError) Error() string { return e.message }
+
+// AccessKey is an API key entry of the access control configuration file.
+type AccessKey struct {
+	Name        string   `json:"name"`        // identifies the key in logs and metrics
+	Key         string   `json:"key"`         // secret sent by the clients
+	Namespaces  []string `json:"namespaces"`  // namespaces whose methods may be called
+	Methods     []string `json:"methods"`     // methods (or prefixes ending in '*') which may be called
+	Rate        float64  `json:"rate"`        // requests per second, zero for unlimited
+	DailyBudget uint64   `json:"dailyBudget"` // compute units per UTC day, zero for unlimited
+}
+
+// AccessConfig is the content of the access control configuration file. If a
+// key allows neither namespaces nor methods, all methods of the endpoint are
+// allowed for it.
+type AccessConfig struct {
+	Keys        []AccessKey       `json:"keys"`
+	Costs       map[string]uint64 `json:"costs"`       // compute units per method (or prefix ending in '*')
+	DefaultCost uint64            `json:"defaultCost"` // compute units of the methods without configured cost
+}
+
+// accessClient is the runtime state of an API key.
+type accessClient struct {
+	AccessKey
+	limiter *rate.Limiter // nil if the request rate is unlimited
+	day     int64         // UTC day of the budget usage
+	used    uint64        // compute units used on the day
+
+	requestMeter metrics.Meter
+	deniedMeter  metrics.Meter
+	limitedMeter metrics.Meter
+	computeMeter metrics.Meter
+	usedGauge    metrics.Gauge
+}
+
+// allowed reports whether the key may call the given method.
+func (c *accessClient) allowed(method string) bool {
+	if len(c.Namespaces) == 0 && len(c.Methods) == 0 {
+		return true
+	}
+	namespace := method
+	if i := strings.IndexByte(method, '_'); i >= 0 {
+		namespace = method[:i]
+	}
+	for _, ns := range c.Namespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	for _, pattern := range c.Methods {
+		if matchMethod(pattern, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// accessController authenticates the requests to the HTTP and websocket RPC
+// endpoints by API key, and restricts the calls of every key to its allowed
+// methods, request rate and daily compute budget. The configuration is loaded
+// from a file and may be reloaded at runtime, retaining the usage of the keys.
+type accessController struct {
+	path string
+	now  func() time.Time // overridden in tests
+
+	mu          sync.Mutex
+	clients     map[string]*accessClient // API key -> client
+	costs       map[string]uint64
+	defaultCost uint64
+}
+
+// newAccessController creates an access controller from the configuration file.
+func newAccessController(path string) (*accessController, error) {
+	ac := &accessController{path: path, now: time.Now}
+	if err := ac.reload(); err != nil {
+		return nil, err
+	}
+	return ac, nil
+}
+
+// reload reloads the configuration file. The usage of the keys present before
+// and after the reload is retained.
+func (ac *accessController) reload() error {
+	var config AccessConfig
+	if err := common.LoadJSON(ac.path, &config); err != nil {
+		return fmt.Errorf("can't load access control config: %v", err)
+	}
+	var (
+		names = make(map[string]bool)
+		keys  = make(map[string]bool)
+	)
+	for _, key := range config.Keys {
+		switch {
+		case key.Key == "" || key.Name == "":
+			return errors.New("access control config: API keys need a name and a key")
+		case names[key.Name]:
+			return fmt.Errorf("access control config: duplicate key name %q", key.Name)
+		case keys[key.Key]:
+			return fmt.Errorf("access control config: duplicate key of %q", key.Name)
+		case key.Rate < 0:
+			return fmt.Errorf("access control config: negative rate of key %q", key.Name)
+		}
+		names[key.Name], keys[key.Key] = true, true
+	}
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	clients := make(map[string]*accessClient, len(config.Keys))
+	for _, key := range config.Keys {
+		client := ac.clients[key.Key]
+		if client == nil || client.Name != key.Name {
+			prefix := "rpc/access/" + key.Name + "/"
+			client = &accessClient{
+				requestMeter: metrics.GetOrRegisterMeter(prefix+"requests", nil),
+				deniedMeter:  metrics.GetOrRegisterMeter(prefix+"denied", nil),
+				limitedMeter: metrics.GetOrRegisterMeter(prefix+"limited", nil),
+				computeMeter: metrics.GetOrRegisterMeter(prefix+"compute", nil),
+				usedGauge:    metrics.GetOrRegisterGauge(prefix+"budget/used", nil),
+			}
+		}
+		client.AccessKey = key
+		switch {
+		case key.Rate == 0:
+			client.limiter = nil
+		case client.limiter == nil || client.limiter.Limit() != rate.Limit(key.Rate):
+			client.limiter = rate.NewLimiter(rate.Limit(key.Rate), int(math.Ceil(key.Rate)))
+		}
+		clients[key.Key] = client
+	}
+	ac.clients, ac.costs, ac.defaultCost = clients, config.Costs, config.DefaultCost
+	log.Info("Loaded RPC access control config", "path", ac.path, "keys", len(clients))
+	return nil
+}
+
+// cost returns the compute units of a method call. Exact method entries take
+// precedence over the longest matching prefix. The caller must hold ac.mu.
+func (ac *accessController) cost(method string) uint64 {
+	if cost, ok := ac.costs[method]; ok {
+		return cost
+	}
+	var (
+		cost    = ac.defaultCost
+		longest = -1
+	)
+	for pattern, c := range ac.costs {
+		if strings.HasSuffix(pattern, "*") && len(pattern) > longest && matchMethod(pattern, method) {
+			cost, longest = c, len(pattern)
+		}
+	}
+	return cost
+}
+
+// known reports whether the API key is configured.
+func (ac *accessController) known(key string) bool {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	return ac.clients[key] != nil
+}
+
+// authorize is the rpc.CallAuthorizer checking the calls against the limits of
+// the API key of the request.
+func (ac *accessController) authorize(ctx context.Context, method string) error {
+	key, _ := ctx.Value(accessKeyContextKey{}).(string)
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	client := ac.clients[key]
+	if client == nil {
+		return errInvalidAPIKey
+	}
+	if !client.allowed(method) {
+		client.deniedMeter.Mark(1)
+		return &accessError{-32004, fmt.Sprintf("method %s is not allowed", method)}
+	}
+	now := ac.now()
+	if client.limiter != nil && !client.limiter.AllowN(now, 1) {
+		client.limitedMeter.Mark(1)
+		return errRateLimited
+	}
+	if day := now.Unix() / 86400; day != client.day {
+		client.day, client.used = day, 0
+	}
+	cost := ac.cost(method)
+	if client.DailyBudget != 0 && client.used+cost > client.DailyBudget {
+		client.limitedMeter.Mark(1)
+		return errBudgetExhausted
+	}
+	client.used += cost
+	client.requestMeter.Mark(1)
+	client.computeMeter.Mark(int64(cost))
+	client.usedGauge.Update(int64(client.used))
+	return nil
+}
+
+// accessKeyContextKey is the context key of the API key of a request.
+type accessKeyContextKey struct{}
+
+// requestKey returns the API key of a request, taken from the header if present
+// or the request path otherwise.
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(accessKeyHeader); key != "" {
+		return key
+	}
+	return strings.TrimPrefix(r.URL.Path, "/")
+}
+
+// isKeyPath reports whether the request path is a configured API key.
+func (ac *accessController) isKeyPath(r *http.Request) bool {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	return key != "" && ac.known(key)
+}
+
+// newAccessHandler creates a http.Handler rejecting the requests without a valid
+// API key, and passing the key to the RPC handler next through the request context.
+func newAccessHandler(ac *accessController, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := requestKey(r)
+		if !ac.known(key) {
+			http.Error(w, errInvalidAPIKey.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accessKeyContextKey{}, key)))
+	})
+}
+
+// matchMethod reports whether the method matches the pattern, which is either a
+// method name or a prefix ending in '*'.
+func matchMethod(pattern, method string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(method, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == method
+}
@@ -0,0 +1,226 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const testAccessConfig = `{
+	"keys": [
+		{"name": "alice", "key": "alicekey", "namespaces": ["eth"], "methods": ["debug_trace*"], "rate": 2},
+		{"name": "bob", "key": "bobkey", "dailyBudget": 23}
+	],
+	"costs": {"debug_trace*": 10, "debug_traceBlock": 20},
+	"defaultCost": 1
+}`
+
+// writeAccessConfig writes the access control config into a file of the directory.
+func writeAccessConfig(t *testing.T, dir, config string) string {
+	path := filepath.Join(dir, "access.json")
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAccessControllerAuthorize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ac, err := newAccessController(writeAccessConfig(t, dir, testAccessConfig))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	now := time.Unix(1600000000, 0)
+	ac.now = func() time.Time { return now }
+
+	authorize := func(key, method string) error {
+		return ac.authorize(context.WithValue(context.Background(), accessKeyContextKey{}, key), method)
+	}
+	tests := []struct {
+		key, method string
+		advance     time.Duration
+		err         string
+	}{
+		// Allowed namespaces and method patterns, within the request rate
+		{key: "alicekey", method: "eth_blockNumber"},
+		{key: "alicekey", method: "debug_traceTransaction"},
+		{key: "alicekey", method: "debug_getBadBlocks", err: "method debug_getBadBlocks is not allowed"},
+		{key: "alicekey", method: "eth_chainId", err: errRateLimited.Error()},
+		{key: "alicekey", method: "eth_chainId", advance: time.Second},
+		{key: "carolkey", method: "eth_chainId", err: errInvalidAPIKey.Error()},
+		// Daily budget weighted by the method costs
+		{key: "bobkey", method: "debug_traceBlock"},
+		{key: "bobkey", method: "debug_traceTransaction", err: errBudgetExhausted.Error()},
+		{key: "bobkey", method: "eth_blockNumber"},
+		{key: "bobkey", method: "eth_chainId"},
+		{key: "bobkey", method: "eth_chainId"},
+		{key: "bobkey", method: "eth_chainId", err: errBudgetExhausted.Error()},
+		{key: "bobkey", method: "debug_traceTransaction", advance: 24 * time.Hour},
+	}
+	for i, test := range tests {
+		now = now.Add(test.advance)
+		err := authorize(test.key, test.method)
+		if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, test.err)
+		}
+	}
+	// Reloading retains the usage of the remaining keys and drops removed ones.
+	writeAccessConfig(t, dir, `{"keys": [{"name": "bob", "key": "bobkey", "dailyBudget": 11}], "defaultCost": 1}`)
+	if err := ac.reload(); err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if err := authorize("bobkey", "eth_chainId"); err != nil {
+		t.Errorf("call within reloaded budget failed: %v", err)
+	}
+	if err := authorize("bobkey", "eth_chainId"); err != errBudgetExhausted {
+		t.Errorf("error mismatch after reload: have %v, want %v", err, errBudgetExhausted)
+	}
+	if err := authorize("alicekey", "eth_chainId"); err != errInvalidAPIKey {
+		t.Errorf("error mismatch for removed key: have %v, want %v", err, errInvalidAPIKey)
+	}
+	// Invalid configs are rejected, keeping the previous one.
+	writeAccessConfig(t, dir, `{"keys": [{"name": "bob", "key": "k"}, {"name": "bob", "key": "l"}]}`)
+	if err := ac.reload(); err == nil {
+		t.Error("config with duplicate names loaded")
+	}
+	if !ac.known("bobkey") {
+		t.Error("previous config dropped by failed reload")
+	}
+}
+
+type accessTestService struct{}
+
+func (s *accessTestService) Echo(str string) string { return str }
+
+func TestAccessControlEndpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ac, err := newAccessController(writeAccessConfig(t, dir, `{"keys": [{"name": "alice", "key": "alicekey", "namespaces": ["test"]}]}`))
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	var (
+		apis     = []rpc.API{{Namespace: "test", Service: new(accessTestService), Public: true}}
+		endpoint = rpcEndpointConfig{access: ac}
+		srv      = newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	)
+	if err := srv.enableRPC(apis, httpConfig{rpcEndpointConfig: endpoint}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.enableWS(apis, wsConfig{Origins: []string{"*"}, rpcEndpointConfig: endpoint}); err != nil {
+		t.Fatal(err)
+	}
+	srv.mux.Handle("/graphql", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	if err := srv.setListenAddr("localhost", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.stop()
+
+	callWith := func(method string) func(*rpc.Client, error) error {
+		return func(client *rpc.Client, err error) error {
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+			var result interface{}
+			return client.Call(&result, method, "hello")
+		}
+	}
+	echo := callWith("test_echo")
+	var (
+		ctx     = context.Background()
+		httpURL = "http://" + srv.listenAddr()
+		wsURL   = "ws://" + srv.listenAddr()
+	)
+	// API keys given as the request path
+	if err := echo(rpc.DialHTTP(httpURL + "/alicekey")); err != nil {
+		t.Errorf("HTTP call with key path failed: %v", err)
+	}
+	if err := echo(rpc.DialWebsocket(ctx, wsURL+"/alicekey", "")); err != nil {
+		t.Errorf("websocket call with key path failed: %v", err)
+	}
+	// API keys given as header
+	client, err := rpc.DialHTTP(httpURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetHeader(accessKeyHeader, "alicekey")
+	if err := echo(client, nil); err != nil {
+		t.Errorf("HTTP call with key header failed: %v", err)
+	}
+	// Calls outside of the allowed namespaces are rejected with a JSON-RPC error
+	err = callWith("rpc_modules")(rpc.DialWebsocket(ctx, wsURL+"/alicekey", ""))
+	if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32004 {
+		t.Errorf("disallowed websocket call error mismatch: have %v, want method not allowed", err)
+	}
+	// Requests with missing or unknown keys are rejected
+	if err := echo(rpc.DialHTTP(httpURL)); err == nil {
+		t.Error("HTTP call without key succeeded")
+	}
+	if err := echo(rpc.DialHTTP(httpURL + "/bobkey")); err == nil {
+		t.Error("HTTP call with unknown key succeeded")
+	}
+	if err := echo(rpc.DialWebsocket(ctx, wsURL, "")); err == nil {
+		t.Error("websocket call without key succeeded")
+	}
+	// Handlers registered on the mux require a key as well
+	get := func(key string) int {
+		req, _ := http.NewRequest("GET", httpURL+"/graphql", nil)
+		if key != "" {
+			req.Header.Set(accessKeyHeader, key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := get(""); status != http.StatusUnauthorized {
+		t.Errorf("handler request without key status mismatch: have %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := get("bobkey"); status != http.StatusUnauthorized {
+		t.Errorf("handler request with unknown key status mismatch: have %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := get("alicekey"); status != http.StatusOK {
+		t.Errorf("handler request with key status mismatch: have %d, want %d", status, http.StatusOK)
+	}
+}
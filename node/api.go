@@ -180,10 +180,15 @@ func (api *privateAdminAPI) StartRPC(host *string, port *int, cors *string, apis
 	}
 
 	// Determine config.
+	endpointConfig, err := api.node.rpcEndpointConfig(api.node.config.HTTPJWTAuth)
+	if err != nil {
+		return false, err
+	}
 	config := httpConfig{
 		CorsAllowedOrigins: api.node.config.HTTPCors,
 		Vhosts:             api.node.config.HTTPVirtualHosts,
 		Modules:            api.node.config.HTTPModules,
+		rpcEndpointConfig:  endpointConfig,
 	}
 	if cors != nil {
 		config.CorsAllowedOrigins = nil
@@ -240,9 +245,14 @@ func (api *privateAdminAPI) StartWS(host *string, port *int, allowedOrigins *str
 	}
 
 	// Determine config.
+	endpointConfig, err := api.node.rpcEndpointConfig(api.node.config.WSJWTAuth)
+	if err != nil {
+		return false, err
+	}
 	config := wsConfig{
-		Modules: api.node.config.WSModules,
-		Origins: api.node.config.WSOrigins,
+		Modules:           api.node.config.WSModules,
+		Origins:           api.node.config.WSOrigins,
+		rpcEndpointConfig: endpointConfig,
 		// ExposeAll: api.node.config.WSExposeAll,
 	}
 	if apis != nil {
@@ -280,6 +290,18 @@ func (api *privateAdminAPI) StopWS() (bool, error) {
 	return true, nil
 }
 
+// ReloadAccessControl reloads the API keys of the HTTP and WebSocket servers from
+// the access control configuration file.
+func (api *privateAdminAPI) ReloadAccessControl() (bool, error) {
+	if api.node.access == nil {
+		return false, errAccessNotEnabled
+	}
+	if err := api.node.access.reload(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // publicAdminAPI is the collection of administrative API methods exposed over
 // both secure and unsecure RPC channels.
 type publicAdminAPI struct {
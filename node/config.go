@@ -45,6 +45,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirJWTSecret       = "jwtsecret"          // Path within the datadir to the RPC authentication secret
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -136,6 +137,10 @@ type Config struct {
 	// interface.
 	HTTPTimeouts rpc.HTTPTimeouts
 
+	// HTTPJWTAuth requires JSON-RPC requests over HTTP to carry a HS256 JWT signed
+	// with the JWTSecret in the Authorization header.
+	HTTPJWTAuth bool `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -162,6 +167,29 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSJWTAuth requires websocket handshakes to carry a HS256 JWT signed with the
+	// JWTSecret in the Authorization header.
+	WSJWTAuth bool `toml:",omitempty"`
+
+	// BatchRequestLimit is the maximum number of requests in a batch served by the
+	// IPC, HTTP and websocket RPC interfaces. Zero disables the limit.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum number of response bytes of a batch, or of
+	// a single request, served by the IPC, HTTP and websocket RPC interfaces. Zero
+	// disables the limit.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
+	// JWTSecret is the path to the hex encoded 32 byte secret authenticating the
+	// RPC endpoints with JWT authentication enabled. If the path is empty, the
+	// secret is stored in the data directory. A missing secret file is generated.
+	JWTSecret string `toml:",omitempty"`
+
+	// AccessControlFile is the path to a JSON file of API keys authenticating the
+	// requests to the HTTP and websocket RPC interfaces, and restricting the calls
+	// of each key. Access control is disabled if the path is empty.
+	AccessControlFile string `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -373,6 +401,15 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// JWTSecretPath returns the path of the JWT secret file, or an empty string if no
+// path is configured and no data directory is used.
+func (c *Config) JWTSecretPath() string {
+	if c.JWTSecret != "" {
+		return c.JWTSecret
+	}
+	return c.ResolvePath(datadirJWTSecret)
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*enode.Node {
 	return c.parsePersistentNodes(&c.staticNodesWarning, c.ResolvePath(datadirStaticNodes))
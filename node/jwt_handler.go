@@ -0,0 +1,170 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// jwtSecretLength is the length of the shared JWT secret in bytes.
+	jwtSecretLength = 32
+
+	// jwtExpiryTimeout is the maximum deviation of the issued-at claim of a token
+	// from the local time.
+	jwtExpiryTimeout = 60 * time.Second
+)
+
+var (
+	errMissingJWT      = errors.New("missing token")
+	errMalformedJWT    = errors.New("malformed token")
+	errUnsupportedJWT  = errors.New("unsupported token algorithm")
+	errInvalidJWTSig   = errors.New("invalid token signature")
+	errMissingJWTIat   = errors.New("missing issued-at claim")
+	errStaleJWTIat     = errors.New("stale token")
+	errFutureJWTIat    = errors.New("token issued in the future")
+	errJWTSecretLength = fmt.Errorf("invalid JWT secret length, want %d bytes", jwtSecretLength)
+)
+
+// jwtHandler rejects the requests not carrying a valid HS256 JWT in their
+// Authorization header.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// newJWTHandler creates a http.Handler authenticating the requests with the
+// given secret before passing them to next.
+func newJWTHandler(secret []byte, next http.Handler) http.Handler {
+	return &jwtHandler{secret: secret, next: next}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		http.Error(w, errMissingJWT.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err := verifyJWT(h.secret, strings.TrimPrefix(auth, "Bearer "), time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// verifyJWT checks that the token is signed with the secret using HS256 and
+// that it was issued within jwtExpiryTimeout of the given time.
+func verifyJWT(secret []byte, token string, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errMalformedJWT
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return err
+	}
+	if header.Alg != "HS256" {
+		return errUnsupportedJWT
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errMalformedJWT
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return errInvalidJWTSig
+	}
+	var claims struct {
+		Iat *int64 `json:"iat"`
+	}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return err
+	}
+	if claims.Iat == nil {
+		return errMissingJWTIat
+	}
+	issued := time.Unix(*claims.Iat, 0)
+	if issued.Before(now.Add(-jwtExpiryTimeout)) {
+		return errStaleJWTIat
+	}
+	if issued.After(now.Add(jwtExpiryTimeout)) {
+		return errFutureJWTIat
+	}
+	return nil
+}
+
+// decodeJWTSegment decodes a base64url encoded JSON segment of a token.
+func decodeJWTSegment(segment string, v interface{}) error {
+	blob, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errMalformedJWT
+	}
+	if err := json.Unmarshal(blob, v); err != nil {
+		return errMalformedJWT
+	}
+	return nil
+}
+
+// obtainJWTSecret loads the hex encoded JWT secret from the given file. If the
+// file does not exist, a new random secret is generated and stored in it.
+func obtainJWTSecret(fileName string) ([]byte, error) {
+	if fileName == "" {
+		return nil, errors.New("no JWT secret file configured")
+	}
+	if data, err := ioutil.ReadFile(fileName); err == nil {
+		secret := common.FromHex(strings.TrimSpace(string(data)))
+		if len(secret) != jwtSecretLength {
+			return nil, errJWTSecretLength
+		}
+		log.Info("Loaded JWT secret file", "path", fileName)
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// No secret found, generate and store a new one.
+	secret := make([]byte, jwtSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(fileName, []byte(common.Bytes2Hex(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", fileName)
+	return secret, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// signTestJWT creates a token with the given header and claims signed with the secret.
+func signTestJWT(secret []byte, header, claims string) string {
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(header)) + "." + enc.EncodeToString([]byte(claims))
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + enc.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	var (
+		secret = bytes.Repeat([]byte{0x01}, jwtSecretLength)
+		other  = bytes.Repeat([]byte{0x02}, jwtSecretLength)
+		now    = time.Unix(1600000000, 0)
+		header = `{"alg":"HS256","typ":"JWT"}`
+		iat    = func(offset int64) string { return fmt.Sprintf(`{"iat":%d}`, now.Unix()+offset) }
+	)
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{signTestJWT(secret, header, iat(0)), nil},
+		{signTestJWT(secret, header, iat(-60)), nil},
+		{signTestJWT(secret, header, iat(60)), nil},
+		{signTestJWT(secret, header, iat(-61)), errStaleJWTIat},
+		{signTestJWT(secret, header, iat(61)), errFutureJWTIat},
+		{signTestJWT(secret, header, `{}`), errMissingJWTIat},
+		{signTestJWT(secret, `{"alg":"none"}`, iat(0)), errUnsupportedJWT},
+		{signTestJWT(other, header, iat(0)), errInvalidJWTSig},
+		{signTestJWT(secret, header, iat(0))[1:], errMalformedJWT},
+		{"foo.bar", errMalformedJWT},
+	}
+	for i, test := range tests {
+		if err := verifyJWT(secret, test.token, now); err != test.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x01}, jwtSecretLength)
+	auth := rpcEndpointConfig{jwtSecret: secret}
+	srv := createAndStartServer(t, httpConfig{rpcEndpointConfig: auth}, true, wsConfig{Origins: []string{"*"}, rpcEndpointConfig: auth})
+	defer srv.stop()
+
+	call := func(client *rpc.Client, err error) error {
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		var modules map[string]string
+		return client.Call(&modules, "rpc_modules")
+	}
+	var (
+		ctx      = context.Background()
+		httpURL  = "http://" + srv.listenAddr()
+		wsURL    = "ws://" + srv.listenAddr()
+		badCreds = bytes.Repeat([]byte{0x02}, jwtSecretLength)
+	)
+	if err := call(rpc.DialHTTPWithJWT(httpURL, secret)); err != nil {
+		t.Errorf("authenticated HTTP call failed: %v", err)
+	}
+	if err := call(rpc.DialWebsocketWithJWT(ctx, wsURL, "", secret)); err != nil {
+		t.Errorf("authenticated websocket call failed: %v", err)
+	}
+	if err := call(rpc.DialHTTP(httpURL)); err == nil {
+		t.Error("unauthenticated HTTP call succeeded")
+	}
+	if err := call(rpc.DialWebsocket(ctx, wsURL, "")); err == nil {
+		t.Error("unauthenticated websocket call succeeded")
+	}
+	if err := call(rpc.DialHTTPWithJWT(httpURL, badCreds)); err == nil {
+		t.Error("HTTP call with wrong secret succeeded")
+	}
+	if err := call(rpc.DialWebsocketWithJWT(ctx, wsURL, "", badCreds)); err == nil {
+		t.Error("websocket call with wrong secret succeeded")
+	}
+	// Handlers registered on the mux require a token as well
+	srv.mux.Handle("/graphql", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	get := func(key []byte) int {
+		req, _ := http.NewRequest("GET", httpURL+"/graphql", nil)
+		if key != nil {
+			claims := fmt.Sprintf(`{"iat":%d}`, time.Now().Unix())
+			req.Header.Set("Authorization", "Bearer "+signTestJWT(key, `{"alg":"HS256","typ":"JWT"}`, claims))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := get(nil); status != http.StatusUnauthorized {
+		t.Errorf("handler request without token status mismatch: have %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := get(badCreds); status != http.StatusUnauthorized {
+		t.Errorf("handler request with wrong secret status mismatch: have %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := get(secret); status != http.StatusOK {
+		t.Errorf("handler request with token status mismatch: have %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestObtainJWTSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwtsecret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A missing secret is generated and reloaded afterwards.
+	path := filepath.Join(dir, "geth", "jwtsecret")
+	secret, err := obtainJWTSecret(path)
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	if len(secret) != jwtSecretLength {
+		t.Fatalf("generated secret length mismatch: have %d, want %d", len(secret), jwtSecretLength)
+	}
+	reloaded, err := obtainJWTSecret(path)
+	if err != nil {
+		t.Fatalf("failed to reload secret: %v", err)
+	}
+	if !bytes.Equal(secret, reloaded) {
+		t.Fatalf("reloaded secret mismatch: have %x, want %x", reloaded, secret)
+	}
+	// Secrets of invalid length are rejected.
+	short := filepath.Join(dir, "short")
+	if err := ioutil.WriteFile(short, []byte("0x0102"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obtainJWTSecret(short); err != errJWTSecretLength {
+		t.Fatalf("error mismatch: have %v, want %v", err, errJWTSecretLength)
+	}
+}
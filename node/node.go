@@ -58,6 +58,8 @@ type Node struct {
 	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
 
 	databases map[*closeTrackingDB]struct{} // All open databases
+
+	access *accessController // API key access control of the HTTP and WS servers, nil if disabled
 }
 
 const (
@@ -139,6 +141,11 @@ func New(conf *Config) (*Node, error) {
 	node.http = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
+	if conf.AccessControlFile != "" {
+		if node.access, err = newAccessController(conf.AccessControlFile); err != nil {
+			return nil, err
+		}
+	}
 
 	return node, nil
 }
@@ -332,17 +339,26 @@ func (n *Node) startRPC() error {
 
 	// Configure IPC.
 	if n.ipc.endpoint != "" {
-		if err := n.ipc.start(n.rpcAPIs); err != nil {
+		config, err := n.rpcEndpointConfig(false)
+		if err != nil {
+			return err
+		}
+		if err := n.ipc.start(n.rpcAPIs, config); err != nil {
 			return err
 		}
 	}
 
 	// Configure HTTP.
 	if n.config.HTTPHost != "" {
+		endpointConfig, err := n.rpcEndpointConfig(n.config.HTTPJWTAuth)
+		if err != nil {
+			return err
+		}
 		config := httpConfig{
 			CorsAllowedOrigins: n.config.HTTPCors,
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
+			rpcEndpointConfig:  endpointConfig,
 		}
 		if err := n.http.setListenAddr(n.config.HTTPHost, n.config.HTTPPort); err != nil {
 			return err
@@ -355,9 +371,14 @@ func (n *Node) startRPC() error {
 	// Configure WebSocket.
 	if n.config.WSHost != "" {
 		server := n.wsServerForPort(n.config.WSPort)
+		endpointConfig, err := n.rpcEndpointConfig(n.config.WSJWTAuth)
+		if err != nil {
+			return err
+		}
 		config := wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
+			Modules:           n.config.WSModules,
+			Origins:           n.config.WSOrigins,
+			rpcEndpointConfig: endpointConfig,
 		}
 		if err := server.setListenAddr(n.config.WSHost, n.config.WSPort); err != nil {
 			return err
@@ -373,6 +394,24 @@ func (n *Node) startRPC() error {
 	return n.ws.start()
 }
 
+// rpcEndpointConfig returns the request limits and access control of the RPC
+// endpoints, along with the JWT secret if the endpoint requires authentication.
+func (n *Node) rpcEndpointConfig(auth bool) (rpcEndpointConfig, error) {
+	config := rpcEndpointConfig{
+		batchItemLimit:         n.config.BatchRequestLimit,
+		batchResponseSizeLimit: n.config.BatchResponseMaxSize,
+		access:                 n.access,
+	}
+	if auth {
+		secret, err := obtainJWTSecret(n.config.JWTSecretPath())
+		if err != nil {
+			return config, err
+		}
+		config.jwtSecret = secret
+	}
+	return config, nil
+}
+
 func (n *Node) wsServerForPort(port int) *httpServer {
 	if n.config.HTTPHost == "" || n.http.port == port {
 		return n.http
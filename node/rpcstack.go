@@ -39,17 +39,29 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
+	rpcEndpointConfig
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
 	Origins []string
 	Modules []string
+	rpcEndpointConfig
+}
+
+// rpcEndpointConfig is the request limit and authentication configuration of an RPC endpoint
+type rpcEndpointConfig struct {
+	batchItemLimit         int
+	batchResponseSizeLimit int
+	jwtSecret              []byte            // JWT authentication secret, nil if disabled
+	access                 *accessController // API key access control, nil if disabled (not supported by IPC)
 }
 
 type rpcHandler struct {
 	http.Handler
-	server *rpc.Server
+	server    *rpc.Server
+	access    *accessController
+	jwtSecret []byte
 }
 
 type httpServer struct {
@@ -171,9 +183,9 @@ func (h *httpServer) start() error {
 
 func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	rpc := h.httpHandler.Load().(*rpcHandler)
-	if r.RequestURI == "/" {
-		// Serve JSON-RPC on the root path.
-		ws := h.wsHandler.Load().(*rpcHandler)
+	ws := h.wsHandler.Load().(*rpcHandler)
+	if r.RequestURI == "/" || rpc.isKeyPath(r) || ws.isKeyPath(r) {
+		// Serve JSON-RPC on the root path, or the API key paths if access control is enabled.
 		if ws != nil && isWebsocket(r) {
 			ws.ServeHTTP(w, r)
 			return
@@ -185,13 +197,26 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	} else if rpc != nil {
 		// Requests to a path below root are handled by the mux,
 		// which has all the handlers registered via Node.RegisterHandler.
-		// These are made available when RPC is enabled.
-		h.mux.ServeHTTP(w, r)
+		// These are made available when RPC is enabled, behind the same
+		// API key and JWT checks as JSON-RPC if those are enabled.
+		var handler http.Handler = &h.mux
+		if rpc.access != nil {
+			handler = newAccessHandler(rpc.access, handler)
+		}
+		if len(rpc.jwtSecret) != 0 {
+			handler = newJWTHandler(rpc.jwtSecret, handler)
+		}
+		handler.ServeHTTP(w, r)
 		return
 	}
 	w.WriteHeader(404)
 }
 
+// isKeyPath reports whether the request path is an API key of the handler.
+func (h *rpcHandler) isKeyPath(r *http.Request) bool {
+	return h != nil && h.access != nil && h.access.isKeyPath(r)
+}
+
 // stop shuts down the HTTP server.
 func (h *httpServer) stop() {
 	h.mu.Lock()
@@ -235,13 +260,21 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	handler := NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret)
+	if config.access != nil {
+		srv.SetCallAuthorizer(config.access.authorize)
+		handler = newAccessHandler(config.access, handler)
+	}
 	h.httpConfig = config
 	h.httpHandler.Store(&rpcHandler{
-		Handler: NewHTTPHandlerStack(srv, config.CorsAllowedOrigins, config.Vhosts),
-		server:  srv,
+		Handler:   handler,
+		server:    srv,
+		access:    config.access,
+		jwtSecret: config.jwtSecret,
 	})
 	return nil
 }
@@ -267,13 +300,20 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
 	if err := RegisterApisFromWhitelist(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
+	handler := NewWSHandlerStack(srv.WebsocketHandler(config.Origins), config.jwtSecret)
+	if config.access != nil {
+		srv.SetCallAuthorizer(config.access.authorize)
+		handler = newAccessHandler(config.access, handler)
+	}
 	h.wsConfig = config
 	h.wsHandler.Store(&rpcHandler{
-		Handler: srv.WebsocketHandler(config.Origins),
+		Handler: handler,
 		server:  srv,
+		access:  config.access,
 	})
 	return nil
 }
@@ -316,14 +356,27 @@ func isWebsocket(r *http.Request) bool {
 		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
 }
 
-// NewHTTPHandlerStack returns wrapped http-related handlers
-func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string) http.Handler {
+// NewHTTPHandlerStack returns wrapped http-related handlers. Requests are
+// authenticated if a JWT secret is given.
+func NewHTTPHandlerStack(srv http.Handler, cors []string, vhosts []string, jwtSecret []byte) http.Handler {
 	// Wrap the CORS-handler within a host-handler
 	handler := newCorsHandler(srv, cors)
 	handler = newVHostHandler(vhosts, handler)
+	if len(jwtSecret) != 0 {
+		handler = newJWTHandler(jwtSecret, handler)
+	}
 	return newGzipHandler(handler)
 }
 
+// NewWSHandlerStack returns a wrapped ws-related handler. Handshakes are
+// authenticated if a JWT secret is given.
+func NewWSHandlerStack(srv http.Handler, jwtSecret []byte) http.Handler {
+	if len(jwtSecret) != 0 {
+		return newJWTHandler(jwtSecret, srv)
+	}
+	return srv
+}
+
 func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
@@ -439,14 +492,16 @@ func newIPCServer(log log.Logger, endpoint string) *ipcServer {
 }
 
 // Start starts the httpServer's http.Server
-func (is *ipcServer) start(apis []rpc.API) error {
+func (is *ipcServer) start(apis []rpc.API, config rpcEndpointConfig) error {
 	is.mu.Lock()
 	defer is.mu.Unlock()
 
 	if is.listener != nil {
 		return nil // already running
 	}
-	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis)
+	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	listener, err := rpc.ServeIPCEndpoint(srv, is.endpoint, apis)
 	if err != nil {
 		is.log.Warn("IPC opening failed", "url", is.endpoint, "error", err)
 		return err
@@ -77,6 +77,8 @@ type Client struct {
 	idgen    func() ID // for subscriptions
 	isHTTP   bool
 	services *serviceRegistry
+	config   handlerConfig   // restrictions of the calls served to the remote side
+	connCtx  context.Context // base context of the calls served to the remote side
 
 	idCounter uint32
 
@@ -110,8 +112,8 @@ type clientConn struct {
 }
 
 func (c *Client) newClientConn(conn ServerCodec) *clientConn {
-	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	ctx := context.WithValue(c.connCtx, clientContextKey{}, c)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.config)
 	return &clientConn{conn, handler}
 }
 
@@ -197,17 +199,19 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(context.Background(), conn, randomIDGenerator(), new(serviceRegistry), handlerConfig{})
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(connCtx context.Context, conn ServerCodec, idgen func() ID, services *serviceRegistry, config handlerConfig) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		config:      config,
+		connCtx:     connCtx,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
@@ -25,16 +25,26 @@ import (
 
 // StartIPCEndpoint starts an IPC endpoint.
 func StartIPCEndpoint(ipcEndpoint string, apis []API) (net.Listener, *Server, error) {
+	handler := NewServer()
+	listener, err := ServeIPCEndpoint(handler, ipcEndpoint, apis)
+	if err != nil {
+		return nil, nil, err
+	}
+	return listener, handler, nil
+}
+
+// ServeIPCEndpoint registers the given APIs on a preconfigured server and serves
+// it on a new IPC endpoint.
+func ServeIPCEndpoint(handler *Server, ipcEndpoint string, apis []API) (net.Listener, error) {
 	// Register all the APIs exposed by the services.
 	var (
-		handler    = NewServer()
 		regMap     = make(map[string]struct{})
 		registered []string
 	)
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			log.Info("IPC registration failed", "namespace", api.Namespace, "error", err)
-			return nil, nil, err
+			return nil, err
 		}
 		if _, ok := regMap[api.Namespace]; !ok {
 			registered = append(registered, api.Namespace)
@@ -45,8 +55,8 @@ func StartIPCEndpoint(ipcEndpoint string, apis []API) (net.Listener, *Server, er
 	// All APIs registered, start the IPC listener.
 	listener, err := ipcListen(ipcEndpoint)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	go handler.ServeListener(listener)
-	return listener, handler, nil
+	return listener, nil
 }
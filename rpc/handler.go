@@ -61,17 +61,31 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	config         handlerConfig // request limits and call authorization
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
 }
 
+// handlerConfig restricts the size of the batches and responses served by a handler,
+// and the calls it executes. Zero values disable the respective restriction.
+type handlerConfig struct {
+	batchItems   int            // maximum number of messages in a batch
+	responseSize int            // maximum size of the result(s) of a call or batch, in bytes
+	authorize    CallAuthorizer // consulted before executing a method call
+}
+
+// CallAuthorizer is consulted before a method call is executed. The context carries
+// the values of the connection's context, e.g. of the HTTP request. A non-nil error
+// rejects the call and is returned to the caller.
+type CallAuthorizer func(ctx context.Context, method string) error
+
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, config handlerConfig) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -82,6 +96,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		rootCtx:        rootCtx,
 		cancelRoot:     cancelRoot,
 		allowSubscribe: true,
+		config:         config,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
 	}
@@ -101,6 +116,13 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject the whole batch if it has more messages than allowed:
+	if h.config.batchItems > 0 && len(msgs) > h.config.batchItems {
+		h.startCallProc(func(cp *callProc) {
+			h.conn.writeJSON(cp.ctx, errorMessage(&invalidRequestError{"batch too large"}))
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -114,9 +136,24 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
+		var (
+			answers = make([]*jsonrpcMessage, 0, len(msgs))
+			size    int
+		)
 		for _, msg := range calls {
+			// Once the responses grew too large, fail the remaining calls
+			// without executing them.
+			if h.config.responseSize > 0 && size > h.config.responseSize {
+				if msg.hasValidID() {
+					answers = append(answers, msg.errorResponse(&responseTooLargeError{}))
+				}
+				continue
+			}
 			if answer := h.handleCallMsg(cp, msg); answer != nil {
+				size += len(answer.Result)
+				if h.config.responseSize > 0 && size > h.config.responseSize {
+					answer = msg.errorResponse(&responseTooLargeError{})
+				}
 				answers = append(answers, answer)
 			}
 		}
@@ -137,6 +174,9 @@ func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	}
 	h.startCallProc(func(cp *callProc) {
 		answer := h.handleCallMsg(cp, msg)
+		if answer != nil && h.config.responseSize > 0 && len(answer.Result) > h.config.responseSize {
+			answer = msg.errorResponse(&responseTooLargeError{})
+		}
 		h.addSubscriptions(cp.notifiers)
 		if answer != nil {
 			h.conn.writeJSON(cp.ctx, answer)
@@ -329,6 +369,11 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 	if callb == nil {
 		return msg.errorResponse(&methodNotFoundError{method: msg.Method})
 	}
+	if h.config.authorize != nil {
+		if err := h.config.authorize(cp.ctx, msg.Method); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	args, err := parsePositionalArguments(msg.Params, callb.argTypes)
 	if err != nil {
 		return msg.errorResponse(&invalidParamsError{err.Error()})
@@ -367,6 +412,11 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage) *jsonrpcMes
 	if callb == nil {
 		return msg.errorResponse(&subscriptionNotFoundError{namespace, name})
 	}
+	if h.config.authorize != nil {
+		if err := h.config.authorize(cp.ctx, msg.Method); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 
 	// Parse subscription name arg too, but remove it before calling the callback.
 	argTypes := append([]reflect.Type{stringType}, callb.argTypes...)
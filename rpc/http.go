@@ -46,6 +46,7 @@ type httpConn struct {
 	closeCh   chan interface{}
 	mu        sync.Mutex // protects headers
 	headers   http.Header
+	jwtSecret []byte // signs a token for every request if set
 }
 
 // httpConn is treated specially by Client.
@@ -105,6 +106,16 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
+	return dialHTTP(endpoint, client, nil)
+}
+
+// DialHTTPWithJWT creates a new RPC client that connects to an RPC server over HTTP,
+// authenticating every request with a HS256 JWT signed by the given secret.
+func DialHTTPWithJWT(endpoint string, jwtSecret []byte) (*Client, error) {
+	return dialHTTP(endpoint, new(http.Client), jwtSecret)
+}
+
+func dialHTTP(endpoint string, client *http.Client, jwtSecret []byte) (*Client, error) {
 	// Sanity check URL so we don't end up with a client that will fail every request.
 	_, err := url.Parse(endpoint)
 	if err != nil {
@@ -117,10 +128,11 @@ func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 	headers.Set("content-type", contentType)
 	return newClient(initctx, func(context.Context) (ServerCodec, error) {
 		hc := &httpConn{
-			client:  client,
-			headers: headers,
-			url:     endpoint,
-			closeCh: make(chan interface{}),
+			client:    client,
+			headers:   headers,
+			url:       endpoint,
+			closeCh:   make(chan interface{}),
+			jwtSecret: jwtSecret,
 		}
 		return hc, nil
 	})
@@ -187,6 +199,9 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	hc.mu.Lock()
 	req.Header = hc.headers.Clone()
 	hc.mu.Unlock()
+	if len(hc.jwtSecret) != 0 {
+		req.Header.Set("authorization", "Bearer "+newJWT(hc.jwtSecret, time.Now()))
+	}
 
 	// do request
 	resp, err := hc.client.Do(req)
@@ -46,6 +46,7 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+	config   handlerConfig
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -58,6 +59,24 @@ func NewServer() *Server {
 	return server
 }
 
+// SetBatchLimits sets limits applied to batch requests. There are two limits: 'itemLimit'
+// is the maximum number of items in a batch. 'responseSizeLimit' is the maximum number of
+// response bytes across all requests in a batch, and of the response of a single request.
+// A zero value disables the respective limit.
+//
+// This method should be called before processing any requests via ServeCodec, ServeHTTP,
+// ServeListener etc.
+func (s *Server) SetBatchLimits(itemLimit, responseSizeLimit int) {
+	s.config.batchItems, s.config.responseSize = itemLimit, responseSizeLimit
+}
+
+// SetCallAuthorizer sets a function consulted before every method call, which may
+// reject the call by returning an error. This method should be called before
+// processing any requests via ServeCodec, ServeHTTP, ServeListener etc.
+func (s *Server) SetCallAuthorizer(authorize CallAuthorizer) {
+	s.config.authorize = authorize
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -72,6 +91,12 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec)
+}
+
+// serveCodec serves the codec like ServeCodec, deriving the contexts of the calls
+// from the given connection context.
+func (s *Server) serveCodec(connCtx context.Context, codec ServerCodec) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -83,7 +108,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(connCtx, codec, s.idgen, &s.services, s.config)
 	<-codec.closed()
 	c.Close()
 }
@@ -97,7 +122,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.config)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
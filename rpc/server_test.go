@@ -150,3 +150,54 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+func TestServerBatchLimits(t *testing.T) {
+	server := newTestServer()
+	server.SetBatchLimits(3, 50)
+	defer server.Stop()
+
+	p1, p2 := net.Pipe()
+	go server.ServeCodec(NewCodec(p1), 0)
+	defer p2.Close()
+	p2.SetDeadline(time.Now().Add(10 * time.Second))
+	reader := bufio.NewReader(p2)
+
+	tests := []struct {
+		request, wantResp string
+	}{
+		// small single call
+		{
+			`{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`,
+			`{"jsonrpc":"2.0","id":1,"result":{"String":"x","Int":1,"Args":null}}`,
+		},
+		// single call with an oversized result
+		{
+			`{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["` + strings.Repeat("x", 50) + `",1]}`,
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32003,"message":"response too large"}}`,
+		},
+		// batch with too many items
+		{
+			`[{"jsonrpc":"2.0","id":3,"method":"test_rets"},{"jsonrpc":"2.0","id":4,"method":"test_rets"},{"jsonrpc":"2.0","id":5,"method":"test_rets"},{"jsonrpc":"2.0","id":6,"method":"test_rets"}]`,
+			`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch too large"}}`,
+		},
+		// batch whose responses exceed the size limit after the first call
+		{
+			`[{"jsonrpc":"2.0","id":7,"method":"test_echo","params":["x",1]},{"jsonrpc":"2.0","id":8,"method":"test_echo","params":["x",1]},{"jsonrpc":"2.0","id":9,"method":"test_echo","params":["x",1]}]`,
+			`[{"jsonrpc":"2.0","id":7,"result":{"String":"x","Int":1,"Args":null}},` +
+				`{"jsonrpc":"2.0","id":8,"error":{"code":-32003,"message":"response too large"}},` +
+				`{"jsonrpc":"2.0","id":9,"error":{"code":-32003,"message":"response too large"}}]`,
+		},
+	}
+	for i, test := range tests {
+		if _, err := p2.Write([]byte(test.request + "\n")); err != nil {
+			t.Fatalf("test %d: write error: %v", i, err)
+		}
+		resp, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("test %d: read error: %v", i, err)
+		}
+		if resp = strings.TrimSpace(resp); resp != test.wantResp {
+			t.Errorf("test %d: wrong response\nhave: %s\nwant: %s", i, resp, test.wantResp)
+		}
+	}
+}
@@ -59,7 +59,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 			return
 		}
 		codec := newWebsocketCodec(conn)
-		s.ServeCodec(codec, 0)
+		s.serveCodec(r.Context(), codec)
 	})
 }
 
@@ -182,11 +182,32 @@ func parseOriginURL(origin string) (string, string, string, error) {
 // DialWebsocketWithDialer creates a new RPC client that communicates with a JSON-RPC server
 // that is listening on the given endpoint using the provided dialer.
 func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, dialer websocket.Dialer) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, dialer, nil)
+}
+
+// DialWebsocketWithJWT creates a new RPC client that communicates with a JSON-RPC server
+// that is listening on the given endpoint, authenticating the handshake of every
+// (re)connection with a HS256 JWT signed by the given secret.
+func DialWebsocketWithJWT(ctx context.Context, endpoint, origin string, jwtSecret []byte) (*Client, error) {
+	dialer := websocket.Dialer{
+		ReadBufferSize:  wsReadBuffer,
+		WriteBufferSize: wsWriteBuffer,
+		WriteBufferPool: wsBufferPool,
+	}
+	return dialWebsocket(ctx, endpoint, origin, dialer, jwtSecret)
+}
+
+func dialWebsocket(ctx context.Context, endpoint, origin string, dialer websocket.Dialer, jwtSecret []byte) (*Client, error) {
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
 	}
 	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		header := header
+		if len(jwtSecret) != 0 {
+			header = header.Clone()
+			header.Set("authorization", "Bearer "+newJWT(jwtSecret, time.Now()))
+		}
 		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
 		if err != nil {
 			hErr := wsHandshakeError{err: err}